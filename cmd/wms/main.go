@@ -0,0 +1,14 @@
+// Command wms is the headless command-line interface to the WMS: list
+// items, locations and stock, post adjustments and manage the offline
+// queue from a terminal, using the same settings.json as the desktop app.
+package main
+
+import (
+	"os"
+
+	"github.com/larkin1/wmsproject/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -8,9 +8,11 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,35 +21,64 @@ type Client struct {
 	APIKey   string
 	Client   *http.Client
 	BasePath string
+	Site     string // scopes fetches and commits to one warehouse; empty means all
+	Store    *Store // local copy of fetched data, used when offline
+
+	applyMu sync.Mutex // serialises read-modify-write of caches by syncs and ApplyChange
+
+	// MaxCacheAge rejects cached data older than this when offline; 0 for no limit.
+	MaxCacheAge time.Duration
+
+	freshMu   sync.Mutex
+	freshness map[string]Freshness
 }
 
 type CommitPayload struct {
-	DeviceID string `json:"device_id"`
-	Location string `json:"location"`
-	Delta    int    `json:"delta"`
-	ItemID   int    `json:"item_id"`
+	DeviceID  string `json:"device_id"`
+	Location  string `json:"location"`
+	Delta     int    `json:"delta"`
+	ItemID    int    `json:"item_id"`
+	UoM       string `json:"uom,omitempty"`
+	UoMQty    int    `json:"uom_qty,omitempty"`
+	Site      string `json:"site,omitempty"`
+	Reference string `json:"reference,omitempty"` // order or document the movement belongs to
+	POLineID  int    `json:"po_line_id,omitempty"`
+	Operator  string `json:"operator,omitempty"` // badge of whoever made the movement
 }
 
 type Item struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID       int             `json:"id"`
+	Name     string          `json:"name"`
+	BaseUnit string          `json:"base_unit,omitempty"`
+	Units    []UnitOfMeasure `json:"units,omitempty"`
+	MinQty   int             `json:"min_qty,omitempty"` // reorder point, 0 for none
+	MaxQty   int             `json:"max_qty,omitempty"` // level to reorder up to
+	Barcodes []string        `json:"barcodes,omitempty"`
+
+	UpdatedAt string `json:"updated_at,omitempty"`
 }
 
 type Location struct {
-	LocationName string `json:"location"`
-	Items        []int  `json:"items"`
+	LocationName string         `json:"location"`
+	Items        []int          `json:"items"`
+	Capacity     int            `json:"capacity,omitempty"`      // max units in total, 0 for no limit
+	ItemCapacity map[string]int `json:"item_capacity,omitempty"` // max units per item ID
+	Site         string         `json:"site,omitempty"`
+
+	UpdatedAt string `json:"updated_at,omitempty"`
 }
 
-// CachedItems wraps items with metadata
-type CachedItems struct {
-	Timestamp int64  `json:"timestamp"`
-	Items     []Item `json:"items"`
+// CapacityFor returns the limit for one item at the location, if any.
+func (l Location) CapacityFor(itemID int) (int, bool) {
+	limit, ok := l.ItemCapacity[strconv.Itoa(itemID)]
+	return limit, ok && limit > 0
 }
 
-// CachedLocations wraps locations with metadata
-type CachedLocations struct {
-	Timestamp int64       `json:"timestamp"`
-	Locations []Location  `json:"locations"`
+// StockLevel is one row of the overview view: on-hand quantity per location and item
+type StockLevel struct {
+	Location string `json:"location"`
+	ItemID   int    `json:"item_id"`
+	Qty      int    `json:"qty"`
 }
 
 func NewClient(baseURL, apiKey, basePath string) *Client {
@@ -55,84 +86,27 @@ func NewClient(baseURL, apiKey, basePath string) *Client {
 		BaseURL:  strings.TrimSuffix(baseURL, "/"),
 		APIKey:   apiKey,
 		BasePath: basePath,
+		Store:    NewStore(basePath),
 		Client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
 	}
 }
 
-func (c *Client) getCacheFilePath(filename string) string {
-	return filepath.Join(c.BasePath, filename)
-}
-
-func (c *Client) saveItemsCache(items []Item) error {
-	cached := CachedItems{
-		Timestamp: time.Now().Unix(),
-		Items:     items,
+// siteFilter restricts a query to the client's site.
+func (c *Client) siteFilter() string {
+	if c.Site == "" {
+		return ""
 	}
-
-	data, err := json.MarshalIndent(cached, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	cachePath := c.getCacheFilePath("items.cache.json")
-	log.Printf("[API] Saving items cache to: %s\n", cachePath)
-	return os.WriteFile(cachePath, data, 0644)
+	return "&site=eq." + url.QueryEscape(c.Site)
 }
 
-func (c *Client) loadItemsCache() ([]Item, error) {
-	cachePath := c.getCacheFilePath("items.cache.json")
-	data, err := os.ReadFile(cachePath)
-	if err != nil {
-		log.Printf("[API] Items cache not found: %v\n", err)
-		return nil, err
-	}
-
-	var cached CachedItems
-	err = json.Unmarshal(data, &cached)
-	if err != nil {
-		log.Printf("[API] Failed to parse items cache: %v\n", err)
-		return nil, err
+// sharedSiteFilter restricts a query to the client's site plus rows shared by all sites.
+func (c *Client) sharedSiteFilter() string {
+	if c.Site == "" {
+		return ""
 	}
-
-	log.Printf("[API] Loaded items cache from %s (%d items, cached at %d)\n", cachePath, len(cached.Items), cached.Timestamp)
-	return cached.Items, nil
-}
-
-func (c *Client) saveLocationsCache(locations []Location) error {
-	cached := CachedLocations{
-		Timestamp: time.Now().Unix(),
-		Locations: locations,
-	}
-
-	data, err := json.MarshalIndent(cached, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	cachePath := c.getCacheFilePath("locations.cache.json")
-	log.Printf("[API] Saving locations cache to: %s\n", cachePath)
-	return os.WriteFile(cachePath, data, 0644)
-}
-
-func (c *Client) loadLocationsCache() ([]Location, error) {
-	cachePath := c.getCacheFilePath("locations.cache.json")
-	data, err := os.ReadFile(cachePath)
-	if err != nil {
-		log.Printf("[API] Locations cache not found: %v\n", err)
-		return nil, err
-	}
-
-	var cached CachedLocations
-	err = json.Unmarshal(data, &cached)
-	if err != nil {
-		log.Printf("[API] Failed to parse locations cache: %v\n", err)
-		return nil, err
-	}
-
-	log.Printf("[API] Loaded locations cache from %s (%d locations, cached at %d)\n", cachePath, len(cached.Locations), cached.Timestamp)
-	return cached.Locations, nil
+	return "&or=" + url.QueryEscape("(site.is.null,site.eq."+c.Site+")")
 }
 
 func (c *Client) Check() bool {
@@ -152,11 +126,17 @@ func (c *Client) Check() bool {
 }
 
 func (c *Client) SendCommit(deviceID, location string, delta, itemID int) (map[string]interface{}, error) {
-	payload := CommitPayload{
+	return c.PostCommit(CommitPayload{
 		DeviceID: deviceID,
 		Location: location,
 		Delta:    delta,
 		ItemID:   itemID,
+	})
+}
+
+func (c *Client) PostCommit(payload CommitPayload) (map[string]interface{}, error) {
+	if payload.Site == "" {
+		payload.Site = c.Site
 	}
 
 	data, _ := json.Marshal(payload)
@@ -182,90 +162,65 @@ func (c *Client) SendCommit(deviceID, location string, delta, itemID int) (map[s
 	return result, nil
 }
 
+// FetchItems syncs items into the cache and returns the full list. Only rows
+// changed since the cached watermark are downloaded, see syncItems.
 func (c *Client) FetchItems() ([]Item, error) {
 	log.Println("[API] FetchItems() called")
-	req, _ := http.NewRequest("GET", c.BaseURL+"/rest/v1/items?select=*", nil)
-	c.setAuthHeaders(req)
-
-	log.Printf("[API] Making request to: %s\n", c.BaseURL+"/rest/v1/items?select=*")
-	resp, err := c.Client.Do(req)
+	items, err := c.syncItems()
 	if err != nil {
-		log.Printf("[API] Request error: %v (trying cache)\n", err)
-		return c.loadItemsCache()
+		log.Printf("[API] Item sync failed: %v (trying cache)\n", err)
+		return c.itemsFallback()
 	}
-	defer resp.Body.Close()
-
-	log.Printf("[API] Response status: %d\n", resp.StatusCode)
+	c.markLive("items")
 
-	body, _ := io.ReadAll(resp.Body)
-	log.Printf("[API] Response body: %s\n", string(body))
+	log.Printf("[API] Have %d items\n", len(items))
+	return items, nil
+}
 
-	var items []Item
-	err = json.Unmarshal(body, &items)
+// FetchLocations syncs locations into the cache and returns the full list.
+func (c *Client) FetchLocations() ([]Location, error) {
+	log.Println("[API] FetchLocations() called")
+	locations, err := c.syncLocations()
 	if err != nil {
-		log.Printf("[API] JSON unmarshal error: %v (trying cache)\n", err)
-		return c.loadItemsCache()
-	}
-
-	if resp.StatusCode >= 400 {
-		log.Printf("[API] HTTP error %d (trying cache)\n", resp.StatusCode)
-		return c.loadItemsCache()
-	}
-
-	// Success: save to cache
-	if len(items) > 0 {
-		c.saveItemsCache(items)
-	}
-
-	log.Printf("[API] Parsed %d items\n", len(items))
-	for i, item := range items {
-		log.Printf("[API] Item %d: ID=%d, Name=%s\n", i, item.ID, item.Name)
+		log.Printf("[API] Location sync failed: %v (trying cache)\n", err)
+		return c.locationsFallback()
 	}
+	c.markLive("locations")
 
-	return items, nil
+	log.Printf("[API] Have %d locations\n", len(locations))
+	return locations, nil
 }
 
-func (c *Client) FetchLocations() ([]Location, error) {
-	log.Println("[API] FetchLocations() called")
-	req, _ := http.NewRequest("GET", c.BaseURL+"/rest/v1/locations?select=*", nil)
+func (c *Client) FetchOverview() ([]StockLevel, error) {
+	log.Println("[API] FetchOverview() called")
+	req, _ := http.NewRequest("GET", c.BaseURL+"/rest/v1/overview?select=*"+c.siteFilter(), nil)
 	c.setAuthHeaders(req)
 
-	log.Printf("[API] Making request to: %s\n", c.BaseURL+"/rest/v1/locations?select=*")
 	resp, err := c.Client.Do(req)
 	if err != nil {
 		log.Printf("[API] Request error: %v (trying cache)\n", err)
-		return c.loadLocationsCache()
+		return c.overviewFallback()
 	}
 	defer resp.Body.Close()
 
-	log.Printf("[API] Response status: %d\n", resp.StatusCode)
-
 	body, _ := io.ReadAll(resp.Body)
-	log.Printf("[API] Response body: %s\n", string(body))
-
-	var locations []Location
-	err = json.Unmarshal(body, &locations)
-	if err != nil {
-		log.Printf("[API] JSON unmarshal error: %v (trying cache)\n", err)
-		return c.loadLocationsCache()
-	}
-
 	if resp.StatusCode >= 400 {
 		log.Printf("[API] HTTP error %d (trying cache)\n", resp.StatusCode)
-		return c.loadLocationsCache()
+		return c.overviewFallback()
 	}
 
-	// Success: save to cache
-	if len(locations) > 0 {
-		c.saveLocationsCache(locations)
+	var rows []StockLevel
+	err = json.Unmarshal(body, &rows)
+	if err != nil {
+		log.Printf("[API] JSON unmarshal error: %v (trying cache)\n", err)
+		return c.overviewFallback()
 	}
 
-	log.Printf("[API] Parsed %d locations\n", len(locations))
-	for i, loc := range locations {
-		log.Printf("[API] Location %d: %s with %d items\n", i, loc.LocationName, len(loc.Items))
-	}
+	c.Store.saveOverview(rows)
+	c.markLive("overview")
 
-	return locations, nil
+	log.Printf("[API] Parsed %d overview rows\n", len(rows))
+	return rows, nil
 }
 
 func (c *Client) ExportItemsToCSV(filePath string) error {
@@ -276,6 +231,10 @@ func (c *Client) ExportItemsToCSV(filePath string) error {
 		return err
 	}
 
+	return c.WriteItemsCSV(filePath, items)
+}
+
+func (c *Client) WriteItemsCSV(filePath string, items []Item) error {
 	log.Printf("[API] Exporting %d items to CSV\n", len(items))
 
 	file, err := os.Create(filePath)
@@ -316,8 +275,7 @@ func (c *Client) ExportLocationsToCSV(filePath string) error {
 	writer.Write([]string{"location", "items"})
 
 	for _, loc := range locations {
-		itemsStr := fmt.Sprintf("%v", loc.Items)
-		writer.Write([]string{loc.LocationName, itemsStr})
+		writer.Write([]string{loc.LocationName, ItemList(loc.Items)})
 	}
 
 	writer.Flush()
@@ -325,6 +283,15 @@ func (c *Client) ExportLocationsToCSV(filePath string) error {
 	return nil
 }
 
+// ItemList formats item IDs as "1,2,3" for CSV files.
+func ItemList(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
 func (c *Client) setAuthHeaders(req *http.Request) {
 	req.Header.Set("Authorization", "Bearer "+c.APIKey)
 	req.Header.Set("apikey", c.APIKey)
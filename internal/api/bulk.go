@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// UpsertItems creates or updates items by ID in one request. Fields left
+// empty keep their current values, except the named columns, which are
+// always sent so that emptying them clears them.
+func (c *Client) UpsertItems(items []Item, columns ...string) error {
+	rows := make([]map[string]json.RawMessage, len(items))
+	for i, item := range items {
+		barcodes := item.Barcodes
+		if barcodes == nil {
+			barcodes = []string{}
+		}
+		full := struct {
+			BaseUnit string   `json:"base_unit"`
+			MinQty   int      `json:"min_qty"`
+			MaxQty   int      `json:"max_qty"`
+			Barcodes []string `json:"barcodes"`
+		}{item.Base(), item.MinQty, item.MaxQty, barcodes}
+		row, err := withColumns(item, full, columns)
+		if err != nil {
+			return err
+		}
+		rows[i] = row
+	}
+
+	log.Printf("[API] Upserting %d items\n", len(items))
+	return c.postRows("items?on_conflict=id", rows, true)
+}
+
+// UpsertLocations creates or updates locations by code in one request.
+// Locations without a site are stored for the client's site. The named
+// columns are always sent, as for UpsertItems.
+func (c *Client) UpsertLocations(locations []Location, columns ...string) error {
+	rows := make([]map[string]json.RawMessage, len(locations))
+	for i, loc := range locations {
+		if loc.Site == "" {
+			loc.Site = c.Site
+		}
+		full := struct {
+			Capacity int `json:"capacity"`
+		}{loc.Capacity}
+		row, err := withColumns(loc, full, columns)
+		if err != nil {
+			return err
+		}
+		rows[i] = row
+	}
+
+	log.Printf("[API] Upserting %d locations\n", len(rows))
+	return c.postRows("locations?on_conflict=location", rows, true)
+}
+
+// withColumns encodes row as JSON fields and adds the named columns from
+// full, which holds them without omitempty, where row left them out.
+func withColumns(row, full interface{}, columns []string) (map[string]json.RawMessage, error) {
+	var fields, all map[string]json.RawMessage
+	if err := remarshal(row, &fields); err != nil {
+		return nil, err
+	}
+	if err := remarshal(full, &all); err != nil {
+		return nil, err
+	}
+	for _, column := range columns {
+		if _, ok := fields[column]; !ok {
+			value, ok := all[column]
+			if !ok {
+				return nil, fmt.Errorf("unknown column %q", column)
+			}
+			fields[column] = value
+		}
+	}
+	return fields, nil
+}
+
+func remarshal(v interface{}, out interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// PostCommits inserts several commits in one request.
+func (c *Client) PostCommits(payloads []CommitPayload) error {
+	rows := make([]CommitPayload, len(payloads))
+	for i, p := range payloads {
+		if p.Site == "" {
+			p.Site = c.Site
+		}
+		rows[i] = p
+	}
+
+	log.Printf("[API] Posting %d commits\n", len(rows))
+	return c.postRows("commits", rows, false)
+}
+
+// postRows POSTs rows to a table, merging on conflict when upsert is set.
+// PostgREST needs every object in a bulk request to have the same keys, and
+// omitted fields must stay untouched on merge, so rows are sent in groups
+// sharing the same set of fields.
+func (c *Client) postRows(path string, rows interface{}, upsert bool) error {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return err
+	}
+
+	var order []string
+	groups := make(map[string][]map[string]json.RawMessage)
+	for _, obj := range objects {
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sig := strings.Join(keys, ",")
+		if _, ok := groups[sig]; !ok {
+			order = append(order, sig)
+		}
+		groups[sig] = append(groups[sig], obj)
+	}
+
+	for _, sig := range order {
+		if err := c.postJSON(path, groups[sig], upsert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) postJSON(path string, v interface{}, upsert bool) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("POST", c.BaseURL+"/rest/v1/"+path, bytes.NewBuffer(data))
+	c.setAuthHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+	if upsert {
+		req.Header.Set("Prefer", "resolution=merge-duplicates,return=minimal")
+	} else {
+		req.Header.Set("Prefer", "return=minimal")
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error: %d %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sources of data returned by the fetch methods.
+const (
+	SourceLive  = "live"  // fetched from the API just now
+	SourceCache = "cache" // read from the local cache after a failed fetch
+)
+
+// Freshness describes where a table's last fetch came from and how old it is.
+type Freshness struct {
+	Source    string
+	FetchedAt time.Time // when the data was last known to match the server
+}
+
+// Age returns how old the data is.
+func (f Freshness) Age() time.Duration {
+	if f.FetchedAt.IsZero() {
+		return 0
+	}
+	return time.Since(f.FetchedAt)
+}
+
+// Offline reports whether the data came from the cache.
+func (f Freshness) Offline() bool {
+	return f.Source == SourceCache
+}
+
+// ExpiredCacheError is returned instead of cached data older than MaxCacheAge.
+type ExpiredCacheError struct {
+	Table     string
+	FetchedAt time.Time
+	MaxAge    time.Duration
+}
+
+func (e *ExpiredCacheError) Error() string {
+	return fmt.Sprintf("cached %s from %s is older than %s", e.Table, e.FetchedAt.Format("2006-01-02 15:04"), e.MaxAge)
+}
+
+// Freshness returns the source and age of the last fetch of a table
+// ("items", "locations" or "overview"). The zero value means it hasn't
+// been fetched yet.
+func (c *Client) Freshness(table string) Freshness {
+	c.freshMu.Lock()
+	defer c.freshMu.Unlock()
+	return c.freshness[table]
+}
+
+func (c *Client) setFreshness(table string, f Freshness) {
+	c.freshMu.Lock()
+	defer c.freshMu.Unlock()
+	if c.freshness == nil {
+		c.freshness = make(map[string]Freshness)
+	}
+	c.freshness[table] = f
+}
+
+func (c *Client) markLive(table string) {
+	c.setFreshness(table, Freshness{Source: SourceLive, FetchedAt: time.Now()})
+}
+
+// useCache records that a table is being served from a cache last known
+// to match the server at timestamp and applies the max-age policy.
+func (c *Client) useCache(table string, timestamp int64) error {
+	fetchedAt := time.Unix(timestamp, 0)
+	c.setFreshness(table, Freshness{Source: SourceCache, FetchedAt: fetchedAt})
+
+	if c.MaxCacheAge > 0 && time.Since(fetchedAt) > c.MaxCacheAge {
+		return &ExpiredCacheError{Table: table, FetchedAt: fetchedAt, MaxAge: c.MaxCacheAge}
+	}
+	return nil
+}
+
+func (c *Client) itemsFallback() ([]Item, error) {
+	cached, err := c.Store.readItems()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.useCache("items", c.Store.syncedAt("items", cached.Timestamp)); err != nil {
+		return nil, err
+	}
+	return cached.Items, nil
+}
+
+func (c *Client) locationsFallback() ([]Location, error) {
+	cached, err := c.Store.readLocations()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.useCache("locations", c.Store.syncedAt("locations", cached.Timestamp)); err != nil {
+		return nil, err
+	}
+	return cached.Locations, nil
+}
+
+func (c *Client) overviewFallback() ([]StockLevel, error) {
+	cached, err := c.Store.readOverview()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.useCache("overview", cached.Timestamp); err != nil {
+		return nil, err
+	}
+	return cached.Rows, nil
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// CommitRecord is a stored commit: the payload plus its ID and timestamp.
+type CommitRecord struct {
+	ID int `json:"id"`
+	CommitPayload
+	CreatedAt Timestamp `json:"created_at"`
+}
+
+// Timestamp reads PostgreSQL timestamps with or without a time zone;
+// ones without are taken as UTC.
+type Timestamp struct {
+	time.Time
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil || s == "" {
+		return err
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999"} {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid timestamp %q", s)
+}
+
+// historyPageSize is how many commits are requested at a time. PostgREST
+// servers may cap responses below it, so paging goes on until a page
+// comes back empty.
+const historyPageSize = 1000
+
+// FetchCommits returns synced commits created in [from, to), oldest first.
+// Zero times leave that end open. locationPrefix, if set, keeps only
+// locations starting with it.
+func (c *Client) FetchCommits(from, to time.Time, locationPrefix string) ([]CommitRecord, error) {
+	log.Println("[API] FetchCommits() called")
+	filter := c.siteFilter()
+	if !from.IsZero() {
+		filter += "&created_at=gte." + url.QueryEscape(from.UTC().Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		filter += "&created_at=lt." + url.QueryEscape(to.UTC().Format(time.RFC3339))
+	}
+	if locationPrefix != "" {
+		filter += "&location=like." + url.QueryEscape(locationPrefix+"*")
+	}
+
+	var commits, page []CommitRecord
+	for offset := 0; ; offset += len(page) {
+		endpoint := fmt.Sprintf("%s/rest/v1/commits?select=*&order=created_at.asc,id.asc&limit=%d&offset=%d%s",
+			c.BaseURL, historyPageSize, offset, filter)
+		req, _ := http.NewRequest("GET", endpoint, nil)
+		c.setAuthHeaders(req)
+
+		resp, err := c.Client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("API error: %d", resp.StatusCode)
+		}
+
+		page = nil
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		commits = append(commits, page...)
+	}
+
+	log.Printf("[API] Fetched %d commits\n", len(commits))
+	return commits, nil
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// PickList is an order to be picked, made up of item + quantity lines.
+type PickList struct {
+	ID        int        `json:"id"`
+	Reference string     `json:"reference"`
+	Status    string     `json:"status"`
+	Lines     []PickLine `json:"pick_lines"`
+}
+
+type PickLine struct {
+	ID       int    `json:"id"`
+	ItemID   int    `json:"item_id"`
+	Location string `json:"location"`
+	Qty      int    `json:"qty"`
+}
+
+// CachedPickLists wraps pick lists with metadata
+type CachedPickLists struct {
+	Timestamp int64      `json:"timestamp"`
+	PickLists []PickList `json:"pick_lists"`
+}
+
+// FetchPickLists returns open pick lists with their lines, falling back to
+// the last fetched lists when offline.
+func (c *Client) FetchPickLists() ([]PickList, error) {
+	log.Println("[API] FetchPickLists() called")
+	endpoint := c.BaseURL + "/rest/v1/pick_lists?select=*,pick_lines(*)&status=eq.open" + c.siteFilter()
+	req, _ := http.NewRequest("GET", endpoint, nil)
+	c.setAuthHeaders(req)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		log.Printf("[API] Request error: %v (trying cache)\n", err)
+		return c.loadPickListsCache()
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		log.Printf("[API] HTTP error %d (trying cache)\n", resp.StatusCode)
+		return c.loadPickListsCache()
+	}
+
+	var lists []PickList
+	err = json.Unmarshal(body, &lists)
+	if err != nil {
+		log.Printf("[API] JSON unmarshal error: %v (trying cache)\n", err)
+		return c.loadPickListsCache()
+	}
+
+	c.Store.write("pick_lists.cache.json", CachedPickLists{
+		Timestamp: time.Now().Unix(),
+		PickLists: lists,
+	})
+
+	log.Printf("[API] Parsed %d pick lists\n", len(lists))
+	return lists, nil
+}
+
+func (c *Client) loadPickListsCache() ([]PickList, error) {
+	var cached CachedPickLists
+	if err := c.Store.read("pick_lists.cache.json", &cached); err != nil {
+		return nil, fmt.Errorf("no pick lists available offline: %w", err)
+	}
+
+	log.Printf("[API] Loaded %d pick lists from cache (cached at %d)\n", len(cached.PickLists), cached.Timestamp)
+	return cached.PickLists, nil
+}
@@ -0,0 +1,403 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Realtime modes for Subscriber.
+const (
+	RealtimeSupabase = "supabase" // Supabase Realtime websocket (postgres_changes)
+	RealtimeSSE      = "sse"      // generic server-sent events endpoint
+)
+
+// RealtimeTables are the tables a Subscriber listens to.
+var RealtimeTables = []string{"commits", "items", "locations"}
+
+// ChangeEvent is a row change pushed by the server.
+type ChangeEvent struct {
+	Table     string          `json:"table"`
+	Type      string          `json:"type"` // INSERT, UPDATE or DELETE
+	Record    json.RawMessage `json:"record"`
+	OldRecord json.RawMessage `json:"old_record,omitempty"` // the deleted row for DELETE
+}
+
+// row returns the record the change is about.
+func (ev ChangeEvent) row() json.RawMessage {
+	if ev.Type == "DELETE" && len(ev.OldRecord) > 0 {
+		return ev.OldRecord
+	}
+	return ev.Record
+}
+
+// Subscriber keeps a realtime connection open, applies pushed changes to
+// the local caches and notifies listeners. It reconnects with backoff
+// whenever the connection drops.
+type Subscriber struct {
+	client *Client
+	mode   string
+	url    string
+
+	mu        sync.Mutex
+	listeners []func(ChangeEvent)
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewSubscriber creates a subscriber. For RealtimeSSE, sseURL is the event
+// stream endpoint; for RealtimeSupabase it is derived from the API URL.
+func (c *Client) NewSubscriber(mode, sseURL string) (*Subscriber, error) {
+	if mode != RealtimeSupabase && mode != RealtimeSSE {
+		return nil, fmt.Errorf("unknown realtime mode %q", mode)
+	}
+	if mode == RealtimeSSE && sseURL == "" {
+		return nil, fmt.Errorf("realtime mode %q needs realtime_url", mode)
+	}
+
+	return &Subscriber{
+		client:   c,
+		mode:     mode,
+		url:      sseURL,
+		stopChan: make(chan struct{}),
+	}, nil
+}
+
+// OnChange registers a listener called after each change is applied to the caches.
+// Listeners run on the subscriber goroutine.
+func (s *Subscriber) OnChange(listener func(ChangeEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *Subscriber) Start() {
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *Subscriber) Stop() {
+	close(s.stopChan)
+	s.wg.Wait()
+}
+
+func (s *Subscriber) stopped() bool {
+	select {
+	case <-s.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Subscriber) run() {
+	defer s.wg.Done()
+
+	backoff := time.Second
+	for !s.stopped() {
+		start := time.Now()
+		var err error
+		if s.mode == RealtimeSupabase {
+			err = s.runSupabase()
+		} else {
+			err = s.runSSE()
+		}
+		if s.stopped() {
+			return
+		}
+
+		// A connection that stayed up for a while resets the backoff
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		log.Printf("[Realtime] Disconnected: %v (reconnecting in %s)\n", err, backoff)
+
+		select {
+		case <-s.stopChan:
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (s *Subscriber) dispatch(ev ChangeEvent) {
+	if err := s.client.ApplyChange(ev); err != nil {
+		log.Printf("[Realtime] Failed to apply %s on %s: %v\n", ev.Type, ev.Table, err)
+		return
+	}
+
+	s.mu.Lock()
+	listeners := append([]func(ChangeEvent){}, s.listeners...)
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(ev)
+	}
+}
+
+// phoenixMessage is the envelope of the Supabase Realtime protocol.
+type phoenixMessage struct {
+	Topic   string          `json:"topic"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+	Ref     string          `json:"ref"`
+}
+
+func (s *Subscriber) runSupabase() error {
+	endpoint := websocketURL(s.client.BaseURL) + "/realtime/v1/websocket?vsn=1.0.0&apikey=" + s.client.APIKey
+	log.Printf("[Realtime] Connecting to %s/realtime/v1/websocket\n", websocketURL(s.client.BaseURL))
+	conn, err := dialWebSocket(endpoint, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var changes []map[string]string
+	for _, table := range RealtimeTables {
+		change := map[string]string{"event": "*", "schema": "public", "table": table}
+		if s.client.Site != "" && table != "items" {
+			change["filter"] = "site=eq." + s.client.Site
+		}
+		changes = append(changes, change)
+	}
+
+	ref := 0
+	send := func(topic, event string, payload interface{}) error {
+		ref++
+		data, _ := json.Marshal(map[string]interface{}{
+			"topic":   topic,
+			"event":   event,
+			"payload": payload,
+			"ref":     strconv.Itoa(ref),
+		})
+		return conn.WriteText(data)
+	}
+
+	err = send("realtime:wms", "phx_join", map[string]interface{}{
+		"config":       map[string]interface{}{"postgres_changes": changes},
+		"access_token": s.client.APIKey,
+	})
+	if err != nil {
+		return err
+	}
+	log.Println("[Realtime] Joined realtime:wms")
+
+	// Heartbeats keep the socket alive; stopping closes the connection to unblock reads
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(25 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-s.stopChan:
+				conn.Close()
+				return
+			case <-ticker.C:
+				send("phoenix", "heartbeat", map[string]interface{}{})
+			}
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(90 * time.Second))
+		data, err := conn.ReadText()
+		if err != nil {
+			return err
+		}
+
+		var msg phoenixMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+
+		switch msg.Event {
+		case "postgres_changes":
+			var payload struct {
+				Data ChangeEvent `json:"data"`
+			}
+			if err := json.Unmarshal(msg.Payload, &payload); err == nil {
+				s.dispatch(payload.Data)
+			}
+		case "INSERT", "UPDATE", "DELETE":
+			// Older Realtime servers send row changes as top-level events
+			var ev ChangeEvent
+			if err := json.Unmarshal(msg.Payload, &ev); err == nil {
+				ev.Type = msg.Event
+				s.dispatch(ev)
+			}
+		case "phx_reply":
+			if strings.Contains(string(msg.Payload), `"status":"error"`) {
+				return fmt.Errorf("join rejected: %s", msg.Payload)
+			}
+		case "phx_error", "phx_close":
+			return fmt.Errorf("channel closed: %s", msg.Event)
+		}
+	}
+}
+
+// runSSE reads a text/event-stream whose data lines are JSON change events:
+//
+//	event: items
+//	data: {"type": "INSERT", "record": {"id": 7, "name": "Bolt"}}
+//
+// The event name is used as the table when the data doesn't include one.
+func (s *Subscriber) runSSE() error {
+	req, err := http.NewRequest("GET", s.url, nil)
+	if err != nil {
+		return err
+	}
+	s.client.setAuthHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+	if s.client.Site != "" {
+		q := req.URL.Query()
+		q.Set("site", s.client.Site)
+		req.URL.RawQuery = q.Encode()
+	}
+
+	// Streams stay open indefinitely, so don't reuse the client's timeout
+	httpClient := &http.Client{Transport: s.client.Client.Transport}
+	log.Printf("[Realtime] Connecting to %s\n", s.url)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	// Stopping closes the body to unblock the scanner
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-done:
+		case <-s.stopChan:
+			resp.Body.Close()
+		}
+	}()
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("SSE error: %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				var ev ChangeEvent
+				if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &ev); err == nil {
+					if ev.Table == "" {
+						ev.Table = event
+					}
+					s.dispatch(ev)
+				}
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("stream ended")
+}
+
+// ApplyChange merges a pushed row change into the local caches: items and
+// locations are upserted or removed, and inserted commits adjust the
+// cached overview. Rows for other sites are ignored.
+func (c *Client) ApplyChange(ev ChangeEvent) error {
+	record := ev.row()
+	var meta struct {
+		Site *string `json:"site"`
+	}
+	json.Unmarshal(record, &meta)
+	if c.Site != "" && meta.Site != nil && *meta.Site != c.Site {
+		return nil
+	}
+
+	c.applyMu.Lock()
+	defer c.applyMu.Unlock()
+
+	switch ev.Table {
+	case "items":
+		var item Item
+		if err := json.Unmarshal(record, &item); err != nil {
+			return err
+		}
+		// The watermark is left alone so a delta sync still picks up
+		// anything missed while disconnected
+		cached, _ := c.Store.readItems()
+		cached.Items = upsert(cached.Items, item, ev.Type == "DELETE", func(i Item) bool { return i.ID == item.ID })
+		return c.Store.saveItems(cached)
+
+	case "locations":
+		var loc Location
+		if err := json.Unmarshal(record, &loc); err != nil {
+			return err
+		}
+		cached, _ := c.Store.readLocations()
+		cached.Locations = upsert(cached.Locations, loc, ev.Type == "DELETE", func(l Location) bool { return l.LocationName == loc.LocationName })
+		return c.Store.saveLocations(cached)
+
+	case "commits":
+		if ev.Type != "INSERT" {
+			return nil
+		}
+		var commit CommitPayload
+		if err := json.Unmarshal(record, &commit); err != nil {
+			return err
+		}
+		rows, _ := c.Store.Overview()
+		found := false
+		for i := range rows {
+			if rows[i].Location == commit.Location && rows[i].ItemID == commit.ItemID {
+				rows[i].Qty += commit.Delta
+				found = true
+				break
+			}
+		}
+		if !found {
+			rows = append(rows, StockLevel{Location: commit.Location, ItemID: commit.ItemID, Qty: commit.Delta})
+		}
+		return c.Store.saveOverview(rows)
+	}
+	return nil
+}
+
+// upsert replaces the element matching match with v, appends v if none
+// matches, or removes the match when remove is set.
+func upsert[T any](list []T, v T, remove bool, match func(T) bool) []T {
+	for i := range list {
+		if match(list[i]) {
+			if remove {
+				return append(list[:i], list[i+1:]...)
+			}
+			list[i] = v
+			return list
+		}
+	}
+	if remove {
+		return list
+	}
+	return append(list, v)
+}
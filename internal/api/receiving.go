@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// PurchaseOrder is expected inbound stock, made up of item + quantity lines.
+type PurchaseOrder struct {
+	ID        int      `json:"id"`
+	Reference string   `json:"reference"`
+	Supplier  string   `json:"supplier"`
+	Status    string   `json:"status"`
+	Lines     []POLine `json:"po_lines"`
+}
+
+type POLine struct {
+	ID       int `json:"id"`
+	ItemID   int `json:"item_id"`
+	Qty      int `json:"qty"`
+	Received int `json:"received"` // synced receipts so far, in base units
+}
+
+// CachedPurchaseOrders wraps purchase orders with metadata
+type CachedPurchaseOrders struct {
+	Timestamp      int64           `json:"timestamp"`
+	PurchaseOrders []PurchaseOrder `json:"purchase_orders"`
+}
+
+// FetchPurchaseOrders returns open purchase orders with their lines, falling
+// back to the last fetched orders when offline.
+func (c *Client) FetchPurchaseOrders() ([]PurchaseOrder, error) {
+	log.Println("[API] FetchPurchaseOrders() called")
+	endpoint := c.BaseURL + "/rest/v1/purchase_orders?select=*,po_lines(*)&status=eq.open" + c.siteFilter()
+	req, _ := http.NewRequest("GET", endpoint, nil)
+	c.setAuthHeaders(req)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		log.Printf("[API] Request error: %v (trying cache)\n", err)
+		return c.loadPurchaseOrdersCache()
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		log.Printf("[API] HTTP error %d (trying cache)\n", resp.StatusCode)
+		return c.loadPurchaseOrdersCache()
+	}
+
+	var orders []PurchaseOrder
+	err = json.Unmarshal(body, &orders)
+	if err != nil {
+		log.Printf("[API] JSON unmarshal error: %v (trying cache)\n", err)
+		return c.loadPurchaseOrdersCache()
+	}
+
+	c.Store.write("purchase_orders.cache.json", CachedPurchaseOrders{
+		Timestamp:      time.Now().Unix(),
+		PurchaseOrders: orders,
+	})
+
+	log.Printf("[API] Parsed %d purchase orders\n", len(orders))
+	return orders, nil
+}
+
+func (c *Client) loadPurchaseOrdersCache() ([]PurchaseOrder, error) {
+	var cached CachedPurchaseOrders
+	if err := c.Store.read("purchase_orders.cache.json", &cached); err != nil {
+		return nil, fmt.Errorf("no purchase orders available offline: %w", err)
+	}
+
+	log.Printf("[API] Loaded %d purchase orders from cache (cached at %d)\n", len(cached.PurchaseOrders), cached.Timestamp)
+	return cached.PurchaseOrders, nil
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultReservationTTL is used for reservations created without an expiry.
+const DefaultReservationTTL = 24 * time.Hour
+
+// Reservation holds stock at a location for an order until it is picked,
+// released or expires.
+type Reservation struct {
+	ID        int       `json:"id,omitempty"`
+	ItemID    int       `json:"item_id"`
+	Location  string    `json:"location"`
+	Qty       int       `json:"qty"`
+	Reference string    `json:"reference"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Released  bool      `json:"released"`
+	Site      string    `json:"site,omitempty"`
+}
+
+// Active reports whether the reservation still holds stock at time t.
+func (r Reservation) Active(t time.Time) bool {
+	return !r.Released && (r.ExpiresAt.IsZero() || r.ExpiresAt.After(t))
+}
+
+// FetchReservations returns reservations that are not released or expired.
+func (c *Client) FetchReservations() ([]Reservation, error) {
+	log.Println("[API] FetchReservations() called")
+	now := time.Now().UTC().Format(time.RFC3339)
+	// Reservations without an expiry hold until released. Values with colons
+	// must be quoted inside a logic tree
+	active := "(expires_at.is.null,expires_at.gt.\"" + now + "\")"
+	endpoint := c.BaseURL + "/rest/v1/reservations?select=*&released=is.false&or=" + url.QueryEscape(active) + c.siteFilter()
+	req, _ := http.NewRequest("GET", endpoint, nil)
+	c.setAuthHeaders(req)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		log.Printf("[API] Request error: %v\n", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API error: %d", resp.StatusCode)
+	}
+
+	var reservations []Reservation
+	err = json.Unmarshal(body, &reservations)
+	if err != nil {
+		log.Printf("[API] JSON unmarshal error: %v\n", err)
+		return nil, err
+	}
+
+	log.Printf("[API] Parsed %d reservations\n", len(reservations))
+	return reservations, nil
+}
+
+// CreateReservation holds stock and returns the stored reservation.
+func (c *Client) CreateReservation(r Reservation) (Reservation, error) {
+	if r.Site == "" {
+		r.Site = c.Site
+	}
+	if r.Qty <= 0 {
+		return Reservation{}, fmt.Errorf("reservation quantity must be positive")
+	}
+	if r.ExpiresAt.IsZero() {
+		r.ExpiresAt = time.Now().Add(DefaultReservationTTL)
+	}
+
+	data, _ := json.Marshal(r)
+	req, _ := http.NewRequest("POST", c.BaseURL+"/rest/v1/reservations", bytes.NewBuffer(data))
+	c.setAuthHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "return=representation")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return Reservation{}, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return Reservation{}, fmt.Errorf("API error: %d", resp.StatusCode)
+	}
+
+	var created []Reservation
+	if err := json.Unmarshal(body, &created); err != nil || len(created) == 0 {
+		return r, nil
+	}
+
+	log.Printf("[API] Reserved %d x item %d at %s for %s\n", r.Qty, r.ItemID, r.Location, r.Reference)
+	return created[0], nil
+}
+
+// ReleaseReservation frees a single reservation.
+func (c *Client) ReleaseReservation(id int) error {
+	return c.releaseWhere("id=eq." + strconv.Itoa(id))
+}
+
+// ReleaseReserved frees qty units held for a reference's item at a
+// location, e.g. those just picked or those a short pick left behind.
+// Reservations are used up oldest first: one it empties is released and
+// the last is shrunk, so the rest of the line stays held.
+func (c *Client) ReleaseReserved(reference string, itemID int, location string, qty int) error {
+	if qty <= 0 {
+		return nil
+	}
+	filter := fmt.Sprintf("reference=eq.%s&item_id=eq.%d&location=eq.%s",
+		url.QueryEscape(reference), itemID, url.QueryEscape(location))
+	req, _ := http.NewRequest("GET", c.BaseURL+"/rest/v1/reservations?select=*&released=is.false&order=id&"+filter, nil)
+	c.setAuthHeaders(req)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("API error: %d", resp.StatusCode)
+	}
+	var held []Reservation
+	if err := json.Unmarshal(body, &held); err != nil {
+		return err
+	}
+
+	for _, r := range held {
+		if qty <= 0 {
+			break
+		}
+		id := "id=eq." + strconv.Itoa(r.ID)
+		if r.Qty <= qty {
+			err = c.updateWhere(id, map[string]interface{}{"released": true})
+		} else {
+			err = c.updateWhere(id, map[string]interface{}{"qty": r.Qty - qty})
+		}
+		if err != nil {
+			return err
+		}
+		qty -= r.Qty
+	}
+	return nil
+}
+
+func (c *Client) releaseWhere(filter string) error {
+	return c.updateWhere(filter, map[string]interface{}{"released": true})
+}
+
+// updateWhere patches the open reservations matching filter.
+func (c *Client) updateWhere(filter string, fields map[string]interface{}) error {
+	data, _ := json.Marshal(fields)
+	req, _ := http.NewRequest("PATCH", c.BaseURL+"/rest/v1/reservations?released=is.false&"+filter, bytes.NewBuffer(data))
+	c.setAuthHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("API error: %d", resp.StatusCode)
+	}
+
+	log.Printf("[API] Updated reservations where %s: %s\n", filter, data)
+	return nil
+}
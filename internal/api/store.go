@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CachedItems wraps items with metadata
+type CachedItems struct {
+	Timestamp int64  `json:"timestamp"`
+	Items     []Item `json:"items"`
+
+	// Watermark is the newest updated_at seen; the next sync only asks for
+	// rows changed after it. FullSync is when the table was last fully downloaded.
+	Watermark string `json:"watermark,omitempty"`
+	FullSync  int64  `json:"full_sync,omitempty"`
+}
+
+// CachedLocations wraps locations with metadata
+type CachedLocations struct {
+	Timestamp int64      `json:"timestamp"`
+	Locations []Location `json:"locations"`
+
+	Watermark string `json:"watermark,omitempty"`
+	FullSync  int64  `json:"full_sync,omitempty"`
+}
+
+// CachedOverview wraps overview rows with metadata
+type CachedOverview struct {
+	Timestamp int64        `json:"timestamp"`
+	Rows      []StockLevel `json:"rows"`
+}
+
+// Store is the local data store: the last fetched items, locations and
+// stock, kept as JSON files in the site's data directory. Screens read it
+// directly when offline; fetch methods keep it up to date.
+type Store struct {
+	dir    string
+	mu     sync.Mutex // guards files shared with the realtime subscriber
+	syncMu sync.Mutex // guards read-modify-write of the sync times
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) path(filename string) string {
+	return filepath.Join(s.dir, filename)
+}
+
+// write replaces a file atomically so a power cut never leaves half a cache.
+func (s *Store) write(filename string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cachePath := s.path(filename)
+	log.Printf("[Store] Saving %s\n", cachePath)
+	tmp := cachePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cachePath)
+}
+
+func (s *Store) read(filename string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(filename))
+	if err != nil {
+		log.Printf("[Store] %s not found: %v\n", filename, err)
+		return err
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		log.Printf("[Store] Failed to parse %s: %v\n", filename, err)
+	}
+	return err
+}
+
+func (s *Store) saveItems(cached CachedItems) error {
+	cached.Timestamp = time.Now().Unix()
+	return s.write("items.cache.json", cached)
+}
+
+func (s *Store) readItems() (CachedItems, error) {
+	var cached CachedItems
+	err := s.read("items.cache.json", &cached)
+	return cached, err
+}
+
+// Items returns the stored items.
+func (s *Store) Items() ([]Item, error) {
+	cached, err := s.readItems()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Store] Loaded %d items (saved at %d)\n", len(cached.Items), cached.Timestamp)
+	return cached.Items, nil
+}
+
+func (s *Store) saveLocations(cached CachedLocations) error {
+	cached.Timestamp = time.Now().Unix()
+	return s.write("locations.cache.json", cached)
+}
+
+func (s *Store) readLocations() (CachedLocations, error) {
+	var cached CachedLocations
+	err := s.read("locations.cache.json", &cached)
+	return cached, err
+}
+
+// Locations returns the stored locations.
+func (s *Store) Locations() ([]Location, error) {
+	cached, err := s.readLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Store] Loaded %d locations (saved at %d)\n", len(cached.Locations), cached.Timestamp)
+	return cached.Locations, nil
+}
+
+func (s *Store) saveOverview(rows []StockLevel) error {
+	return s.write("overview.cache.json", CachedOverview{
+		Timestamp: time.Now().Unix(),
+		Rows:      rows,
+	})
+}
+
+func (s *Store) readOverview() (CachedOverview, error) {
+	var cached CachedOverview
+	err := s.read("overview.cache.json", &cached)
+	return cached, err
+}
+
+// Overview returns the stored on-hand stock rows.
+func (s *Store) Overview() ([]StockLevel, error) {
+	cached, err := s.readOverview()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Store] Loaded %d overview rows (saved at %d)\n", len(cached.Rows), cached.Timestamp)
+	return cached.Rows, nil
+}
+
+// markSynced records that a table was just synced successfully. Delta
+// syncs that change nothing don't rewrite the table's cache, so its
+// timestamp alone would make quiet tables look out of date.
+func (s *Store) markSynced(table string) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	synced := make(map[string]int64)
+	s.read("synced.json", &synced)
+	synced[table] = time.Now().Unix()
+	if err := s.write("synced.json", synced); err != nil {
+		log.Printf("[Store] Failed to record %s sync: %v\n", table, err)
+	}
+}
+
+// syncedAt returns when a table's cache was last known to match the
+// server: the later of its last sync and its last save.
+func (s *Store) syncedAt(table string, saved int64) int64 {
+	synced := make(map[string]int64)
+	s.read("synced.json", &synced)
+	return max(synced[table], saved)
+}
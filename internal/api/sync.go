@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// FullSyncInterval is how long delta syncs are used before a table is
+// downloaded in full again, which also drops rows deleted on the server.
+const FullSyncInterval = 24 * time.Hour
+
+// needsFullSync reports whether a cache must be replaced rather than patched.
+// Tables without an updated_at column never get a watermark and always sync in full.
+func needsFullSync(watermark string, fullSync int64) bool {
+	return watermark == "" || time.Since(time.Unix(fullSync, 0)) > FullSyncInterval
+}
+
+// fetchRows GETs rows of a table changed at or after watermark, or every
+// row if watermark is empty, and decodes them into v. Rows at the
+// watermark itself are fetched again, since another row may have been
+// committed later with the same updated_at; upserting them is harmless.
+func (c *Client) fetchRows(table, filter, watermark string, v interface{}) error {
+	endpoint := c.BaseURL + "/rest/v1/" + table + "?select=*" + filter
+	if watermark != "" {
+		endpoint += "&updated_at=gte." + url.QueryEscape(watermark)
+	}
+	req, _ := http.NewRequest("GET", endpoint, nil)
+	c.setAuthHeaders(req)
+
+	log.Printf("[API] Making request to: %s\n", endpoint)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("API error: %d", resp.StatusCode)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// laterTimestamp returns whichever of two updated_at values is newer.
+func laterTimestamp(a, b string) string {
+	ta, errA := time.Parse(time.RFC3339Nano, a)
+	tb, errB := time.Parse(time.RFC3339Nano, b)
+	switch {
+	case errB != nil:
+		return a
+	case errA != nil || tb.After(ta):
+		return b
+	}
+	return a
+}
+
+// unchangedDropped returns the fetched rows that differ from the cached
+// ones, leaving out the boundary rows the inclusive watermark fetches again.
+func unchangedDropped[T any](cached, fetched []T, same func(a, b T) bool) []T {
+	var changed []T
+	for _, row := range fetched {
+		i := slices.IndexFunc(cached, func(c T) bool { return same(c, row) })
+		if i < 0 || !reflect.DeepEqual(cached[i], row) {
+			changed = append(changed, row)
+		}
+	}
+	return changed
+}
+
+func (c *Client) syncItems() ([]Item, error) {
+	cached, _ := c.Store.readItems()
+	full := needsFullSync(cached.Watermark, cached.FullSync)
+	watermark := cached.Watermark
+	if full {
+		watermark = ""
+	}
+
+	var changed []Item
+	if err := c.fetchRows("items", c.sharedSiteFilter(), watermark, &changed); err != nil {
+		return nil, err
+	}
+
+	c.applyMu.Lock()
+	defer c.applyMu.Unlock()
+
+	// Re-read in case realtime changes were applied during the request
+	cached, _ = c.Store.readItems()
+	if full {
+		cached = CachedItems{Items: changed, FullSync: time.Now().Unix()}
+	} else {
+		changed = unchangedDropped(cached.Items, changed, func(a, b Item) bool { return a.ID == b.ID })
+		for _, item := range changed {
+			cached.Items = upsert(cached.Items, item, false, func(i Item) bool { return i.ID == item.ID })
+		}
+	}
+	for _, item := range changed {
+		cached.Watermark = laterTimestamp(cached.Watermark, item.UpdatedAt)
+	}
+
+	log.Printf("[API] Synced items: %d changed (full=%v)\n", len(changed), full)
+	// A full sync replaces the cache even when it comes back empty, so
+	// rows deleted on the server go and FullSync is stamped
+	if full || len(changed) > 0 {
+		c.Store.saveItems(cached)
+	}
+	c.Store.markSynced("items")
+	return cached.Items, nil
+}
+
+func (c *Client) syncLocations() ([]Location, error) {
+	cached, _ := c.Store.readLocations()
+	full := needsFullSync(cached.Watermark, cached.FullSync)
+	watermark := cached.Watermark
+	if full {
+		watermark = ""
+	}
+
+	var changed []Location
+	if err := c.fetchRows("locations", c.siteFilter(), watermark, &changed); err != nil {
+		return nil, err
+	}
+
+	c.applyMu.Lock()
+	defer c.applyMu.Unlock()
+
+	cached, _ = c.Store.readLocations()
+	if full {
+		cached = CachedLocations{Locations: changed, FullSync: time.Now().Unix()}
+	} else {
+		changed = unchangedDropped(cached.Locations, changed, func(a, b Location) bool { return a.LocationName == b.LocationName })
+		for _, loc := range changed {
+			cached.Locations = upsert(cached.Locations, loc, false, func(l Location) bool { return l.LocationName == loc.LocationName })
+		}
+	}
+	for _, loc := range changed {
+		cached.Watermark = laterTimestamp(cached.Watermark, loc.UpdatedAt)
+	}
+
+	log.Printf("[API] Synced locations: %d changed (full=%v)\n", len(changed), full)
+	if full || len(changed) > 0 {
+		c.Store.saveLocations(cached)
+	}
+	c.Store.markSynced("locations")
+	return cached.Locations, nil
+}
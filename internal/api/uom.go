@@ -0,0 +1,46 @@
+package api
+
+import "fmt"
+
+// DefaultBaseUnit is used for items that don't define a base unit.
+const DefaultBaseUnit = "EA"
+
+// UnitOfMeasure is an alternative unit an item is handled in,
+// e.g. a case of 24 has Factor 24 base units.
+type UnitOfMeasure struct {
+	Name   string `json:"name"`
+	Factor int    `json:"factor"`
+}
+
+// Base returns the item's base unit name.
+func (i Item) Base() string {
+	if i.BaseUnit == "" {
+		return DefaultBaseUnit
+	}
+	return i.BaseUnit
+}
+
+// UnitNames lists the base unit followed by every valid conversion unit.
+func (i Item) UnitNames() []string {
+	names := []string{i.Base()}
+	for _, u := range i.Units {
+		if u.Name == "" || u.Name == i.Base() || u.Factor <= 0 {
+			continue
+		}
+		names = append(names, u.Name)
+	}
+	return names
+}
+
+// ToBaseUnits converts qty expressed in unit to the item's base unit.
+func (i Item) ToBaseUnits(unit string, qty int) (int, error) {
+	if unit == "" || unit == i.Base() {
+		return qty, nil
+	}
+	for _, u := range i.Units {
+		if u.Name == unit && u.Factor > 0 {
+			return qty * u.Factor, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown unit %q for item %d", unit, i.ID)
+}
@@ -0,0 +1,68 @@
+// Package barcode encodes the Code 128 and QR symbols printed on labels
+// and decodes QR, DataMatrix, Code 128 and EAN-13 from camera frames and
+// image files, in pure Go.
+package barcode
+
+import (
+	"fmt"
+	"image"
+	"io"
+)
+
+// Symbologies Decode recognises.
+const (
+	FormatQR         = "QR"
+	FormatDataMatrix = "DataMatrix"
+	FormatCode128    = "Code128"
+	FormatEAN13      = "EAN-13"
+)
+
+// Result is one decoded symbol.
+type Result struct {
+	Format string
+	Text   string
+}
+
+// Decode finds and decodes every symbol it can in img. Each distinct
+// result is returned once, 2D symbols first.
+func Decode(img image.Image) []Result {
+	lum := newLuminance(img)
+	// Local thresholds handle uneven light; the global one catches modules
+	// too big for the local window
+	for _, bm := range []*bitmap{lum.localThreshold(), lum.globalThreshold()} {
+		var results []Result
+		for _, text := range decodeQR(bm) {
+			results = append(results, Result{FormatQR, text})
+		}
+		for _, text := range decodeDataMatrix(bm) {
+			results = append(results, Result{FormatDataMatrix, text})
+		}
+		results = append(results, decodeLinear(bm)...)
+		if len(results) > 0 {
+			return dedupe(results)
+		}
+	}
+	return nil
+}
+
+// DecodeFile decodes a PNG, JPEG or GIF image.
+func DecodeFile(r io.Reader) ([]Result, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a readable image: %w", err)
+	}
+	return Decode(img), nil
+}
+
+// dedupe drops repeated results, keeping the first of each.
+func dedupe(results []Result) []Result {
+	seen := make(map[Result]bool)
+	var out []Result
+	for _, r := range results {
+		if !seen[r] {
+			seen[r] = true
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package barcode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeFile(t *testing.T) {
+	tests := []struct {
+		file string
+		want []Result
+	}{
+		{"qr.png", []Result{{FormatQR, "LOC-12-03-B"}}},
+		{"qr_rotated.png", []Result{{FormatQR, "ITEM-000123"}}},
+		{"qr_skewed.png", []Result{{FormatQR, "https://wms.example/items/ITEM-000123"}}},
+		{"datamatrix.png", []Result{{FormatDataMatrix, "A-01-02"}}},
+		{"datamatrix_rotated.png", []Result{{FormatDataMatrix, "LOC-0012-B"}}},
+		{"code128.png", []Result{{FormatCode128, "ITEM-000123"}}},
+		{"code128_upside_down.png", []Result{{FormatCode128, "ITEM-000123"}}},
+		{"ean13.png", []Result{{FormatEAN13, "4006381333931"}}},
+		// 4006381333932: the bars are valid but the check digit is wrong
+		{"ean13_bad_checksum.png", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got, err := DecodeFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeFile(%s) = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeFileNotImage(t *testing.T) {
+	f, err := os.Open("barcode_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := DecodeFile(f); err == nil {
+		t.Error("DecodeFile accepted a file that isn't an image")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []string{"A-01-02", "LOC-12-03-B", "ITEM-000123", "1234567890"}
+	for _, data := range tests {
+		t.Run(data, func(t *testing.T) {
+			grid, err := QR(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := readQR(grid); err != nil || got != data {
+				t.Errorf("QR: read %q, %v", got, err)
+			}
+
+			modules, err := Code128(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Runs start with the light quiet zone, as scanned lines do
+			runs := []int{10}
+			last := false
+			for _, dark := range modules {
+				if dark != last {
+					runs = append(runs, 0)
+					last = dark
+				}
+				runs[len(runs)-1]++
+			}
+			runs = append(runs, 10)
+			if got := decodeCode128(runs); len(got) != 1 || got[0] != data {
+				t.Errorf("Code128: read %q", got)
+			}
+		})
+	}
+}
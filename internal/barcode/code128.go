@@ -0,0 +1,100 @@
+package barcode
+
+import "fmt"
+
+// code128Patterns are the bar and space widths of each Code 128 symbol,
+// indexed by value. 103-105 are the start codes and 106 is the stop.
+var code128Patterns = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128CodeC  = 99
+	code128CodeB  = 100
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// Code128 encodes printable ASCII as Code 128 and returns its modules,
+// true for a bar, without quiet zones. Runs of four or more digits use
+// code set C, which packs two digits per symbol.
+func Code128(data string) ([]bool, error) {
+	if data == "" {
+		return nil, fmt.Errorf("nothing to encode")
+	}
+	for _, c := range data {
+		if c < 32 || c > 126 {
+			return nil, fmt.Errorf("code 128 can't encode %q", c)
+		}
+	}
+
+	var values []int
+	set := 0
+	for i := 0; i < len(data); {
+		digits := 0
+		for i+digits < len(data) && data[i+digits] >= '0' && data[i+digits] <= '9' {
+			digits++
+		}
+
+		if digits >= 4 || (digits >= 2 && digits == len(data)) {
+			if digits%2 == 1 {
+				// Odd runs start with one digit in code set B
+				values, set = code128Switch(values, set, code128CodeB)
+				values = append(values, int(data[i])-32)
+				i++
+				digits--
+			}
+			values, set = code128Switch(values, set, code128CodeC)
+			for ; digits > 0; digits -= 2 {
+				values = append(values, int(data[i]-'0')*10+int(data[i+1]-'0'))
+				i += 2
+			}
+			continue
+		}
+
+		values, set = code128Switch(values, set, code128CodeB)
+		values = append(values, int(data[i])-32)
+		i++
+	}
+
+	checksum := values[0]
+	for i, v := range values[1:] {
+		checksum += (i + 1) * v
+	}
+	values = append(values, checksum%103, code128Stop)
+
+	var modules []bool
+	for _, v := range values {
+		for i, w := range code128Patterns[v] {
+			for n := 0; n < int(w-'0'); n++ {
+				modules = append(modules, i%2 == 0)
+			}
+		}
+	}
+	return modules, nil
+}
+
+// code128Switch starts or changes to a code set (code128CodeB or
+// code128CodeC) if it isn't already active.
+func code128Switch(values []int, current, set int) ([]int, int) {
+	switch {
+	case current == set:
+		return values, set
+	case current == 0 && set == code128CodeB:
+		return append(values, code128StartB), set
+	case current == 0:
+		return append(values, code128StartC), set
+	}
+	return append(values, set), set
+}
@@ -0,0 +1,729 @@
+package barcode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// dmSize is one ECC 200 symbol size.
+type dmSize struct {
+	rows, cols             int
+	regionRows, regionCols int // data region size, without its border
+	data, ecc, blocks      int // codewords
+}
+
+var dmSizes = []dmSize{
+	{10, 10, 8, 8, 3, 5, 1},
+	{12, 12, 10, 10, 5, 7, 1},
+	{14, 14, 12, 12, 8, 10, 1},
+	{16, 16, 14, 14, 12, 12, 1},
+	{18, 18, 16, 16, 18, 14, 1},
+	{20, 20, 18, 18, 22, 18, 1},
+	{22, 22, 20, 20, 30, 20, 1},
+	{24, 24, 22, 22, 36, 24, 1},
+	{26, 26, 24, 24, 44, 28, 1},
+	{32, 32, 14, 14, 62, 36, 1},
+	{36, 36, 16, 16, 86, 42, 1},
+	{40, 40, 18, 18, 114, 48, 1},
+	{44, 44, 20, 20, 144, 56, 1},
+	{48, 48, 22, 22, 174, 68, 1},
+	{52, 52, 24, 24, 204, 84, 2},
+	{64, 64, 14, 14, 280, 112, 2},
+	{72, 72, 16, 16, 368, 144, 4},
+	{80, 80, 18, 18, 456, 192, 4},
+	{88, 88, 20, 20, 576, 224, 4},
+	{96, 96, 22, 22, 696, 272, 4},
+	{104, 104, 24, 24, 816, 336, 6},
+	{120, 120, 18, 18, 1050, 408, 6},
+	{132, 132, 20, 20, 1304, 496, 8},
+	{144, 144, 22, 22, 1558, 620, 10},
+	{8, 18, 6, 16, 5, 7, 1},
+	{8, 32, 6, 14, 10, 11, 1},
+	{12, 26, 10, 24, 16, 14, 1},
+	{12, 36, 10, 16, 22, 18, 1},
+	{16, 36, 14, 16, 32, 24, 1},
+	{16, 48, 14, 22, 49, 28, 1},
+}
+
+// decodeDataMatrix finds and decodes the DataMatrix symbols in a bitmap.
+// Symbols are located by growing a box from points across the image until
+// it is surrounded by light, so one in the middle of the frame, as when
+// aiming a camera, is found first.
+func decodeDataMatrix(bm *bitmap) []string {
+	var texts []string
+	var tried [][4]int
+	seeds := [][2]int{{bm.w / 2, bm.h / 2}}
+	for _, fy := range []int{1, 2, 3} {
+		for _, fx := range []int{1, 2, 3} {
+			seeds = append(seeds, [2]int{bm.w * fx / 4, bm.h * fy / 4})
+		}
+	}
+	for _, seed := range seeds {
+		box, ok := lightBox(bm, seed[0], seed[1])
+		if !ok {
+			continue
+		}
+		seen := false
+		for _, t := range tried {
+			seen = seen || t == box
+		}
+		if seen {
+			continue
+		}
+		tried = append(tried, box)
+		if text, err := readDataMatrixAt(bm, box); err == nil {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// lightBox grows a box from a point until each side runs through light
+// modules only, returning it as left, top, right, bottom.
+func lightBox(bm *bitmap, x, y int) ([4]int, bool) {
+	const initial = 10
+	left, top, right, bottom := x-initial/2, y-initial/2, x+initial/2, y+initial/2
+	if left < 0 || top < 0 || right >= bm.w || bottom >= bm.h {
+		return [4]int{}, false
+	}
+	anyDark := func(x0, y0, x1, y1 int) bool {
+		for yy := y0; yy <= y1; yy++ {
+			for xx := x0; xx <= x1; xx++ {
+				if bm.at(xx, yy) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	sawDark := false
+	for grew := true; grew; {
+		grew = false
+		for right < bm.w-1 && anyDark(right, top, right, bottom) {
+			right++
+			grew, sawDark = true, true
+		}
+		for bottom < bm.h-1 && anyDark(left, bottom, right, bottom) {
+			bottom++
+			grew, sawDark = true, true
+		}
+		for left > 0 && anyDark(left, top, left, bottom) {
+			left--
+			grew, sawDark = true, true
+		}
+		for top > 0 && anyDark(left, top, right, top) {
+			top--
+			grew, sawDark = true, true
+		}
+		if right == bm.w-1 || bottom == bm.h-1 || left == 0 || top == 0 {
+			return [4]int{}, false // ran into the edge of the frame
+		}
+	}
+	return [4]int{left, top, right, bottom}, sawDark && right-left >= 10 && bottom-top >= 10
+}
+
+// quadCorners returns the corners of the dark shape in a box, in order
+// around it. It takes the furthest dark pixels diagonally, or along the
+// axes for a symbol turned near 45 degrees, whichever spans more.
+func quadCorners(bm *bitmap, box [4]int) [4]point {
+	diagonal := [4]point{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}
+	straight := [4]point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+	var best [4]point
+	bestArea := -1.0
+	for _, dirs := range [][4]point{diagonal, straight} {
+		var corners [4]point
+		for i, d := range dirs {
+			furthest := math.Inf(-1)
+			for y := box[1]; y <= box[3]; y++ {
+				for x := box[0]; x <= box[2]; x++ {
+					if !bm.at(x, y) {
+						continue
+					}
+					// The pixel's outer corner or edge in that direction
+					p := point{float64(x) + 0.5 + d.x/2, float64(y) + 0.5 + d.y/2}
+					if reach := p.x*d.x + p.y*d.y; reach > furthest {
+						furthest, corners[i] = reach, p
+					}
+				}
+			}
+		}
+		area := 0.0
+		for i := range corners {
+			area += corners[i].cross(corners[(i+1)%4])
+		}
+		if area = math.Abs(area) / 2; area > bestArea {
+			best, bestArea = corners, area
+		}
+	}
+	return best
+}
+
+// dmCandidate is a guess at a symbol's orientation and size.
+type dmCandidate struct {
+	corners [4]point // top left, top right, bottom right, bottom left
+	size    dmSize
+	score   float64
+}
+
+func (c dmCandidate) transform() transform {
+	cols, rows := float64(c.size.cols), float64(c.size.rows)
+	return quadToQuad([4]point{{0, 0}, {cols, 0}, {cols, rows}, {0, rows}}, c.corners)
+}
+
+// borderScore is the fraction of the border modules that match the solid
+// left and bottom edges and the alternating top and right ones.
+func (c dmCandidate) borderScore(bm *bitmap) float64 {
+	t := c.transform()
+	rows, cols := c.size.rows, c.size.cols
+	match, total := 0, 0
+	check := func(col, row int, dark bool) {
+		p := t.apply(point{float64(col) + 0.5, float64(row) + 0.5})
+		if bm.atf(p.x, p.y) == dark {
+			match++
+		}
+		total++
+	}
+	for row := 0; row < rows; row++ {
+		check(0, row, true)
+		check(cols-1, row, (rows-1-row)%2 == 0)
+	}
+	for col := 1; col < cols-1; col++ {
+		check(col, rows-1, true)
+		check(col, 0, col%2 == 0)
+	}
+	return float64(match) / float64(total)
+}
+
+// readDataMatrixAt decodes the symbol in a box.
+func readDataMatrixAt(bm *bitmap, box [4]int) (string, error) {
+	q := quadCorners(bm, box)
+	var candidates []dmCandidate
+	for i := 0; i < 4; i++ {
+		// Either neighbour of the L's corner could be its top
+		for _, turn := range []int{1, 3} {
+			bl, tl, br := q[i], q[(i+turn)%4], q[(i+4-turn)%4]
+			// The top right corner module is light, so the dark pixel
+			// furthest that way can be a module off the true corner.
+			// Completing the parallelogram is exact without perspective.
+			for _, size := range dmSizes {
+				best := dmCandidate{}
+				for _, tr := range []point{q[(i+2)%4], tl.add(br.sub(bl))} {
+					c := dmCandidate{corners: [4]point{tl, tr, br, bl}, size: size}
+					if c.score = c.borderScore(bm); c.score > best.score {
+						best = c
+					}
+				}
+				if best.score >= 0.8 {
+					candidates = append(candidates, best)
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	err := errors.New("no DataMatrix symbol")
+	for i, c := range candidates {
+		if i == 3 {
+			break
+		}
+		c.refineTopRight(bm)
+		for pass := 0; pass < 2; pass++ {
+			c.fitEdges(bm)
+		}
+		grid, ok := sampleGrid(bm, c.transform(), c.size.cols, c.size.rows)
+		if !ok {
+			continue
+		}
+		var text string
+		if text, err = readDataMatrix(grid, c.size); err == nil {
+			return text, nil
+		}
+	}
+	return "", err
+}
+
+// refineTopRight moves the estimated top right corner to where the
+// border fits best.
+func (c *dmCandidate) refineTopRight(bm *bitmap) {
+	tl, br, bl := c.corners[0], c.corners[2], c.corners[3]
+	u := br.sub(bl).scale(1 / float64(c.size.cols))
+	v := bl.sub(tl).scale(1 / float64(c.size.rows))
+	start, best := c.corners[1], c.score
+	for j := -8; j <= 8; j++ {
+		for i := -8; i <= 8; i++ {
+			c.corners[1] = start.add(u.scale(float64(i) / 4)).add(v.scale(float64(j) / 4))
+			if score := c.borderScore(bm); score > best {
+				best = score
+			}
+		}
+	}
+	// Among equally good corners take the middle one
+	var sum point
+	n := 0
+	for j := -8; j <= 8; j++ {
+		for i := -8; i <= 8; i++ {
+			c.corners[1] = start.add(u.scale(float64(i) / 4)).add(v.scale(float64(j) / 4))
+			if c.borderScore(bm) == best {
+				sum, n = sum.add(c.corners[1]), n+1
+			}
+		}
+	}
+	c.corners[1] = sum.scale(1 / float64(n))
+	c.score = best
+}
+
+// fitEdges moves the corners to where the symbol's edges cross. Each
+// edge is fitted to the outer boundary of its dark modules, which is more
+// precise than the pixels furthest out.
+func (c *dmCandidate) fitEdges(bm *bitmap) {
+	t := c.transform()
+	rows, cols := c.size.rows, c.size.cols
+	boundary := func(col, row int, out point) (point, bool) {
+		centre := point{float64(col) + 0.5, float64(row) + 0.5}
+		p := t.apply(centre)
+		edge := t.apply(centre.add(out.scale(0.5)))
+		step := edge.sub(p).scale(0.125)
+		if !bm.atf(p.x, p.y) {
+			return point{}, false
+		}
+		for i := 1; i <= 16; i++ {
+			q := p.add(step.scale(float64(i)))
+			if !bm.atf(q.x, q.y) {
+				return q.sub(step.scale(0.5)), true
+			}
+		}
+		return point{}, false
+	}
+
+	var top, right, bottom, left []point
+	for row := 0; row < rows; row++ {
+		if p, ok := boundary(0, row, point{-1, 0}); ok {
+			left = append(left, p)
+		}
+		if (rows-1-row)%2 == 0 {
+			if p, ok := boundary(cols-1, row, point{1, 0}); ok {
+				right = append(right, p)
+			}
+		}
+	}
+	for col := 0; col < cols; col++ {
+		if p, ok := boundary(col, rows-1, point{0, 1}); ok {
+			bottom = append(bottom, p)
+		}
+		if col%2 == 0 {
+			if p, ok := boundary(col, 0, point{0, -1}); ok {
+				top = append(top, p)
+			}
+		}
+	}
+
+	var lines [4][2]point
+	for i, points := range [][]point{top, right, bottom, left} {
+		if len(points) < 2 {
+			return
+		}
+		lines[i][0], lines[i][1] = fitLine(points)
+	}
+	var corners [4]point
+	for i := range corners {
+		// Top left is where the left and top edges cross, and so on
+		p, ok := intersect(lines[(i+3)%4], lines[i])
+		if !ok {
+			return
+		}
+		corners[i] = p
+	}
+	fitted := *c
+	fitted.corners = corners
+	if fitted.score = fitted.borderScore(bm); fitted.score >= c.score {
+		*c = fitted
+	}
+}
+
+// fitLine returns a point on the least squares line through points and
+// its direction.
+func fitLine(points []point) (point, point) {
+	var mean point
+	for _, p := range points {
+		mean = mean.add(p)
+	}
+	mean = mean.scale(1 / float64(len(points)))
+	var sxx, sxy, syy float64
+	for _, p := range points {
+		d := p.sub(mean)
+		sxx += d.x * d.x
+		sxy += d.x * d.y
+		syy += d.y * d.y
+	}
+	// The direction of greatest spread
+	angle := math.Atan2(2*sxy, sxx-syy) / 2
+	return mean, point{math.Cos(angle), math.Sin(angle)}
+}
+
+func intersect(a, b [2]point) (point, bool) {
+	denom := a[1].cross(b[1])
+	if math.Abs(denom) < 1e-9 {
+		return point{}, false
+	}
+	t := b[0].sub(a[0]).cross(b[1]) / denom
+	return a[0].add(a[1].scale(t)), true
+}
+
+// readDataMatrix decodes a sampled symbol.
+func readDataMatrix(grid [][]bool, s dmSize) (string, error) {
+	// Drop the finder and timing borders around each data region
+	rr, rc := s.regionRows, s.regionCols
+	nrow, ncol := s.rows/(rr+2)*rr, s.cols/(rc+2)*rc
+	mapping := make([][]bool, nrow)
+	for r := range mapping {
+		mapping[r] = make([]bool, ncol)
+		for c := range mapping[r] {
+			mapping[r][c] = grid[r/rr*(rr+2)+1+r%rr][c/rc*(rc+2)+1+c%rc]
+		}
+	}
+
+	positions := dmPlacement(nrow, ncol)
+	codewords := make([]byte, s.data+s.ecc)
+	for i := range codewords {
+		for bit, p := range positions[i] {
+			if mapping[p[0]][p[1]] {
+				codewords[i] |= 0x80 >> bit
+			}
+		}
+	}
+
+	// Blocks are interleaved a codeword at a time
+	data := codewords[:s.data]
+	for b := 0; b < s.blocks; b++ {
+		var block []byte
+		for i := b; i < s.data; i += s.blocks {
+			block = append(block, codewords[i])
+		}
+		nData := len(block)
+		for i := b; i < s.ecc; i += s.blocks {
+			block = append(block, codewords[s.data+i])
+		}
+		if err := dmField.correct(block, len(block)-nData, 1); err != nil {
+			return "", err
+		}
+		for j, i := 0, b; i < s.data; j, i = j+1, i+s.blocks {
+			data[i] = block[j]
+		}
+	}
+	return parseDataMatrix(data)
+}
+
+// dmPlacement returns the row and column of each bit of each codeword,
+// most significant first, following the standard's placement algorithm.
+func dmPlacement(nrow, ncol int) [][8][2]int {
+	var positions [][8][2]int
+	used := make([]bool, nrow*ncol)
+	module := func(row, col, chr, bit int) {
+		if row < 0 {
+			row += nrow
+			col += 4 - (nrow+4)%8
+		}
+		if col < 0 {
+			col += ncol
+			row += 4 - (ncol+4)%8
+		}
+		for len(positions) <= chr {
+			positions = append(positions, [8][2]int{})
+		}
+		positions[chr][bit] = [2]int{row, col}
+		used[row*ncol+col] = true
+	}
+	place := func(chr int, cells [8][2]int) {
+		for bit, c := range cells {
+			module(c[0], c[1], chr, bit)
+		}
+	}
+	utah := func(row, col, chr int) {
+		place(chr, [8][2]int{
+			{row - 2, col - 2}, {row - 2, col - 1}, {row - 1, col - 2}, {row - 1, col - 1},
+			{row - 1, col}, {row, col - 2}, {row, col - 1}, {row, col},
+		})
+	}
+
+	chr, row, col := 0, 4, 0
+	for {
+		switch {
+		case row == nrow && col == 0:
+			place(chr, [8][2]int{{nrow - 1, 0}, {nrow - 1, 1}, {nrow - 1, 2}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 1}, {2, ncol - 1}, {3, ncol - 1}})
+			chr++
+		case row == nrow-2 && col == 0 && ncol%4 != 0:
+			place(chr, [8][2]int{{nrow - 3, 0}, {nrow - 2, 0}, {nrow - 1, 0}, {0, ncol - 4}, {0, ncol - 3}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 1}})
+			chr++
+		case row == nrow-2 && col == 0 && ncol%8 == 4:
+			place(chr, [8][2]int{{nrow - 3, 0}, {nrow - 2, 0}, {nrow - 1, 0}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 1}, {2, ncol - 1}, {3, ncol - 1}})
+			chr++
+		case row == nrow+4 && col == 2 && ncol%8 == 0:
+			place(chr, [8][2]int{{nrow - 1, 0}, {nrow - 1, ncol - 1}, {0, ncol - 3}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 3}, {1, ncol - 2}, {1, ncol - 1}})
+			chr++
+		}
+
+		// Diagonally up and right, then down and left
+		for {
+			if row < nrow && col >= 0 && !used[row*ncol+col] {
+				utah(row, col, chr)
+				chr++
+			}
+			row, col = row-2, col+2
+			if row < 0 || col >= ncol {
+				break
+			}
+		}
+		row, col = row+1, col+3
+		for {
+			if row >= 0 && col < ncol && !used[row*ncol+col] {
+				utah(row, col, chr)
+				chr++
+			}
+			row, col = row+2, col-2
+			if row >= nrow || col < 0 {
+				break
+			}
+		}
+		row, col = row+3, col+1
+		if row >= nrow && col >= ncol {
+			break
+		}
+	}
+	return positions
+}
+
+// ASCII encodation values with special meanings.
+const (
+	dmPad          = 129
+	dmLatchC40     = 230
+	dmLatchBase    = 231
+	dmFNC1         = 232
+	dmAppend       = 233
+	dmReaderProg   = 234
+	dmUpperShift   = 235
+	dmMacro05      = 236
+	dmMacro06      = 237
+	dmLatchX12     = 238
+	dmLatchText    = 239
+	dmLatchEDIFACT = 240
+	dmECI          = 241
+	dmUnlatch      = 254
+)
+
+// dmText collects decoded characters, applying upper shifts.
+type dmText struct {
+	out   []byte
+	upper bool // add 128 to the next character
+}
+
+func (t *dmText) emit(c int) {
+	if t.upper {
+		c += 128
+		t.upper = false
+	}
+	t.out = append(t.out, byte(c))
+}
+
+// parseDataMatrix decodes the data codewords, switching between the
+// encodation modes as they latch.
+func parseDataMatrix(data []byte) (string, error) {
+	t := &dmText{}
+	trailer := ""
+	for i := 0; i < len(data); {
+		c := int(data[i])
+		i++
+		switch {
+		case c >= 1 && c <= 128:
+			t.emit(c - 1)
+		case c == dmPad:
+			i = len(data)
+		case c >= 130 && c <= 229:
+			t.out = fmt.Appendf(t.out, "%02d", c-130)
+		case c == dmLatchC40, c == dmLatchText:
+			i = dmDecodeC40(data, i, c == dmLatchText, t)
+		case c == dmLatchX12:
+			i = dmDecodeX12(data, i, t)
+		case c == dmLatchEDIFACT:
+			i = dmDecodeEDIFACT(data, i, t)
+		case c == dmLatchBase:
+			var err error
+			if i, err = dmDecodeBase256(data, i, t); err != nil {
+				return "", err
+			}
+		case c == dmFNC1:
+			// Leading FNC1 marks GS1 data; later ones separate its fields
+			if i > 1 {
+				t.out = append(t.out, 0x1d)
+			}
+		case c == dmUpperShift:
+			t.upper = true
+		case c == dmMacro05, c == dmMacro06:
+			t.out = fmt.Appendf(t.out, "[)>\x1e%02d\x1d", c-dmMacro05+5)
+			trailer = "\x1e\x04"
+		case c == dmAppend:
+			i += 3 // structured append: position and file ID
+		case c == dmECI:
+			// The assignment number takes one to three codewords
+			switch {
+			case i < len(data) && data[i] <= 127:
+				i++
+			case i < len(data) && data[i] <= 191:
+				i += 2
+			default:
+				i += 3
+			}
+		case c == dmReaderProg:
+		default:
+			return "", fmt.Errorf("invalid DataMatrix codeword %d", c)
+		}
+	}
+	return qrText(append(t.out, trailer...)), nil
+}
+
+// dmDecodeC40 decodes C40 or Text mode, three values to two codewords,
+// from data[i] until it unlatches, returning where ASCII mode resumes.
+func dmDecodeC40(data []byte, i int, text bool, t *dmText) int {
+	shift := 0
+	for i+1 < len(data) {
+		if data[i] == dmUnlatch {
+			return i + 1
+		}
+		v := int(data[i])<<8 + int(data[i+1]) - 1
+		i += 2
+		for _, c := range [3]int{v / 1600, v / 40 % 40, v % 40} {
+			switch shift {
+			case 0:
+				switch {
+				case c < 3:
+					shift = c + 1
+					continue
+				case c == 3:
+					t.emit(' ')
+				case c < 14:
+					t.emit('0' + c - 4)
+				case text:
+					t.emit('a' + c - 14)
+				default:
+					t.emit('A' + c - 14)
+				}
+			case 1:
+				t.emit(c)
+			case 2:
+				switch {
+				case c < 15:
+					t.emit('!' + c)
+				case c < 22:
+					t.emit(':' + c - 15)
+				case c < 27:
+					t.emit('[' + c - 22)
+				case c == 27:
+					t.emit(0x1d) // FNC1
+				case c == 30:
+					t.upper = true
+				}
+			case 3:
+				switch {
+				case !text:
+					t.emit('`' + c)
+				case c == 0:
+					t.emit('`')
+				case c < 27:
+					t.emit('A' + c - 1)
+				default:
+					t.emit('{' + c - 27)
+				}
+			}
+			shift = 0
+		}
+	}
+	// A last odd codeword is in ASCII without an unlatch
+	return i
+}
+
+// dmDecodeX12 decodes ANSI X12 mode, like C40 but with a fixed set.
+func dmDecodeX12(data []byte, i int, t *dmText) int {
+	for i+1 < len(data) {
+		if data[i] == dmUnlatch {
+			return i + 1
+		}
+		v := int(data[i])<<8 + int(data[i+1]) - 1
+		i += 2
+		for _, c := range [3]int{v / 1600, v / 40 % 40, v % 40} {
+			switch {
+			case c == 0:
+				t.emit('\r')
+			case c == 1:
+				t.emit('*')
+			case c == 2:
+				t.emit('>')
+			case c == 3:
+				t.emit(' ')
+			case c < 14:
+				t.emit('0' + c - 4)
+			default:
+				t.emit('A' + c - 14)
+			}
+		}
+	}
+	// A last odd codeword is in ASCII without an unlatch
+	return i
+}
+
+// dmDecodeEDIFACT decodes EDIFACT mode, four 6-bit values to three
+// codewords, until the unlatch value.
+func dmDecodeEDIFACT(data []byte, i int, t *dmText) int {
+	for i+2 < len(data) {
+		v := int(data[i])<<16 | int(data[i+1])<<8 | int(data[i+2])
+		for j := 0; j < 4; j++ {
+			c := v >> (18 - 6*j) & 0x3f
+			if c == 0x1f {
+				// Unlatch; ASCII resumes at the next whole codeword
+				return i + (6*j+6+7)/8
+			}
+			if c&0x20 == 0 {
+				c |= 0x40
+			}
+			t.emit(c)
+		}
+		i += 3
+	}
+	return i
+}
+
+// dmDecodeBase256 decodes a Base 256 field, whose length and bytes are
+// scrambled by their position in the symbol.
+func dmDecodeBase256(data []byte, i int, t *dmText) (int, error) {
+	unscramble := func(pos int) int {
+		v := int(data[pos]) - (149*(pos+1)%255 + 1)
+		if v < 0 {
+			v += 256
+		}
+		return v
+	}
+	if i >= len(data) {
+		return i, errors.New("base 256 field has no length")
+	}
+	n := unscramble(i)
+	i++
+	switch {
+	case n == 0:
+		n = len(data) - i // to the end of the symbol
+	case n >= 250:
+		if i >= len(data) {
+			return i, errors.New("base 256 field has no length")
+		}
+		n = 250*(n-249) + unscramble(i)
+		i++
+	}
+	if i+n > len(data) {
+		return i, errors.New("base 256 field runs past the data")
+	}
+	for ; n > 0; n-- {
+		t.emit(unscramble(i))
+		i++
+	}
+	return i, nil
+}
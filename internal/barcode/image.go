@@ -0,0 +1,146 @@
+package barcode
+
+import (
+	"image"
+	_ "image/gif" // decoders for image.Decode
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// bitmap is a binarized image, true for dark.
+type bitmap struct {
+	w, h int
+	dark []bool
+}
+
+func (b *bitmap) at(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.w || y >= b.h {
+		return false
+	}
+	return b.dark[y*b.w+x]
+}
+
+// atf samples the pixel containing a point.
+func (b *bitmap) atf(x, y float64) bool {
+	if x < 0 || y < 0 {
+		return false
+	}
+	return b.at(int(x), int(y))
+}
+
+// luminance is a greyscale copy of an image, 0 black to 255 white.
+type luminance struct {
+	w, h int
+	pix  []uint8
+}
+
+func newLuminance(img image.Image) *luminance {
+	bounds := img.Bounds()
+	l := &luminance{w: bounds.Dx(), h: bounds.Dy()}
+	l.pix = make([]uint8, l.w*l.h)
+	switch src := img.(type) {
+	case *image.Gray:
+		for y := 0; y < l.h; y++ {
+			copy(l.pix[y*l.w:], src.Pix[y*src.Stride:y*src.Stride+l.w])
+		}
+	case *image.YCbCr:
+		for y := 0; y < l.h; y++ {
+			copy(l.pix[y*l.w:], src.Y[y*src.YStride:y*src.YStride+l.w])
+		}
+	default:
+		i := 0
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, a := img.At(x, y).RGBA()
+				// Transparent pixels count as the white page behind them
+				lum := (299*r + 587*g + 114*b) / 1000
+				lum = lum*a/0xffff + (0xffff - a)
+				l.pix[i] = uint8(lum >> 8)
+				i++
+			}
+		}
+	}
+	return l
+}
+
+// localThreshold binarizes against the mean of the surrounding window, so
+// shadows and uneven lighting across a photo don't hide the code.
+func (l *luminance) localThreshold() *bitmap {
+	w, h := l.w, l.h
+	// Summed-area table with a zero row and column in front
+	integral := make([]int, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		row := 0
+		for x := 0; x < w; x++ {
+			row += int(l.pix[y*w+x])
+			integral[(y+1)*(w+1)+x+1] = integral[y*(w+1)+x+1] + row
+		}
+	}
+
+	half := max(max(w, h)/16, 8)
+	b := &bitmap{w: w, h: h, dark: make([]bool, w*h)}
+	for y := 0; y < h; y++ {
+		y0, y1 := max(y-half, 0), min(y+half+1, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := max(x-half, 0), min(x+half+1, w)
+			total := integral[y1*(w+1)+x1] - integral[y0*(w+1)+x1] - integral[y1*(w+1)+x0] + integral[y0*(w+1)+x0]
+			count := (y1 - y0) * (x1 - x0)
+			// Dark when clearly below the local mean
+			b.dark[y*w+x] = int(l.pix[y*w+x])*count*100 < total*85
+		}
+	}
+	return b
+}
+
+// globalThreshold binarizes at the valley between the dark and light
+// peaks of the histogram, for evenly lit images with large modules.
+func (l *luminance) globalThreshold() *bitmap {
+	var histogram [256]int
+	for _, p := range l.pix {
+		histogram[p]++
+	}
+
+	// Otsu's method: the split with the most variance between the halves
+	total := len(l.pix)
+	sumAll := 0
+	for i, n := range histogram {
+		sumAll += i * n
+	}
+	best, bestVar := 127, -1.0
+	below, sumBelow := 0, 0
+	for t := 0; t < 256; t++ {
+		below += histogram[t]
+		sumBelow += t * histogram[t]
+		above := total - below
+		if below == 0 || above == 0 {
+			continue
+		}
+		meanBelow := float64(sumBelow) / float64(below)
+		meanAbove := float64(sumAll-sumBelow) / float64(above)
+		v := float64(below) * float64(above) * (meanBelow - meanAbove) * (meanBelow - meanAbove)
+		if v > bestVar {
+			best, bestVar = t, v
+		}
+	}
+
+	b := &bitmap{w: l.w, h: l.h, dark: make([]bool, len(l.pix))}
+	for i, p := range l.pix {
+		b.dark[i] = int(p) <= best
+	}
+	return b
+}
+
+// runs returns the lengths of alternating light and dark runs along a
+// line of the bitmap, starting with light.
+func (b *bitmap) runs(x, y, dx, dy, n int) []int {
+	runs := []int{0}
+	dark := false
+	for i := 0; i < n; i++ {
+		if b.at(x+i*dx, y+i*dy) != dark {
+			dark = !dark
+			runs = append(runs, 0)
+		}
+		runs[len(runs)-1]++
+	}
+	return runs
+}
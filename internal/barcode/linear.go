@@ -0,0 +1,329 @@
+package barcode
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// linearLines is how many rows, and columns, are scanned for 1D codes.
+const linearLines = 40
+
+// decodeLinear scans rows and columns of the bitmap, from the middle
+// out, for Code 128 and EAN-13 symbols in either direction.
+func decodeLinear(bm *bitmap) []Result {
+	var results []Result
+	eanSeen := make(map[string]int)
+	scan := func(runs []int) {
+		for _, rs := range [][]int{runs, reverseRuns(runs)} {
+			for _, text := range decodeCode128(rs) {
+				results = append(results, Result{FormatCode128, text})
+			}
+			// A misread EAN-13 passes its checksum one time in ten, so it
+			// must read the same on two lines
+			for _, text := range decodeEAN13(rs) {
+				if eanSeen[text]++; eanSeen[text] == 2 {
+					results = append(results, Result{FormatEAN13, text})
+				}
+			}
+		}
+	}
+
+	for _, horizontal := range []bool{true, false} {
+		length, across := bm.w, bm.h
+		if !horizontal {
+			length, across = bm.h, bm.w
+		}
+		step := max(across/linearLines, 1)
+		for i := 0; i < linearLines && i*step/2 < across/2+step; i++ {
+			// Alternate above and below the middle
+			offset := (i + 1) / 2 * step
+			if i%2 == 1 {
+				offset = -offset
+			}
+			pos := across/2 + offset
+			if pos < 0 || pos >= across {
+				continue
+			}
+			if horizontal {
+				scan(bm.runs(0, pos, 1, 0, length))
+			} else {
+				scan(bm.runs(pos, 0, 0, 1, length))
+			}
+		}
+	}
+	return results
+}
+
+// reverseRuns reverses a line of runs, keeping a light run first.
+func reverseRuns(runs []int) []int {
+	out := make([]int, 0, len(runs)+1)
+	if len(runs)%2 == 0 {
+		out = append(out, 0) // the line ended dark
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		out = append(out, runs[i])
+	}
+	return out
+}
+
+// patternVariance measures how far runs are from a pattern of module
+// widths, as a fraction of the total width. Any run further than
+// maxRun modules off fails with +Inf.
+func patternVariance(runs []int, pattern []int, maxRun float64) float64 {
+	total, modules := 0, 0
+	for i := range pattern {
+		total += runs[i]
+		modules += pattern[i]
+	}
+	if total < modules {
+		return math.Inf(1)
+	}
+	unit := float64(total) / float64(modules)
+	maxRun *= unit
+	variance := 0.0
+	for i, p := range pattern {
+		v := math.Abs(float64(runs[i]) - float64(p)*unit)
+		if v > maxRun {
+			return math.Inf(1)
+		}
+		variance += v
+	}
+	return variance / float64(total)
+}
+
+// Code 128 symbol values with special meanings.
+const (
+	code128Shift  = 98
+	code128CodeA  = 101
+	code128FNC1   = 102
+	code128StartA = 103
+)
+
+var errNoStop = errors.New("no stop code")
+
+// decodeCode128 returns the Code 128 symbols along a line of runs.
+func decodeCode128(runs []int) []string {
+	var texts []string
+	for i := 1; i+6 < len(runs); i += 2 {
+		start := code128Match(runs[i:], code128StartA, code128StartC)
+		if start < 0 {
+			continue
+		}
+		width := 0
+		for _, r := range runs[i : i+6] {
+			width += r
+		}
+		if runs[i-1] < width/2 {
+			continue // no quiet zone
+		}
+		text, end, err := readCode128(runs, i, start)
+		if err != nil {
+			continue
+		}
+		texts = append(texts, text)
+		i = end
+	}
+	return texts
+}
+
+// code128Match returns the value from lo to hi whose pattern best fits
+// the next six runs, or -1.
+func code128Match(runs []int, lo, hi int) int {
+	if len(runs) < 6 {
+		return -1
+	}
+	best, bestVariance := -1, 0.25
+	for v := lo; v <= hi; v++ {
+		var pattern [6]int
+		for j := range pattern {
+			pattern[j] = int(code128Patterns[v][j] - '0')
+		}
+		if variance := patternVariance(runs, pattern[:], 0.7); variance < bestVariance {
+			best, bestVariance = v, variance
+		}
+	}
+	return best
+}
+
+// readCode128 reads the symbol whose start code is at runs[i], returning
+// its text and the index of its last run.
+func readCode128(runs []int, i, start int) (string, int, error) {
+	values := []int{start}
+	pos := i + 6
+	for {
+		v := code128Match(runs[pos:], 0, code128Stop)
+		if v < 0 {
+			return "", 0, errNoStop
+		}
+		pos += 6
+		if v == code128Stop {
+			// The stop code's final bar, then light
+			if pos >= len(runs) || (pos+1 < len(runs) && runs[pos+1] < runs[pos]*3) {
+				return "", 0, errNoStop
+			}
+			break
+		}
+		values = append(values, v)
+	}
+	if len(values) < 3 {
+		return "", 0, errors.New("no data")
+	}
+
+	checksum := values[0]
+	for j, v := range values[1 : len(values)-1] {
+		checksum += (j + 1) * v
+	}
+	if checksum%103 != values[len(values)-1] {
+		return "", 0, errors.New("bad checksum")
+	}
+
+	var sb strings.Builder
+	set := start
+	shifted := false
+	for j, v := range values[1 : len(values)-1] {
+		current := set
+		if shifted {
+			// A shift swaps A and B for one symbol
+			current = code128StartA + code128StartB - set
+			shifted = false
+		}
+		switch {
+		case v == code128FNC1:
+			// Leading FNC1 marks GS1 data; later ones separate its fields
+			if j > 0 {
+				sb.WriteByte(0x1d)
+			}
+		case current == code128StartC && v < 100:
+			sb.WriteByte(byte('0' + v/10))
+			sb.WriteByte(byte('0' + v%10))
+		case current == code128StartC:
+			if v == code128CodeA {
+				set = code128StartA
+			} else if v == code128CodeB {
+				set = code128StartB
+			}
+		case v < 64:
+			sb.WriteByte(byte(v + 32))
+		case v < 96 && current == code128StartA:
+			sb.WriteByte(byte(v - 64)) // control characters
+		case v < 96:
+			sb.WriteByte(byte(v + 32))
+		case v == code128Shift:
+			shifted = true
+		case v == code128CodeC:
+			set = code128StartC
+		case v == code128CodeB && current == code128StartA, v == code128CodeA && current == code128StartB:
+			set = code128StartA + code128StartB - current
+		}
+		// FNC2, FNC3 and FNC4 have no place in location and item codes
+	}
+	return sb.String(), pos, nil
+}
+
+// eanDigits are the module widths of each digit's L code, starting with
+// light; G codes are the reverse, and R codes the same widths starting
+// with dark.
+var eanDigits = [10][4]int{
+	{3, 2, 1, 1}, {2, 2, 2, 1}, {2, 1, 2, 2}, {1, 4, 1, 1}, {1, 1, 3, 2},
+	{1, 2, 3, 1}, {1, 1, 1, 4}, {1, 3, 1, 2}, {1, 2, 1, 3}, {3, 1, 1, 2},
+}
+
+// eanFirstDigit is the L and G parity of the six left digits, G as 1 with
+// the first digit highest, for each implied first digit.
+var eanFirstDigit = [10]int{0x00, 0x0B, 0x0D, 0x0E, 0x13, 0x19, 0x1C, 0x15, 0x16, 0x1A}
+
+// decodeEAN13 returns the EAN-13 numbers along a line of runs.
+func decodeEAN13(runs []int) []string {
+	var texts []string
+	guard := []int{1, 1, 1}
+	for i := 1; i+58 < len(runs); i += 2 {
+		if patternVariance(runs[i:], guard, 0.7) > 0.48 || runs[i-1] < runs[i]+runs[i+1]+runs[i+2] {
+			continue
+		}
+		if text, ok := readEAN13(runs[i:]); ok {
+			texts = append(texts, text)
+			i += 58
+		}
+	}
+	return texts
+}
+
+// readEAN13 reads the symbol whose start guard begins runs.
+func readEAN13(runs []int) (string, bool) {
+	digits := make([]byte, 13)
+	parity := 0
+	pos := 3
+	for d := 1; d <= 6; d++ {
+		digit, g := eanDigit(runs[pos:], true)
+		if digit < 0 {
+			return "", false
+		}
+		digits[d] = byte(digit)
+		parity = parity<<1 | g
+		pos += 4
+	}
+	if patternVariance(runs[pos:], []int{1, 1, 1, 1, 1}, 0.7) > 0.48 {
+		return "", false
+	}
+	pos += 5
+	for d := 7; d <= 12; d++ {
+		digit, _ := eanDigit(runs[pos:], false)
+		if digit < 0 {
+			return "", false
+		}
+		digits[d] = byte(digit)
+		pos += 4
+	}
+	if patternVariance(runs[pos:], []int{1, 1, 1}, 0.7) > 0.48 {
+		return "", false
+	}
+	if pos+3 < len(runs) && runs[pos+3] < runs[pos]+runs[pos+1]+runs[pos+2] {
+		return "", false // no quiet zone
+	}
+
+	first := -1
+	for d, p := range eanFirstDigit {
+		if p == parity {
+			first = d
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+	digits[0] = byte(first)
+
+	sum := 0
+	for i, d := range digits[:12] {
+		if i%2 == 0 {
+			sum += int(d)
+		} else {
+			sum += 3 * int(d)
+		}
+	}
+	if (10-sum%10)%10 != int(digits[12]) {
+		return "", false
+	}
+	for i := range digits {
+		digits[i] += '0'
+	}
+	return string(digits), true
+}
+
+// eanDigit matches four runs to a digit, reporting for left-hand digits
+// whether it was a G code.
+func eanDigit(runs []int, left bool) (digit, g int) {
+	best, bestVariance := -1, 0.48
+	for d, widths := range eanDigits {
+		if v := patternVariance(runs, widths[:], 0.7); v < bestVariance {
+			best, bestVariance, g = d, v, 0
+		}
+		if left {
+			reversed := []int{widths[3], widths[2], widths[1], widths[0]}
+			if v := patternVariance(runs, reversed, 0.7); v < bestVariance {
+				best, bestVariance, g = d, v, 1
+			}
+		}
+	}
+	return best, g
+}
@@ -0,0 +1,287 @@
+package barcode
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+	"unicode/utf8"
+)
+
+// qrAlphanumeric are the characters of alphanumeric mode, by value.
+const qrAlphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// readQR decodes a sampled QR symbol, trying it mirrored if it doesn't
+// read the right way round.
+func readQR(grid [][]bool) (string, error) {
+	text, err := readQRMatrix(grid)
+	if err == nil {
+		return text, nil
+	}
+	mirrored := make([][]bool, len(grid))
+	for y := range grid {
+		mirrored[y] = make([]bool, len(grid))
+		for x := range grid {
+			mirrored[y][x] = grid[x][y]
+		}
+	}
+	if text, err := readQRMatrix(mirrored); err == nil {
+		return text, nil
+	}
+	return "", err
+}
+
+func readQRMatrix(grid [][]bool) (string, error) {
+	size := len(grid)
+	version := (size - 17) / 4
+	if version < 1 || version > 40 || size != 17+4*version {
+		return "", fmt.Errorf("%d modules is not a QR size", size)
+	}
+	level, mask, err := readQRFormat(grid)
+	if err != nil {
+		return "", err
+	}
+
+	q := newQRMatrix(version)
+	q.drawFunctionPatterns()
+	for y := range grid {
+		copy(q.modules[y], grid[y])
+	}
+	q.applyMask(mask)
+	raw := q.readData()
+
+	// De-interleave, the reverse of codewords
+	dataBlocks := qrDataBlocks(version, level)
+	ecLen := qrECCPerBlock[level][version]
+	blocks := make([][]byte, len(dataBlocks))
+	for i, n := range dataBlocks {
+		blocks[i] = make([]byte, n+ecLen)
+	}
+	next := 0
+	for i := 0; i < dataBlocks[len(dataBlocks)-1]; i++ {
+		for b, n := range dataBlocks {
+			if i < n {
+				blocks[b][i] = raw[next]
+				next++
+			}
+		}
+	}
+	for i := 0; i < ecLen; i++ {
+		for b, n := range dataBlocks {
+			blocks[b][n+i] = raw[next]
+			next++
+		}
+	}
+
+	var data []byte
+	for b, n := range dataBlocks {
+		if err := qrField.correct(blocks[b], ecLen, 0); err != nil {
+			return "", err
+		}
+		data = append(data, blocks[b][:n]...)
+	}
+	return parseQRSegments(data, version)
+}
+
+// readQRFormat reads the error correction level and mask from whichever
+// copy of the format information is closest to a valid code.
+func readQRFormat(grid [][]bool) (level, mask int, err error) {
+	size := len(grid)
+	var first, second int
+	set := func(v *int, i int, dark bool) {
+		if dark {
+			*v |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		set(&first, i, grid[i][8])
+	}
+	set(&first, 6, grid[7][8])
+	set(&first, 7, grid[8][8])
+	set(&first, 8, grid[8][7])
+	for i := 9; i < 15; i++ {
+		set(&first, i, grid[8][14-i])
+	}
+	for i := 0; i < 8; i++ {
+		set(&second, i, grid[8][size-1-i])
+	}
+	for i := 8; i < 15; i++ {
+		set(&second, i, grid[size-15+i][8])
+	}
+
+	best := 16
+	for l := qrLevelL; l <= qrLevelH; l++ {
+		for m := 0; m < 8; m++ {
+			want := qrFormatBits(l, m)
+			for _, got := range []int{first, second} {
+				if d := bits.OnesCount(uint(want ^ got)); d < best {
+					best, level, mask = d, l, m
+				}
+			}
+		}
+	}
+	// Format codes differ in at least 7 bits, so 3 errors are safe
+	if best > 3 {
+		return 0, 0, errors.New("unreadable QR format information")
+	}
+	return level, mask, nil
+}
+
+// readData reads the codewords in the same zigzag order drawData places
+// them.
+func (q *qrMatrix) readData() []byte {
+	data := make([]byte, qrRawCodewords(q.version))
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.isFunction[y][x] && i < len(data)*8 {
+					if q.modules[y][x] {
+						data[i>>3] |= 0x80 >> (i & 7)
+					}
+					i++
+				}
+			}
+		}
+	}
+	return data
+}
+
+// bitReader reads big-endian bit fields from bytes.
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) remaining() int { return len(r.data)*8 - r.pos }
+
+func (r *bitReader) read(n int) (int, error) {
+	if n > r.remaining() {
+		return 0, errors.New("data ends early")
+	}
+	v := 0
+	for i := 0; i < n; i++ {
+		v = v<<1 | int(r.data[r.pos>>3]>>(7-r.pos&7)&1)
+		r.pos++
+	}
+	return v, nil
+}
+
+// parseQRSegments decodes the segments of the corrected data codewords.
+func parseQRSegments(data []byte, version int) (string, error) {
+	// Character count widths grow at versions 10 and 27
+	size := 0
+	if version >= 27 {
+		size = 2
+	} else if version >= 10 {
+		size = 1
+	}
+	countBits := map[int][3]int{
+		0b0001: {10, 12, 14}, // numeric
+		0b0010: {9, 11, 13},  // alphanumeric
+		0b0100: {8, 16, 16},  // byte
+	}
+
+	r := &bitReader{data: data}
+	var out []byte
+	for r.remaining() >= 4 {
+		mode, _ := r.read(4)
+		switch mode {
+		case 0b0000: // terminator
+			return qrText(out), nil
+		case 0b0111: // ECI: the text is assumed to be UTF-8 anyway
+			first, err := r.read(8)
+			if err != nil {
+				return "", err
+			}
+			if first&0x80 != 0 {
+				extra := 8
+				if first&0x40 != 0 {
+					extra = 16
+				}
+				if _, err := r.read(extra); err != nil {
+					return "", err
+				}
+			}
+			continue
+		case 0b0011: // structured append header
+			if _, err := r.read(16); err != nil {
+				return "", err
+			}
+			continue
+		case 0b0101: // FNC1 first position
+			continue
+		case 0b1001: // FNC1 second position, with its application indicator
+			if _, err := r.read(8); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		widths, ok := countBits[mode]
+		if !ok {
+			return "", fmt.Errorf("unsupported QR mode %04b", mode)
+		}
+		count, err := r.read(widths[size])
+		if err != nil {
+			return "", err
+		}
+		switch mode {
+		case 0b0001:
+			for ; count > 0; count -= min(count, 3) {
+				digits := min(count, 3)
+				v, err := r.read([]int{0, 4, 7, 10}[digits])
+				if err != nil {
+					return "", err
+				}
+				out = fmt.Appendf(out, "%0*d", digits, v)
+			}
+		case 0b0010:
+			for ; count > 0; count -= min(count, 2) {
+				if count >= 2 {
+					v, err := r.read(11)
+					if err != nil || v >= 45*45 {
+						return "", errors.New("invalid alphanumeric data")
+					}
+					out = append(out, qrAlphanumeric[v/45], qrAlphanumeric[v%45])
+				} else {
+					v, err := r.read(6)
+					if err != nil || v >= 45 {
+						return "", errors.New("invalid alphanumeric data")
+					}
+					out = append(out, qrAlphanumeric[v])
+				}
+			}
+		case 0b0100:
+			for ; count > 0; count-- {
+				v, err := r.read(8)
+				if err != nil {
+					return "", err
+				}
+				out = append(out, byte(v))
+			}
+		}
+	}
+	return qrText(out), nil
+}
+
+// qrText returns byte-mode data as a string: UTF-8 when valid, else the
+// standard's default ISO 8859-1.
+func qrText(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	var sb strings.Builder
+	for _, c := range b {
+		sb.WriteRune(rune(c))
+	}
+	return sb.String()
+}
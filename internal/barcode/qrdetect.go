@@ -0,0 +1,373 @@
+package barcode
+
+import (
+	"math"
+	"sort"
+)
+
+// finder is a candidate QR finder pattern: the centre and the estimated
+// module size, with how many scan lines crossed it.
+type finder struct {
+	point
+	module float64
+	count  int
+}
+
+// findFinders scans the rows of the bitmap for the 1:1:3:1:1 dark, light,
+// dark, light, dark runs across a finder pattern, confirming each in the
+// other direction.
+func findFinders(bm *bitmap) []*finder {
+	var found []*finder
+	add := func(c point, module float64) {
+		for _, f := range found {
+			if math.Abs(c.x-f.x) <= f.module && math.Abs(c.y-f.y) <= f.module &&
+				math.Abs(module-f.module) <= math.Max(1, f.module) {
+				n := float64(f.count)
+				f.x = (f.x*n + c.x) / (n + 1)
+				f.y = (f.y*n + c.y) / (n + 1)
+				f.module = (f.module*n + module) / (n + 1)
+				f.count++
+				return
+			}
+		}
+		found = append(found, &finder{c, module, 1})
+	}
+
+	for y := 0; y < bm.h; y++ {
+		var counts [5]int
+		state := 0
+		for x := 0; x <= bm.w; x++ {
+			dark := x < bm.w && bm.at(x, y)
+			if dark {
+				if state%2 == 1 {
+					state++
+				}
+				counts[state]++
+				continue
+			}
+			if state%2 == 1 {
+				counts[state]++
+				continue
+			}
+			if state < 4 {
+				state++
+				counts[state]++
+				continue
+			}
+			if finderRatio(counts) {
+				cx := float64(x-counts[4]-counts[3]) - float64(counts[2])/2
+				if c, module, ok := confirmFinder(bm, point{cx, float64(y)}, counts); ok {
+					add(c, module)
+				}
+			}
+			counts = [5]int{counts[2], counts[3], counts[4], 1, 0}
+			state = 3
+		}
+	}
+	return found
+}
+
+// finderRatio reports whether runs are close to 1:1:3:1:1.
+func finderRatio(counts [5]int) bool {
+	total := 0
+	for _, c := range counts {
+		if c == 0 {
+			return false
+		}
+		total += c
+	}
+	if total < 7 {
+		return false
+	}
+	module := float64(total) / 7
+	slack := module / 2
+	return math.Abs(module-float64(counts[0])) < slack &&
+		math.Abs(module-float64(counts[1])) < slack &&
+		math.Abs(3*module-float64(counts[2])) < 3*slack &&
+		math.Abs(module-float64(counts[3])) < slack &&
+		math.Abs(module-float64(counts[4])) < slack
+}
+
+// confirmFinder checks a candidate vertically, then horizontally again
+// through the corrected centre, returning the refined centre.
+func confirmFinder(bm *bitmap, c point, counts [5]int) (point, float64, bool) {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	y, ok := crossCheck(bm, c, 0, 1, counts[2], total)
+	if !ok {
+		return c, 0, false
+	}
+	c.y = y
+	x, ok := crossCheck(bm, c, 1, 0, counts[2], total)
+	if !ok {
+		return c, 0, false
+	}
+	c.x = x
+	// A diagonal check weeds out crosses and text that pass the others
+	if _, ok := crossCheck(bm, c, 1, 1, counts[2]*2, 0); !ok {
+		return c, 0, false
+	}
+	return c, float64(total) / 7, true
+}
+
+// crossCheck measures the five runs through c along the direction dx, dy
+// and returns the centre coordinate along that axis. The runs must add
+// up to about expectTotal, unless it is 0.
+func crossCheck(bm *bitmap, c point, dx, dy, maxCount, expectTotal int) (float64, bool) {
+	cx, cy := int(c.x), int(c.y)
+	if !bm.at(cx, cy) {
+		return 0, false
+	}
+	var counts [5]int
+	// Walk back from the centre through dark, light, dark
+	i := 0
+	for state := 2; state >= 0; state-- {
+		wantDark := state != 1
+		for bm.at(cx-i*dx, cy-i*dy) == wantDark && inside(bm, cx-i*dx, cy-i*dy) {
+			counts[state]++
+			i++
+			if state != 2 && counts[state] > maxCount {
+				return 0, false
+			}
+		}
+		if !inside(bm, cx-i*dx, cy-i*dy) && state > 0 {
+			return 0, false
+		}
+	}
+	// And forward through the centre's remainder, light, dark
+	i = 1
+	for state := 2; state <= 4; state++ {
+		wantDark := state != 3
+		for bm.at(cx+i*dx, cy+i*dy) == wantDark && inside(bm, cx+i*dx, cy+i*dy) {
+			counts[state]++
+			i++
+			if state != 2 && counts[state] > maxCount {
+				return 0, false
+			}
+		}
+		if !inside(bm, cx+i*dx, cy+i*dy) && state < 4 {
+			return 0, false
+		}
+	}
+
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	if (expectTotal > 0 && 5*abs(total-expectTotal) >= 2*expectTotal) || !finderRatio(counts) {
+		return 0, false
+	}
+	end := i - counts[4] - counts[3]
+	mid := float64(end) - float64(counts[2])/2
+	if dx != 0 {
+		return float64(cx) + mid, true
+	}
+	return float64(cy) + mid, true
+}
+
+func inside(bm *bitmap, x, y int) bool {
+	return x >= 0 && y >= 0 && x < bm.w && y < bm.h
+}
+
+// qrCandidate is three finder patterns that could be one symbol.
+type qrCandidate struct {
+	topLeft, topRight, bottomLeft *finder
+	score                         float64 // lower is more square
+}
+
+// finderTriples returns the sets of three finder patterns shaped like a
+// QR code, best first.
+func finderTriples(found []*finder) []qrCandidate {
+	// Patterns seen on one scan line only are usually noise
+	var strong []*finder
+	for _, f := range found {
+		if f.count >= 2 {
+			strong = append(strong, f)
+		}
+	}
+	if len(strong) < 3 {
+		strong = found
+	}
+	sort.Slice(strong, func(i, j int) bool { return strong[i].count > strong[j].count })
+	if len(strong) > 15 {
+		strong = strong[:15]
+	}
+
+	var candidates []qrCandidate
+	for i := 0; i < len(strong); i++ {
+		for j := i + 1; j < len(strong); j++ {
+			for k := j + 1; k < len(strong); k++ {
+				if c, ok := qrTriple(strong[i], strong[j], strong[k]); ok {
+					candidates = append(candidates, c)
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].score < candidates[j].score })
+	return candidates
+}
+
+// qrTriple orders three finder patterns, the top left being at the right
+// angle, and checks they form a plausible symbol.
+func qrTriple(a, b, c *finder) (qrCandidate, bool) {
+	minModule := math.Min(a.module, math.Min(b.module, c.module))
+	maxModule := math.Max(a.module, math.Max(b.module, c.module))
+	if maxModule > 1.5*minModule {
+		return qrCandidate{}, false
+	}
+
+	// The corner is opposite the longest side
+	ab, bc, ca := a.distance(b.point), b.distance(c.point), c.distance(a.point)
+	var corner, p, q *finder
+	var hyp, leg1, leg2 float64
+	switch {
+	case bc >= ab && bc >= ca:
+		corner, p, q, hyp, leg1, leg2 = a, b, c, bc, ab, ca
+	case ca >= ab:
+		corner, p, q, hyp, leg1, leg2 = b, c, a, ca, bc, ab
+	default:
+		corner, p, q, hyp, leg1, leg2 = c, a, b, ab, ca, bc
+	}
+	// Perspective stretches one leg, but not by much in a usable frame
+	if leg1 > 1.6*leg2 || leg2 > 1.6*leg1 || leg1 < 7*minModule {
+		return qrCandidate{}, false
+	}
+	expect := math.Hypot(leg1, leg2)
+	if math.Abs(hyp-expect) > 0.15*expect {
+		return qrCandidate{}, false
+	}
+
+	// Clockwise from the top left is top right, then bottom left
+	if p.sub(corner.point).cross(q.sub(corner.point)) < 0 {
+		p, q = q, p
+	}
+	score := math.Abs(leg1-leg2)/math.Max(leg1, leg2) + math.Abs(hyp-expect)/expect + (maxModule-minModule)/maxModule
+	return qrCandidate{corner, p, q, score}, true
+}
+
+// dimension estimates the modules across the symbol from the finder
+// pattern spacing, rounded to a valid size of 17+4v.
+func (c qrCandidate) dimension() int {
+	// Module sizes were measured along rows, which cross a rotated finder
+	// pattern at a slant and overstate them
+	side := c.topRight.sub(c.topLeft.point)
+	angle := math.Mod(math.Abs(math.Atan2(side.y, side.x)), math.Pi/2)
+	if angle > math.Pi/4 {
+		angle = math.Pi/2 - angle
+	}
+	module := (c.topLeft.module + c.topRight.module + c.bottomLeft.module) / 3 * math.Cos(angle)
+	across := c.topLeft.distance(c.topRight.point) / module
+	down := c.topLeft.distance(c.bottomLeft.point) / module
+	dim := int(math.Round((across+down)/2)) + 7
+	switch dim & 3 {
+	case 0:
+		dim++
+	case 2:
+		dim--
+	case 3:
+		dim -= 2
+	}
+	return dim
+}
+
+// alignment looks for the bottom right alignment pattern, which corrects
+// for perspective, near where the finder patterns put it. It returns
+// false when there is none or it can't be found.
+func (c qrCandidate) alignment(bm *bitmap, dim int) (point, bool) {
+	if dim < 25 {
+		return point{}, false
+	}
+	tl := c.topLeft.point
+	between := float64(dim - 7)
+	u := c.topRight.sub(tl).scale(1 / between)
+	v := c.bottomLeft.sub(tl).scale(1 / between)
+	// The pattern's centre is 3 modules in from the corner finder centres
+	expect := tl.add(u.scale(between - 3)).add(v.scale(between - 3))
+
+	module := math.Hypot(u.x, u.y)
+	bestScore := 0
+	var sum point
+	n := 0
+	for _, reach := range []float64{4, 8, 16} {
+		r := int(reach * module)
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				p := point{math.Round(expect.x) + float64(dx), math.Round(expect.y) + float64(dy)}
+				score := alignmentScore(bm, p, u, v)
+				switch {
+				case score > bestScore:
+					bestScore, sum, n = score, p, 1
+				case score == bestScore:
+					sum, n = sum.add(p), n+1
+				}
+			}
+		}
+		if bestScore >= 24 {
+			break
+		}
+	}
+	if bestScore < 23 {
+		return point{}, false
+	}
+	// The best scores form a patch about a module wide; use its middle
+	return sum.scale(1 / float64(n)), true
+}
+
+// alignmentScore counts the modules of a 5x5 alignment pattern centred
+// on p that match: a dark centre, light ring and dark outer ring.
+func alignmentScore(bm *bitmap, p, u, v point) int {
+	score := 0
+	for j := -2; j <= 2; j++ {
+		for i := -2; i <= 2; i++ {
+			q := p.add(u.scale(float64(i))).add(v.scale(float64(j)))
+			if bm.atf(q.x, q.y) == (max(abs(i), abs(j)) != 1) {
+				score++
+			}
+		}
+	}
+	return score
+}
+
+// grid samples the symbol's modules for a dimension.
+func (c qrCandidate) grid(bm *bitmap, dim int) ([][]bool, bool) {
+	d := float64(dim)
+	modules := [4]point{{3.5, 3.5}, {d - 3.5, 3.5}, {d - 3.5, d - 3.5}, {3.5, d - 3.5}}
+	image := [4]point{c.topLeft.point, c.topRight.point, {}, c.bottomLeft.point}
+	if p, ok := c.alignment(bm, dim); ok {
+		modules[2] = point{d - 6.5, d - 6.5}
+		image[2] = p
+	} else {
+		image[2] = c.topRight.add(c.bottomLeft.sub(c.topLeft.point))
+	}
+	return sampleGrid(bm, quadToQuad(modules, image), dim, dim)
+}
+
+// decodeQR finds and decodes the QR codes in a bitmap.
+func decodeQR(bm *bitmap) []string {
+	var texts []string
+	used := make(map[*finder]bool)
+	for _, c := range finderTriples(findFinders(bm)) {
+		if used[c.topLeft] || used[c.topRight] || used[c.bottomLeft] {
+			continue
+		}
+		dim := c.dimension()
+		// The estimate can be a version out on skewed or small codes
+		for _, d := range []int{dim, dim - 4, dim + 4} {
+			if d < 21 || d > 177 {
+				continue
+			}
+			grid, ok := c.grid(bm, d)
+			if !ok {
+				continue
+			}
+			if text, err := readQR(grid); err == nil {
+				texts = append(texts, text)
+				used[c.topLeft], used[c.topRight], used[c.bottomLeft] = true, true, true
+				break
+			}
+		}
+	}
+	return texts
+}
@@ -0,0 +1,183 @@
+package barcode
+
+import "errors"
+
+var errTooManyErrors = errors.New("too many errors to correct")
+
+// field is GF(256) with a given primitive polynomial, generator 2.
+type field struct {
+	exp [512]int // doubled so products need no modulo
+	log [256]int
+}
+
+var (
+	qrField = newField(0x11D) // x^8+x^4+x^3+x^2+1
+	dmField = newField(0x12D) // x^8+x^5+x^3+x^2+1
+)
+
+func newField(poly int) *field {
+	f := &field{}
+	x := 1
+	for i := 0; i < 255; i++ {
+		f.exp[i] = x
+		f.log[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= poly
+		}
+	}
+	for i := 255; i < len(f.exp); i++ {
+		f.exp[i] = f.exp[i-255]
+	}
+	return f
+}
+
+func (f *field) mul(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return f.exp[f.log[a]+f.log[b]]
+}
+
+func (f *field) div(a, b int) int {
+	if a == 0 {
+		return 0
+	}
+	return f.exp[f.log[a]+255-f.log[b]]
+}
+
+// pow returns the generator raised to n.
+func (f *field) pow(n int) int {
+	n %= 255
+	if n < 0 {
+		n += 255
+	}
+	return f.exp[n]
+}
+
+// eval evaluates a polynomial with the lowest power first.
+func (f *field) eval(poly []int, x int) int {
+	y := 0
+	for i := len(poly) - 1; i >= 0; i-- {
+		y = f.mul(y, x) ^ poly[i]
+	}
+	return y
+}
+
+// remainder returns the error correction codewords for data. The
+// generator's roots are consecutive powers of 2 starting at 2^base.
+func (f *field) remainder(data []byte, degree, base int) []byte {
+	// Generator polynomial, highest power first
+	gen := []int{1}
+	for i := 0; i < degree; i++ {
+		next := make([]int, len(gen)+1)
+		for j := range next {
+			if j < len(gen) {
+				next[j] = gen[j]
+			}
+			if j > 0 {
+				next[j] ^= f.mul(gen[j-1], f.pow(i+base))
+			}
+		}
+		gen = next
+	}
+
+	rem := make([]int, degree)
+	for _, b := range data {
+		factor := int(b) ^ rem[0]
+		copy(rem, rem[1:])
+		rem[degree-1] = 0
+		for i := range rem {
+			rem[i] ^= f.mul(gen[i+1], factor)
+		}
+	}
+
+	out := make([]byte, degree)
+	for i, v := range rem {
+		out[i] = byte(v)
+	}
+	return out
+}
+
+// correct fixes up to ecLen/2 wrong codewords of block, data followed by
+// ecLen error correction codewords, in place.
+func (f *field) correct(block []byte, ecLen, base int) error {
+	n := len(block)
+	syndromes := make([]int, ecLen)
+	clean := true
+	for j := range syndromes {
+		x := f.pow(j + base)
+		s := 0
+		for _, b := range block {
+			s = f.mul(s, x) ^ int(b)
+		}
+		syndromes[j] = s
+		clean = clean && s == 0
+	}
+	if clean {
+		return nil
+	}
+
+	// Berlekamp-Massey finds the error locator, lowest power first
+	locator, prev := []int{1}, []int{1}
+	errs, shift, lastDisc := 0, 1, 1
+	for i := 0; i < ecLen; i++ {
+		d := syndromes[i]
+		for j := 1; j <= errs && j < len(locator); j++ {
+			d ^= f.mul(locator[j], syndromes[i-j])
+		}
+		if d == 0 {
+			shift++
+			continue
+		}
+		next := make([]int, max(len(locator), len(prev)+shift))
+		copy(next, locator)
+		scale := f.div(d, lastDisc)
+		for j, c := range prev {
+			next[j+shift] ^= f.mul(scale, c)
+		}
+		if 2*errs <= i {
+			errs, prev, lastDisc, shift = i+1-errs, locator, d, 1
+		} else {
+			shift++
+		}
+		locator = next
+	}
+	if 2*errs > ecLen {
+		return errTooManyErrors
+	}
+
+	// Error evaluator: syndromes times locator, mod x^ecLen
+	evaluator := make([]int, ecLen)
+	for i := range evaluator {
+		for j := 0; j <= i && j < len(locator); j++ {
+			evaluator[i] ^= f.mul(locator[j], syndromes[i-j])
+		}
+	}
+	// Formal derivative: only odd powers survive in characteristic 2
+	derivative := make([]int, len(locator))
+	for j := 1; j < len(locator); j += 2 {
+		derivative[j-1] = locator[j]
+	}
+
+	// Chien search over every position, then Forney for the values
+	found := 0
+	for power := 0; power < n; power++ {
+		xInv := f.pow(-power)
+		if f.eval(locator, xInv) != 0 {
+			continue
+		}
+		denom := f.eval(derivative, xInv)
+		if denom == 0 {
+			return errTooManyErrors
+		}
+		value := f.div(f.eval(evaluator, xInv), denom)
+		value = f.mul(value, f.pow(power*(1-base)))
+		block[n-1-power] ^= byte(value)
+		found++
+	}
+	if found != errs {
+		return errTooManyErrors
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package barcode
+
+import "math"
+
+type point struct{ x, y float64 }
+
+func (p point) sub(q point) point        { return point{p.x - q.x, p.y - q.y} }
+func (p point) add(q point) point        { return point{p.x + q.x, p.y + q.y} }
+func (p point) scale(f float64) point    { return point{p.x * f, p.y * f} }
+func (p point) cross(q point) float64    { return p.x*q.y - p.y*q.x }
+func (p point) distance(q point) float64 { return math.Hypot(p.x-q.x, p.y-q.y) }
+
+// transform is a perspective transform of row vectors: [x y 1] times the
+// matrix, divided by the third component.
+type transform [3][3]float64
+
+// squareToQuad maps the unit square's corners (0,0), (1,0), (1,1) and
+// (0,1) to the given points.
+func squareToQuad(q [4]point) transform {
+	dx3 := q[0].x - q[1].x + q[2].x - q[3].x
+	dy3 := q[0].y - q[1].y + q[2].y - q[3].y
+	if dx3 == 0 && dy3 == 0 {
+		// A parallelogram needs only an affine transform
+		return transform{
+			{q[1].x - q[0].x, q[1].y - q[0].y, 0},
+			{q[2].x - q[1].x, q[2].y - q[1].y, 0},
+			{q[0].x, q[0].y, 1},
+		}
+	}
+	dx1, dx2 := q[1].x-q[2].x, q[3].x-q[2].x
+	dy1, dy2 := q[1].y-q[2].y, q[3].y-q[2].y
+	denom := dx1*dy2 - dx2*dy1
+	a13 := (dx3*dy2 - dx2*dy3) / denom
+	a23 := (dx1*dy3 - dx3*dy1) / denom
+	return transform{
+		{q[1].x - q[0].x + a13*q[1].x, q[1].y - q[0].y + a13*q[1].y, a13},
+		{q[3].x - q[0].x + a23*q[3].x, q[3].y - q[0].y + a23*q[3].y, a23},
+		{q[0].x, q[0].y, 1},
+	}
+}
+
+// quadToQuad maps the corners of one quadrilateral to another's.
+func quadToQuad(from, to [4]point) transform {
+	return squareToQuad(from).adjugate().times(squareToQuad(to))
+}
+
+// adjugate is the inverse up to a scale factor, which the division in
+// apply cancels.
+func (t transform) adjugate() transform {
+	var a transform
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r0, r1 := (j+1)%3, (j+2)%3
+			c0, c1 := (i+1)%3, (i+2)%3
+			a[i][j] = t[r0][c0]*t[r1][c1] - t[r0][c1]*t[r1][c0]
+		}
+	}
+	return a
+}
+
+// times returns the transform applying t, then o.
+func (t transform) times(o transform) transform {
+	var p transform
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				p[i][j] += t[i][k] * o[k][j]
+			}
+		}
+	}
+	return p
+}
+
+func (t transform) apply(p point) point {
+	w := t[0][2]*p.x + t[1][2]*p.y + t[2][2]
+	return point{
+		(t[0][0]*p.x + t[1][0]*p.y + t[2][0]) / w,
+		(t[0][1]*p.x + t[1][1]*p.y + t[2][1]) / w,
+	}
+}
+
+// sampleGrid reads a grid of modules, sampling each at its centre. The
+// transform maps module coordinates, with (0,0) the symbol's top left
+// corner, into the bitmap.
+func sampleGrid(bm *bitmap, t transform, cols, rows int) ([][]bool, bool) {
+	grid := make([][]bool, rows)
+	for y := range grid {
+		grid[y] = make([]bool, cols)
+		for x := range grid[y] {
+			p := t.apply(point{float64(x) + 0.5, float64(y) + 0.5})
+			// Allow a little slop at the edges, but not a symbol half off
+			// the frame
+			if p.x < -1 || p.y < -1 || p.x > float64(bm.w)+1 || p.y > float64(bm.h)+1 || math.IsNaN(p.x) || math.IsNaN(p.y) {
+				return nil, false
+			}
+			grid[y][x] = bm.atf(p.x, p.y)
+		}
+	}
+	return grid, true
+}
@@ -0,0 +1,184 @@
+package catalog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/larkin1/wmsproject/internal/api"
+)
+
+// Catalog holds items and locations in memory with lookup indexes, so
+// screens can resolve scans and search without going to the API.
+// It is safe for concurrent use.
+type Catalog struct {
+	mu sync.RWMutex
+
+	items     map[int]api.Item
+	byName    map[string]int // lower-cased name -> item ID
+	byBarcode map[string]int // barcode -> item ID
+	entries   []entry        // items in name order, pre-normalised for search
+
+	locations map[string]api.Location
+	locCodes  []entry
+}
+
+// entry is one searchable record with its keys normalised up front.
+type entry struct {
+	itemID   int
+	code     string // location code for location entries
+	name     string // lower-cased
+	words    []string
+	id       string
+	barcodes []string
+}
+
+func New() *Catalog {
+	return &Catalog{
+		items:     make(map[int]api.Item),
+		byName:    make(map[string]int),
+		byBarcode: make(map[string]int),
+		locations: make(map[string]api.Location),
+	}
+}
+
+// Refresh reloads the catalog from the client's local caches. Missing
+// caches leave the corresponding half of the catalog unchanged.
+func (c *Catalog) Refresh(client *api.Client) {
+	if items, err := client.Store.Items(); err == nil {
+		c.SetItems(items)
+	}
+	if locations, err := client.Store.Locations(); err == nil {
+		c.SetLocations(locations)
+	}
+}
+
+// SetItems replaces the items and rebuilds their indexes.
+func (c *Catalog) SetItems(items []api.Item) {
+	byID := make(map[int]api.Item, len(items))
+	byName := make(map[string]int, len(items))
+	byBarcode := make(map[string]int)
+	entries := make([]entry, 0, len(items))
+
+	for _, item := range items {
+		byID[item.ID] = item
+		name := normalise(item.Name)
+		byName[name] = item.ID
+		for _, code := range item.Barcodes {
+			byBarcode[strings.TrimSpace(code)] = item.ID
+		}
+		entries = append(entries, entry{
+			itemID:   item.ID,
+			name:     name,
+			words:    words(name),
+			id:       strconv.Itoa(item.ID),
+			barcodes: item.Barcodes,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items, c.byName, c.byBarcode, c.entries = byID, byName, byBarcode, entries
+}
+
+// SetLocations replaces the locations and rebuilds their index.
+func (c *Catalog) SetLocations(locations []api.Location) {
+	byCode := make(map[string]api.Location, len(locations))
+	codes := make([]entry, 0, len(locations))
+	for _, loc := range locations {
+		byCode[loc.LocationName] = loc
+		name := normalise(loc.LocationName)
+		codes = append(codes, entry{code: loc.LocationName, name: name, words: words(name)})
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		return codes[i].name < codes[j].name
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.locations, c.locCodes = byCode, codes
+}
+
+// Item returns an item by ID.
+func (c *Catalog) Item(id int) (api.Item, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	item, ok := c.items[id]
+	return item, ok
+}
+
+// ItemByName returns the item with exactly this name, ignoring case.
+func (c *Catalog) ItemByName(name string) (api.Item, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id, ok := c.byName[normalise(name)]
+	if !ok {
+		return api.Item{}, false
+	}
+	return c.items[id], true
+}
+
+// ItemByBarcode returns the item a scanned barcode belongs to.
+func (c *Catalog) ItemByBarcode(code string) (api.Item, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id, ok := c.byBarcode[strings.TrimSpace(code)]
+	if !ok {
+		return api.Item{}, false
+	}
+	return c.items[id], true
+}
+
+// Find looks an item up by ID, barcode or exact name, in that order.
+func (c *Catalog) Find(arg string) (api.Item, bool) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		if item, ok := c.Item(id); ok {
+			return item, true
+		}
+	}
+	if item, ok := c.ItemByBarcode(arg); ok {
+		return item, true
+	}
+	return c.ItemByName(arg)
+}
+
+// Items returns every item in name order.
+func (c *Catalog) Items() []api.Item {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	items := make([]api.Item, 0, len(c.entries))
+	for _, e := range c.entries {
+		items = append(items, c.items[e.itemID])
+	}
+	return items
+}
+
+// Location returns a location by its exact code.
+func (c *Catalog) Location(code string) (api.Location, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	loc, ok := c.locations[code]
+	return loc, ok
+}
+
+// Len returns the number of items and locations held.
+func (c *Catalog) Len() (items, locations int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items), len(c.locations)
+}
+
+func normalise(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// words splits a normalised name at spaces and punctuation.
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == '/' || r == '.' || r == ',' || r == '(' || r == ')'
+	})
+}
@@ -0,0 +1,244 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/larkin1/wmsproject/internal/api"
+)
+
+// Scores for how a query matched. Higher is better; within a tier shorter
+// names rank first.
+const (
+	scoreExact     = 1000 // whole name, ID or barcode
+	scorePrefix    = 800  // start of the name, ID or barcode
+	scoreWordStart = 600  // start of a word in the name
+	scoreSubstring = 400  // anywhere in the name
+	scoreWords     = 300  // every query word starts a name word, in any order
+	scoreSubseq    = 200  // characters in order with gaps
+	scoreTypo      = 150  // within a small edit distance of a word
+)
+
+// Match is a search result.
+type Match struct {
+	Item  api.Item
+	Score int
+}
+
+// LocationMatch is a location search result.
+type LocationMatch struct {
+	Location api.Location
+	Score    int
+}
+
+// Search returns items matching query by name, ID or barcode, best first.
+// An empty query returns every item in name order. limit <= 0 means no limit.
+func (c *Catalog) Search(query string, limit int) []Match {
+	q := normalise(query)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var matches []Match
+	for _, e := range c.entries {
+		score := 0
+		if q != "" {
+			score = e.score(q)
+			if score == 0 {
+				continue
+			}
+		}
+		matches = append(matches, Match{Item: c.items[e.itemID], Score: score})
+	}
+
+	// Stable keeps name order among equal scores
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// SearchLocations returns locations whose code matches query, best first.
+func (c *Catalog) SearchLocations(query string, limit int) []LocationMatch {
+	q := normalise(query)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var matches []LocationMatch
+	for _, e := range c.locCodes {
+		score := 0
+		if q != "" {
+			score = e.nameScore(q)
+			if score == 0 {
+				continue
+			}
+		}
+		matches = append(matches, LocationMatch{Location: c.locations[e.code], Score: score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// score rates an entry against a normalised query, 0 for no match.
+func (e entry) score(q string) int {
+	best := e.nameScore(q)
+
+	switch {
+	case e.id == q:
+		best = max(best, scoreExact)
+	case strings.HasPrefix(e.id, q):
+		best = max(best, scorePrefix-len(e.id))
+	}
+
+	for _, code := range e.barcodes {
+		code = strings.ToLower(code)
+		switch {
+		case code == q:
+			// A scanned barcode should beat a name that happens to contain the digits
+			best = max(best, scoreExact+1)
+		case strings.HasPrefix(code, q):
+			best = max(best, scorePrefix-len(code))
+		}
+	}
+	return best
+}
+
+func (e entry) nameScore(q string) int {
+	// Shorter names rank first within a tier, never dropping into the next
+	penalty := min(len(e.name), 99)
+
+	switch {
+	case e.name == q:
+		return scoreExact
+	case strings.HasPrefix(e.name, q):
+		return scorePrefix - penalty
+	}
+	for _, w := range e.words {
+		if strings.HasPrefix(w, q) {
+			return scoreWordStart - penalty
+		}
+	}
+	if strings.Contains(e.name, q) {
+		return scoreSubstring - penalty
+	}
+
+	if terms := words(q); len(terms) > 1 && e.allWordsStart(terms) {
+		return scoreWords - penalty
+	}
+
+	if gaps, ok := subsequence(q, e.name); ok {
+		return scoreSubseq - min(gaps, 49)
+	}
+
+	if d := e.typoDistance(q); d > 0 {
+		return scoreTypo - 40*d
+	}
+	return 0
+}
+
+// allWordsStart reports whether every term is the start of some word.
+func (e entry) allWordsStart(terms []string) bool {
+	for _, t := range terms {
+		found := false
+		for _, w := range e.words {
+			if strings.HasPrefix(w, t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// typoDistance returns the smallest edit distance between q and the start
+// of any word, or 0 if none is within the allowed number of typos.
+func (e entry) typoDistance(q string) int {
+	allowed := maxTypos(len(q))
+	if allowed == 0 {
+		return 0
+	}
+
+	best := allowed + 1
+	for _, w := range e.words {
+		// Compare against a prefix of the word so partial typing still matches
+		if len(w) > len(q)+allowed {
+			w = w[:len(q)+allowed]
+		}
+		if d := distance(q, w); d < best {
+			best = d
+		}
+	}
+	if best == 0 || best > allowed {
+		return 0
+	}
+	return best
+}
+
+// maxTypos allows one typo from four characters and two from eight.
+func maxTypos(n int) int {
+	switch {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// subsequence reports whether q's characters appear in s in order, and how
+// many characters of s were skipped between the first and last match.
+func subsequence(q, s string) (gaps int, ok bool) {
+	qi, start := 0, -1
+	for i := 0; i < len(s) && qi < len(q); i++ {
+		if s[i] == q[qi] {
+			if start < 0 {
+				start = i
+			}
+			qi++
+			if qi == len(q) {
+				return i - start + 1 - len(q), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// distance is the optimal string alignment distance: insertions, deletions,
+// substitutions and transpositions of adjacent characters each cost one.
+func distance(a, b string) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,191 @@
+// Package cli implements the wms command-line interface.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/queue"
+	"github.com/larkin1/wmsproject/internal/webhook"
+)
+
+// env is what every command runs against.
+type env struct {
+	settings *config.Settings
+	dataPath string
+	api      *api.Client
+	queue    *queue.Queue
+	hooks    *webhook.Dispatcher // nil without webhooks in settings
+	out      *output
+	stderr   io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"items":     {"items [-search text] [-limit n]", runItems},
+	"locations": {"locations [-prefix code]", runLocations},
+	"stock":     {"stock [-location code] [-item id|name]", runStock},
+	"commit":    {"commit -location code -item id|name -delta n [-uom unit] [-ref reference] [-force] [-no-flush]", runCommit},
+	"queue":     {"queue status|flush", runQueue},
+	"import":    {"import [-items file] [-locations file] [-stock file] [-apply]", runImport},
+	"export":    {"export -data items|locations|stock|commits [-o file] [-as csv|jsonl|xlsx] [-from date] [-to date] [-location prefix]", runExport},
+	"labels":    {"labels -kind locations|items [-filter text] [-template name] [-o file.zpl|file.pdf] [-print] [-printer host:port]", runLabels},
+	"report":    {"report -kind stock|movements|variance [-o file] [-from date] [-to date] [-location prefix] [-ref prefix]", runReport},
+	"serve":     {"serve [-addr host:port]", runServe},
+	"webhooks":  {"webhooks [-n count]", runWebhooks},
+}
+
+// errUsage means the command's arguments were wrong and its usage should be shown.
+var errUsage = errors.New("usage")
+
+// Run executes the command line and returns the process exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("wms", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	settingsPath := fs.String("settings", "", "path to settings.json (default $WMS_SETTINGS or the app's settings)")
+	site := fs.String("site", "", "site to use instead of the one in settings")
+	format := fs.String("format", "table", "output format: table or json")
+	verbose := fs.Bool("v", false, "log API and queue activity to stderr")
+	fs.Usage = func() { usage(fs, stderr) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		usage(fs, stderr)
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "wms: unknown command %q\n", fs.Arg(0))
+		usage(fs, stderr)
+		return 2
+	}
+	if *format != formatTable && *format != formatJSON {
+		fmt.Fprintf(stderr, "wms: unknown format %q\n", *format)
+		return 2
+	}
+
+	if *verbose {
+		log.SetOutput(stderr)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	e, err := newEnv(*settingsPath, *site)
+	if err != nil {
+		fmt.Fprintf(stderr, "wms: %v\n", err)
+		return 1
+	}
+	e.out = &output{w: stdout, format: *format}
+	e.stderr = stderr
+
+	err = cmd.run(e, fs.Args()[1:])
+	if e.hooks != nil {
+		// Deliveries that fail wait in the outbox for the next run
+		e.hooks.Flush()
+	}
+	if err != nil {
+		if err == errUsage {
+			fmt.Fprintf(stderr, "Usage: wms %s\n", cmd.usage)
+			return 2
+		}
+		fmt.Fprintf(stderr, "wms %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
+func usage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintln(w, "Usage: wms [flags] <command> [command flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+}
+
+// newEnv loads settings and opens the client and queue for the site, using
+// the same data directory as the desktop app.
+func newEnv(settingsPath, site string) (*env, error) {
+	if settingsPath == "" {
+		settingsPath = os.Getenv("WMS_SETTINGS")
+	}
+	if settingsPath == "" {
+		settingsPath = filepath.Join(config.DefaultDir(), "settings.json")
+	}
+
+	settings, err := config.Load(settingsPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading settings: %w", err)
+	}
+	if settings.APIURL == "" || settings.APIKey == "" {
+		return nil, fmt.Errorf("%s has no api_url or api_key", settingsPath)
+	}
+	if settings.DeviceID == "" {
+		settings.DeviceID = config.DefaultDeviceID
+	}
+	if site != "" {
+		settings.Site = site
+	}
+
+	dataPath, err := config.SiteDir(filepath.Dir(settingsPath), settings.Site)
+	if err != nil {
+		return nil, err
+	}
+	os.MkdirAll(dataPath, 0755)
+
+	client := api.NewClient(settings.APIURL, settings.APIKey, dataPath)
+	client.Site = settings.Site
+	if settings.MaxCacheAge != "" {
+		if maxAge, err := time.ParseDuration(settings.MaxCacheAge); err == nil {
+			client.MaxCacheAge = maxAge
+		}
+	}
+
+	e := &env{
+		settings: settings,
+		dataPath: dataPath,
+		api:      client,
+		queue:    queue.NewQueue(client, dataPath),
+	}
+	if len(settings.Webhooks) > 0 {
+		e.hooks = webhook.New(client, settings.Webhooks, dataPath)
+		e.queue.OnBatchSynced(e.hooks.CommitsSynced)
+	}
+	return e, nil
+}
+
+// newFlags creates a subcommand flag set; parse errors print its flags and
+// the command returns errUsage.
+func (e *env) newFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// warnOffline notes on stderr when a table came from the local store.
+func (e *env) warnOffline(table string) {
+	if f := e.api.Freshness(table); f.Offline() {
+		fmt.Fprintf(e.stderr, "wms: offline, %s from %s\n", table, f.FetchedAt.Format("2006-01-02 15:04"))
+	}
+}
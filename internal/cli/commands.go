@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/catalog"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/queue"
+	"github.com/larkin1/wmsproject/internal/stock"
+)
+
+func runItems(e *env, args []string) error {
+	fs := e.newFlags("items")
+	search := fs.String("search", "", "rank items by name, ID or barcode")
+	limit := fs.Int("limit", 0, "maximum number of items, 0 for all")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	items, err := e.api.FetchItems()
+	if err != nil {
+		return err
+	}
+	e.warnOffline("items")
+
+	cat := catalog.New()
+	cat.SetItems(items)
+	items = nil
+	for _, m := range cat.Search(*search, *limit) {
+		items = append(items, m.Item)
+	}
+
+	var rows [][]string
+	for _, item := range items {
+		rows = append(rows, []string{
+			strconv.Itoa(item.ID), item.Name, item.Base(),
+			strconv.Itoa(item.MinQty), strconv.Itoa(item.MaxQty),
+			strings.Join(item.Barcodes, ","),
+		})
+	}
+	return e.out.write(items, []string{"ID", "NAME", "UNIT", "MIN", "MAX", "BARCODES"}, rows)
+}
+
+func runLocations(e *env, args []string) error {
+	fs := e.newFlags("locations")
+	prefix := fs.String("prefix", "", "only locations whose code starts with this")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	all, err := e.api.FetchLocations()
+	if err != nil {
+		return err
+	}
+	e.warnOffline("locations")
+
+	var locations []api.Location
+	for _, loc := range all {
+		if strings.HasPrefix(loc.LocationName, *prefix) {
+			locations = append(locations, loc)
+		}
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].LocationName < locations[j].LocationName
+	})
+
+	var rows [][]string
+	for _, loc := range locations {
+		capacity := "-"
+		if loc.Capacity > 0 {
+			capacity = strconv.Itoa(loc.Capacity)
+		}
+		rows = append(rows, []string{loc.LocationName, api.ItemList(loc.Items), capacity})
+	}
+	return e.out.write(locations, []string{"LOCATION", "ITEMS", "CAPACITY"}, rows)
+}
+
+// stockRow is one line of stock output, including commits not yet synced.
+type stockRow struct {
+	Location string `json:"location"`
+	ItemID   int    `json:"item_id"`
+	Item     string `json:"item"`
+	Qty      int    `json:"qty"`
+	Pending  int    `json:"pending,omitempty"`
+}
+
+func runStock(e *env, args []string) error {
+	fs := e.newFlags("stock")
+	location := fs.String("location", "", "only this location")
+	itemArg := fs.String("item", "", "only this item, by ID, name or barcode")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	overview, err := e.api.FetchOverview()
+	if err != nil {
+		return err
+	}
+	e.warnOffline("overview")
+
+	items, _ := e.api.FetchItems()
+	cat := catalog.New()
+	cat.SetItems(items)
+
+	itemID := 0
+	if *itemArg != "" {
+		item, err := resolveItem(cat, *itemArg)
+		if err != nil {
+			return err
+		}
+		itemID = item.ID
+	}
+
+	type key struct {
+		location string
+		itemID   int
+	}
+	byKey := make(map[key]*stockRow)
+	var rows []*stockRow
+	row := func(location string, itemID int) *stockRow {
+		k := key{location, itemID}
+		if r, ok := byKey[k]; ok {
+			return r
+		}
+		item, _ := cat.Item(itemID)
+		r := &stockRow{Location: location, ItemID: itemID, Item: item.Name}
+		byKey[k] = r
+		rows = append(rows, r)
+		return r
+	}
+
+	for _, level := range overview {
+		row(level.Location, level.ItemID).Qty += level.Qty
+	}
+	for _, commit := range e.queue.Pending() {
+		r := row(commit.Location, commit.ItemID)
+		r.Qty += commit.Delta
+		r.Pending += commit.Delta
+	}
+
+	var result []stockRow
+	for _, r := range rows {
+		if (*location == "" || r.Location == *location) && (itemID == 0 || r.ItemID == itemID) {
+			result = append(result, *r)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Location != result[j].Location {
+			return result[i].Location < result[j].Location
+		}
+		return result[i].ItemID < result[j].ItemID
+	})
+
+	var table [][]string
+	for _, r := range result {
+		pending := ""
+		if r.Pending != 0 {
+			pending = fmt.Sprintf("%+d", r.Pending)
+		}
+		table = append(table, []string{r.Location, strconv.Itoa(r.ItemID), r.Item, strconv.Itoa(r.Qty), pending})
+	}
+	return e.out.write(result, []string{"LOCATION", "ITEM_ID", "ITEM", "QTY", "PENDING"}, table)
+}
+
+// resolveItem finds an item by ID, exact name or barcode.
+func resolveItem(cat *catalog.Catalog, arg string) (api.Item, error) {
+	if item, ok := cat.Find(arg); ok {
+		return item, nil
+	}
+	if id, err := strconv.Atoi(arg); err == nil {
+		// Unknown locally but numeric: trust the ID like the app's item picker does
+		return api.Item{ID: id}, nil
+	}
+	return api.Item{}, fmt.Errorf("no item matches %q", arg)
+}
+
+// commitResult reports what the commit command did.
+type commitResult struct {
+	Commit  queue.Commit `json:"commit"`
+	Sent    int          `json:"sent"`
+	Pending int          `json:"pending"`
+}
+
+func runCommit(e *env, args []string) error {
+	fs := e.newFlags("commit")
+	location := fs.String("location", "", "location code")
+	itemArg := fs.String("item", "", "item ID, name or barcode")
+	qty := fs.Int("delta", 0, "quantity to add, negative to remove")
+	unit := fs.String("uom", "", "unit of measure for -delta (default the item's base unit)")
+	reference := fs.String("ref", "", "order or document reference")
+	force := fs.Bool("force", false, "commit even if the location is over capacity")
+	noFlush := fs.Bool("no-flush", false, "only queue the commit; don't send it now")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *location == "" || *itemArg == "" || *qty == 0 {
+		fs.PrintDefaults()
+		return errUsage
+	}
+
+	items, err := e.api.FetchItems()
+	if err != nil {
+		return err
+	}
+	cat := catalog.New()
+	cat.SetItems(items)
+
+	item, err := resolveItem(cat, *itemArg)
+	if err != nil {
+		return err
+	}
+	delta, err := item.ToBaseUnits(*unit, *qty)
+	if err != nil {
+		return err
+	}
+
+	commit := queue.Commit{
+		DeviceID:  e.settings.DeviceID,
+		Location:  *location,
+		Delta:     delta,
+		ItemID:    item.ID,
+		UoM:       *unit,
+		UoMQty:    *qty,
+		Site:      e.settings.Site,
+		Reference: *reference,
+	}
+
+	if delta > 0 && !*force && e.settings.CapacityPolicy != config.CapacityOff {
+		if err := e.checkCapacity(commit); err != nil {
+			if e.settings.CapacityPolicy == config.CapacityBlock {
+				return fmt.Errorf("%v (use -force to override)", err)
+			}
+			fmt.Fprintf(e.stderr, "wms: warning: %v\n", err)
+		}
+	}
+
+	e.queue.Submit(commit)
+	result := commitResult{Commit: commit, Pending: len(e.queue.Pending())}
+	if !*noFlush {
+		result.Sent, result.Pending, err = e.queue.Flush()
+		if err != nil {
+			fmt.Fprintf(e.stderr, "wms: warning: %v; the commit stays queued\n", err)
+		}
+	}
+
+	rows := [][]string{{commit.Location, strconv.Itoa(commit.ItemID), item.Name,
+		strconv.Itoa(commit.Delta), strconv.Itoa(result.Sent), strconv.Itoa(result.Pending)}}
+	return e.out.write(result, []string{"LOCATION", "ITEM_ID", "ITEM", "DELTA", "SENT", "PENDING"}, rows)
+}
+
+// checkCapacity returns an error if the commit would overfill its location.
+func (e *env) checkCapacity(commit queue.Commit) error {
+	locations, err := e.api.FetchLocations()
+	if err != nil {
+		return nil
+	}
+	for _, loc := range locations {
+		if loc.LocationName == commit.Location {
+			rows, _ := e.api.FetchOverview()
+			return stock.NewLevels(rows, e.queue.Pending()).CheckCapacity(loc, commit.ItemID, commit.Delta)
+		}
+	}
+	return nil
+}
+
+// queueStatus is the output of queue status and queue flush.
+type queueStatus struct {
+	Pending int            `json:"pending"`
+	Sent    int            `json:"sent,omitempty"`
+	Commits []queue.Commit `json:"commits"`
+}
+
+func runQueue(e *env, args []string) error {
+	if len(args) != 1 || (args[0] != "status" && args[0] != "flush") {
+		return errUsage
+	}
+
+	var status queueStatus
+	if args[0] == "flush" {
+		sent, _, err := e.queue.Flush()
+		if err != nil {
+			return err
+		}
+		status.Sent = sent
+	}
+	status.Commits = append([]queue.Commit{}, e.queue.Pending()...)
+	status.Pending = len(status.Commits)
+
+	var rows [][]string
+	for _, c := range status.Commits {
+		rows = append(rows, []string{c.Location, strconv.Itoa(c.ItemID), strconv.Itoa(c.Delta), c.Reference, c.DeviceID})
+	}
+	if e.out.format == formatTable {
+		if args[0] == "flush" {
+			fmt.Fprintf(e.out.w, "Sent %d, %d pending\n", status.Sent, status.Pending)
+		} else {
+			fmt.Fprintf(e.out.w, "%d pending\n", status.Pending)
+		}
+		if status.Pending == 0 {
+			return nil
+		}
+	}
+	return e.out.write(status, []string{"LOCATION", "ITEM_ID", "DELTA", "REFERENCE", "DEVICE"}, rows)
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/larkin1/wmsproject/internal/export"
+)
+
+func runExport(e *env, args []string) error {
+	fs := e.newFlags("export")
+	data := fs.String("data", "", "datasets to export: "+strings.Join(export.Datasets, ", ")+" (several, comma-separated, for xlsx)")
+	path := fs.String("o", "", "output file (default stdout)")
+	format := fs.String("as", "", "csv, jsonl or xlsx (default from the -o extension, else csv)")
+	from := fs.String("from", "", "commits from this date, YYYY-MM-DD")
+	to := fs.String("to", "", "commits up to and including this date, YYYY-MM-DD")
+	location := fs.String("location", "", "only locations starting with this code")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *data == "" {
+		fs.PrintDefaults()
+		return errUsage
+	}
+
+	if *format == "" {
+		*format = export.CSV
+		if f, ok := export.FormatFor(*path); ok {
+			*format = f
+		}
+	}
+
+	filter := export.Filter{Location: *location}
+	var err error
+	if filter.From, err = export.ParseDate(*from); err != nil {
+		return err
+	}
+	if filter.To, err = export.ParseDate(*to); err != nil {
+		return err
+	}
+	if !filter.To.IsZero() {
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+
+	var tables []*export.Table
+	for _, dataset := range strings.Split(*data, ",") {
+		t, err := export.Fetch(e.api, strings.TrimSpace(dataset), filter)
+		if err != nil {
+			return err
+		}
+		e.warnOffline(export.FreshnessTable(t.Name))
+		tables = append(tables, t)
+	}
+
+	if *path == "" {
+		return export.Write(e.out.w, *format, tables...)
+	}
+	if err := export.WriteFile(*path, *format, tables...); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		fmt.Fprintf(e.stderr, "wms: exported %d %s rows to %s\n", len(t.Rows), t.Name, *path)
+	}
+	return nil
+}
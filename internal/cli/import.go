@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/larkin1/wmsproject/internal/importer"
+)
+
+// importResult is the JSON output of the import command.
+type importResult struct {
+	Changes   []importer.Change `json:"changes"`
+	Unchanged int               `json:"unchanged"`
+	Problems  []string          `json:"problems"`
+	Applied   bool              `json:"applied"`
+}
+
+func runImport(e *env, args []string) error {
+	fs := e.newFlags("import")
+	itemsPath := fs.String("items", "", "items CSV (id,name[,base_unit,min_qty,max_qty,barcodes])")
+	locationsPath := fs.String("locations", "", "locations CSV (location,items[,capacity])")
+	stockPath := fs.String("stock", "", "opening stock CSV (location,item_id,qty)")
+	apply := fs.Bool("apply", false, "write the changes; without it only the dry-run diff is shown")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *itemsPath == "" && *locationsPath == "" && *stockPath == "" {
+		fs.PrintDefaults()
+		return errUsage
+	}
+
+	in := &importer.Input{}
+	for _, f := range []struct {
+		path string
+		read func(io.Reader)
+	}{
+		{*itemsPath, in.ReadItems},
+		{*locationsPath, in.ReadLocations},
+		{*stockPath, in.ReadStock},
+	} {
+		if f.path == "" {
+			continue
+		}
+		file, err := os.Open(f.path)
+		if err != nil {
+			return err
+		}
+		f.read(file)
+		file.Close()
+	}
+
+	cur, err := importer.FetchCurrent(e.api)
+	if err != nil {
+		return err
+	}
+	plan := importer.NewPlan(in, cur)
+
+	result := importResult{Changes: plan.Changes, Unchanged: plan.Unchanged, Problems: []string{}}
+	for _, p := range plan.Problems {
+		result.Problems = append(result.Problems, p.Error())
+	}
+
+	if *apply && plan.OK() && !plan.Empty() {
+		err := plan.Apply(e.api, e.settings.DeviceID, func(done, total int) {
+			fmt.Fprintf(e.stderr, "wms: written %d/%d\n", done, total)
+		})
+		if err != nil {
+			return err
+		}
+		result.Applied = true
+	}
+
+	if e.out.format == formatJSON {
+		if err := e.out.write(result, nil, nil); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range plan.Changes {
+			fmt.Fprintln(e.out.w, c)
+		}
+		for _, p := range result.Problems {
+			fmt.Fprintln(e.out.w, "problem  ", p)
+		}
+		state := "dry run, use -apply to write"
+		if result.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(e.out.w, "%d changes, %d unchanged, %d problems (%s)\n",
+			len(plan.Changes), plan.Unchanged, len(plan.Problems), state)
+	}
+
+	if !plan.OK() {
+		return fmt.Errorf("%d problems, nothing written", len(plan.Problems))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/larkin1/wmsproject/internal/label"
+)
+
+func runLabels(e *env, args []string) error {
+	fs := e.newFlags("labels")
+	kind := fs.String("kind", "", "what to label: "+strings.Join(label.Kinds, ", "))
+	filter := fs.String("filter", "", "location code prefix, or item search text")
+	template := fs.String("template", "", "label template (default the kind's built-in one)")
+	path := fs.String("o", "", "output file, .zpl or .pdf (default ZPL on stdout)")
+	print := fs.Bool("print", false, "send ZPL to the label printer")
+	printer := fs.String("printer", "", "printer address, host[:port] (default label_printer from settings)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *kind == "" {
+		fs.PrintDefaults()
+		return errUsage
+	}
+
+	if *template == "" {
+		*template = strings.TrimSuffix(*kind, "s")
+	}
+	t, err := label.FindTemplate(e.settings.LabelTemplates, *template)
+	if err != nil {
+		return err
+	}
+
+	labels, err := label.Fetch(e.api, *kind, *filter)
+	if err != nil {
+		return err
+	}
+	e.warnOffline(*kind)
+	if len(labels) == 0 {
+		return fmt.Errorf("no %s match %q", *kind, *filter)
+	}
+
+	if strings.EqualFold(filepath.Ext(*path), ".pdf") {
+		doc, err := label.PDF(labels, t)
+		if err != nil {
+			return err
+		}
+		if err := doc.WriteFile(*path); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stderr, "wms: wrote %d labels to %s\n", len(labels), *path)
+		return nil
+	}
+
+	zpl, err := label.ZPL(labels, t, e.settings.LabelDPI)
+	if err != nil {
+		return err
+	}
+
+	if *print {
+		addr := *printer
+		if addr == "" {
+			addr = e.settings.LabelPrinter
+		}
+		if addr == "" {
+			return fmt.Errorf("no printer: set label_printer in settings or use -printer")
+		}
+		if err := label.Send(addr, zpl); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stderr, "wms: sent %d labels to %s\n", len(labels), addr)
+	}
+
+	switch {
+	case *path != "":
+		if err := os.WriteFile(*path, zpl, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stderr, "wms: wrote %d labels to %s\n", len(labels), *path)
+	case !*print:
+		e.out.w.Write(zpl)
+	}
+	return nil
+}
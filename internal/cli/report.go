@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/larkin1/wmsproject/internal/export"
+	"github.com/larkin1/wmsproject/internal/location"
+	"github.com/larkin1/wmsproject/internal/report"
+)
+
+func runReport(e *env, args []string) error {
+	fs := e.newFlags("report")
+	kind := fs.String("kind", "", "report to build: "+strings.Join(report.Kinds, ", "))
+	path := fs.String("o", "", "output file (default the site's reports directory)")
+	from := fs.String("from", "", "commits from this date, YYYY-MM-DD")
+	to := fs.String("to", "", "commits up to and including this date, YYYY-MM-DD")
+	loc := fs.String("location", "", "only locations starting with this code")
+	ref := fs.String("ref", report.CountReference, "reference prefix of cycle count adjustments")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *kind == "" {
+		fs.PrintDefaults()
+		return errUsage
+	}
+
+	opts := report.Options{Site: e.settings.Site, Location: *loc, Reference: *ref}
+	var err error
+	if opts.From, err = export.ParseDate(*from); err != nil {
+		return err
+	}
+	if opts.To, err = export.ParseDate(*to); err != nil {
+		return err
+	}
+	if !opts.To.IsZero() {
+		opts.To = opts.To.AddDate(0, 0, 1)
+	}
+
+	parser, err := location.NewParser(e.settings.LocationPattern)
+	if err != nil {
+		return err
+	}
+	doc, err := report.Generate(e.api, parser, *kind, opts)
+	if err != nil {
+		return err
+	}
+	if *kind == report.Stock {
+		e.warnOffline("overview")
+	}
+
+	if *path == "" {
+		*path, err = report.Save(doc, e.dataPath, *kind)
+	} else {
+		err = doc.WriteFile(*path)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(e.out.w, *path)
+	return nil
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/larkin1/wmsproject/internal/mqtt"
+	"github.com/larkin1/wmsproject/internal/server"
+)
+
+// runServe runs the local HTTP API until interrupted, with the queue
+// worker, webhooks and MQTT publishing in the background.
+func runServe(e *env, args []string) error {
+	fs := e.newFlags("serve")
+	addr := fs.String("addr", e.settings.ServerAddr, "address to listen on (default server_addr from settings)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *addr == "" {
+		return errors.New("no address: pass -addr or set server_addr in settings")
+	}
+
+	srv := server.New(e.api, e.queue, e.settings.ServerToken)
+	srv.DeviceID = e.settings.DeviceID
+	srv.CapacityPolicy = e.settings.CapacityPolicy
+
+	if e.hooks != nil {
+		e.hooks.Start()
+		defer e.hooks.Stop()
+	}
+	if e.settings.MQTTBroker != "" {
+		events, err := mqtt.Start(e.settings, e.queue, e.dataPath)
+		if err != nil {
+			return err
+		}
+		defer events.Stop()
+	}
+	e.queue.Start()
+	defer e.queue.Stop()
+	if err := srv.Start(*addr); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stderr, "wms: serving on %s, ctrl-c to stop\n", *addr)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	srv.Stop()
+	return nil
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/larkin1/wmsproject/internal/webhook"
+)
+
+// runWebhooks shows the most recent webhook delivery attempts.
+func runWebhooks(e *env, args []string) error {
+	fs := e.newFlags("webhooks")
+	n := fs.Int("n", 20, "number of attempts to show, 0 for all")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	entries, err := webhook.ReadLog(e.dataPath, *n)
+	if err != nil {
+		return err
+	}
+	if e.hooks != nil {
+		fmt.Fprintf(e.stderr, "wms: %d deliveries waiting\n", e.hooks.Pending())
+	}
+
+	var rows [][]string
+	for _, entry := range entries {
+		status := ""
+		if entry.Status != 0 {
+			status = strconv.Itoa(entry.Status)
+		}
+		rows = append(rows, []string{entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Event, entry.ID,
+			entry.URL, strconv.Itoa(entry.Attempt), status, entry.Result, entry.Error})
+	}
+	if entries == nil {
+		entries = []webhook.LogEntry{}
+	}
+	return e.out.write(entries, []string{"TIME", "EVENT", "ID", "URL", "ATTEMPT", "STATUS", "RESULT", "ERROR"}, rows)
+}
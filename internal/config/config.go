@@ -2,14 +2,182 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const DefaultDeviceID = "TOUGHPAD01"
+
+// AppID is the Fyne application ID, which also names the app's storage directory.
+const AppID = "com.velocidrone.velocidrone"
+
+// Capacity policies for ADD commits that would overfill a location.
+const (
+	CapacityWarn  = "warn"
+	CapacityBlock = "block"
+	CapacityOff   = "off"
 )
 
 type Settings struct {
 	APIURL   string `json:"api_url"`
 	APIKey   string `json:"api_key"`
 	DeviceID string `json:"device_id"`
+
+	// LocationPattern splits location codes into site/zone/aisle/rack/bin,
+	// e.g. "{zone}-{aisle}-{rack}-{bin}". Empty uses the default pattern.
+	LocationPattern string `json:"location_pattern,omitempty"`
+
+	// Site is the active warehouse; Sites lists those the device can switch to.
+	Site  string   `json:"site,omitempty"`
+	Sites []string `json:"sites,omitempty"`
+
+	// PutawayRules limit which zones items may be put away in.
+	PutawayRules []ZoneRule `json:"putaway_rules,omitempty"`
+
+	// CapacityPolicy is CapacityWarn (default), CapacityBlock or CapacityOff.
+	CapacityPolicy string `json:"capacity_policy,omitempty"`
+
+	// Realtime is "supabase", "sse" or empty to disable live updates.
+	// RealtimeURL is the event stream endpoint for "sse".
+	Realtime    string `json:"realtime,omitempty"`
+	RealtimeURL string `json:"realtime_url,omitempty"`
+
+	// MaxCacheAge is how old offline data may be before it is refused,
+	// as a duration such as "72h". Empty means no limit.
+	MaxCacheAge string `json:"max_cache_age,omitempty"`
+
+	// LabelPrinter is a Zebra printer's raw TCP address, "host" or
+	// "host:port" (port 9100 if omitted). LabelDPI is its resolution, 203
+	// if unset. LabelTemplates are added to the built-in label layouts.
+	LabelPrinter   string          `json:"label_printer,omitempty"`
+	LabelDPI       int             `json:"label_dpi,omitempty"`
+	LabelTemplates []LabelTemplate `json:"label_templates,omitempty"`
+
+	// ServerAddr, e.g. ":8080", starts the local HTTP API for other tools on
+	// the network. Requests must carry ServerToken as a bearer token.
+	ServerAddr  string `json:"server_addr,omitempty"`
+	ServerToken string `json:"server_token,omitempty"`
+
+	// Webhooks are notified of synced commits and stock events.
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+
+	// MQTTBroker, e.g. "tcp://broker:1883" or "tls://broker:8883", enables
+	// publishing synced commits and queue status. Topics may contain
+	// {site} and {device}; MQTTQoS is 0, 1 or 2.
+	MQTTBroker      string `json:"mqtt_broker,omitempty"`
+	MQTTClientID    string `json:"mqtt_client_id,omitempty"`
+	MQTTUsername    string `json:"mqtt_username,omitempty"`
+	MQTTPassword    string `json:"mqtt_password,omitempty"`
+	MQTTCommitTopic string `json:"mqtt_commit_topic,omitempty"`
+	MQTTQueueTopic  string `json:"mqtt_queue_topic,omitempty"`
+	MQTTQoS         int    `json:"mqtt_qos,omitempty"`
+
+	// ScannerPrefix and ScannerSuffix are what a keyboard-wedge scanner is
+	// programmed to send around each code, so scans are caught whichever
+	// field has focus; the suffix defaults to Enter. ScannerDevice is a
+	// serial or USB-CDC scanner's path, e.g. "/dev/ttyACM0" or "COM3".
+	ScannerPrefix string `json:"scanner_prefix,omitempty"`
+	ScannerSuffix string `json:"scanner_suffix,omitempty"`
+	ScannerDevice string `json:"scanner_device,omitempty"`
+
+	// CameraCommand captures one frame from a camera as PNG or JPEG on
+	// stdout, enabling camera scanning on the Commit screen, e.g.
+	// "ffmpeg -loglevel error -f v4l2 -i /dev/video0 -frames:v 1 -f image2pipe -c:v png -".
+	CameraCommand string `json:"camera_command,omitempty"`
+
+	// ScanRules classify scanned codes as locations, items, operator
+	// badges or commands, first match winning. With rules set, a scan
+	// matching none of them is rejected.
+	ScanRules []ScanRule `json:"scan_rules,omitempty"`
+}
+
+// LabelTemplate lays out a label: the name in bold, a barcode of the code
+// and the code in plain text. Sizes are in millimetres.
+type LabelTemplate struct {
+	Name     string  `json:"name"`
+	Width    float64 `json:"width_mm"`
+	Height   float64 `json:"height_mm"`
+	Barcode  string  `json:"barcode"` // "code128" or "qr"
+	HideName bool    `json:"hide_name,omitempty"`
+	HideCode bool    `json:"hide_code,omitempty"`
+}
+
+// Webhook is an endpoint that receives events as signed JSON POSTs.
+// Events filters by event name; empty means every event.
+type Webhook struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events,omitempty"`
+}
+
+// Scan rule kinds.
+const (
+	ScanLocation = "location"
+	ScanItem     = "item"
+	ScanBadge    = "badge"
+	ScanCommand  = "command"
+)
+
+// ScanRule matches codes that start with Prefix and whose remainder, if
+// Pattern is set, matches that regular expression in full. StripPrefix
+// removes the prefix before the code is used, e.g. "OP:" from a badge.
+type ScanRule struct {
+	Kind        string `json:"kind"`
+	Prefix      string `json:"prefix,omitempty"`
+	Pattern     string `json:"pattern,omitempty"`
+	StripPrefix bool   `json:"strip_prefix,omitempty"`
+}
+
+// ZoneRule restricts put-away of the listed items to the listed zones.
+// A rule without items applies to every item not covered by another rule.
+type ZoneRule struct {
+	ItemIDs []int    `json:"item_ids,omitempty"`
+	Zones   []string `json:"zones"`
+}
+
+// SiteDir returns the directory holding caches and the queue for a site.
+// The unnamed site uses basePath itself so existing data keeps working.
+func SiteDir(basePath, site string) (string, error) {
+	if site == "" {
+		return basePath, nil
+	}
+	if err := CheckSite(site); err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, "sites", site), nil
+}
+
+// CheckSite rejects site names that aren't a single directory name, so a
+// site from settings can't point its data outside the sites directory.
+func CheckSite(site string) error {
+	if site == "." || !filepath.IsLocal(site) || filepath.Base(site) != site || strings.ContainsAny(site, `/\`) {
+		return fmt.Errorf("invalid site name %q", site)
+	}
+	return nil
+}
+
+// DefaultDir returns the desktop app's storage directory, holding
+// settings.json and the site data, so command-line tools can share it.
+// It mirrors Fyne's storage root for AppID.
+func DefaultDir() string {
+	home, _ := os.UserHomeDir()
+	var root string
+	switch runtime.GOOS {
+	case "darwin":
+		root = filepath.Join(home, "Library", "Preferences", "fyne")
+	case "windows":
+		root = filepath.Join(home, "AppData", "Roaming", "fyne")
+	default:
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			dir = filepath.Join(home, ".config")
+		}
+		root = filepath.Join(dir, "fyne")
+	}
+	return filepath.Join(root, AppID)
 }
 
 func Load(filePath string) (*Settings, error) {
@@ -43,7 +211,7 @@ func CreateDefault(filePath string) (*Settings, error) {
 	settings := &Settings{
 		APIURL:   "",
 		APIKey:   "",
-		DeviceID: "TOUGHPAD01",
+		DeviceID: DefaultDeviceID,
 	}
 
 	err := Save(filePath, settings)
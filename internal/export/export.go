@@ -0,0 +1,178 @@
+// Package export writes items, locations, on-hand stock and commit history
+// as CSV, JSON Lines or XLSX.
+package export
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/larkin1/wmsproject/internal/api"
+)
+
+// Datasets that can be exported.
+const (
+	Items     = "items"
+	Locations = "locations"
+	Stock     = "stock"
+	Commits   = "commits"
+)
+
+// Datasets lists every dataset, in menu order.
+var Datasets = []string{Items, Locations, Stock, Commits}
+
+// FreshnessTable returns the table whose api.Freshness tells whether a
+// dataset came from the cache. Commits are never cached.
+func FreshnessTable(dataset string) string {
+	if dataset == Stock {
+		return "overview"
+	}
+	return dataset
+}
+
+// Formats an export can be written in.
+const (
+	CSV   = "csv"
+	JSONL = "jsonl"
+	XLSX  = "xlsx"
+)
+
+var Formats = []string{CSV, JSONL, XLSX}
+
+// FormatFor guesses the format from a file name's extension.
+func FormatFor(path string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return CSV, true
+	case ".jsonl", ".ndjson":
+		return JSONL, true
+	case ".xlsx":
+		return XLSX, true
+	}
+	return "", false
+}
+
+// Filter narrows an export. From and To bound commit history by creation
+// time (To is exclusive); Location keeps only codes starting with it.
+type Filter struct {
+	From     time.Time
+	To       time.Time
+	Location string
+}
+
+// ParseDate parses a YYYY-MM-DD date in local time. Empty gives the zero time.
+func ParseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// Table is a dataset ready to write: a header and rows of string, int or
+// time.Time values.
+type Table struct {
+	Name   string
+	Header []string
+	Rows   [][]interface{}
+}
+
+func (f Filter) location(code string) bool {
+	return strings.HasPrefix(code, f.Location)
+}
+
+// Fetch loads a dataset from the API (or the local store when offline) and
+// builds its table.
+func Fetch(client *api.Client, dataset string, f Filter) (*Table, error) {
+	items, err := client.FetchItems()
+	if err != nil && dataset == Items {
+		return nil, err
+	}
+
+	switch dataset {
+	case Items:
+		return ItemsTable(items), nil
+	case Locations:
+		locations, err := client.FetchLocations()
+		if err != nil {
+			return nil, err
+		}
+		return LocationsTable(locations, f), nil
+	case Stock:
+		rows, err := client.FetchOverview()
+		if err != nil {
+			return nil, err
+		}
+		return StockTable(rows, items, f), nil
+	case Commits:
+		commits, err := client.FetchCommits(f.From, f.To, f.Location)
+		if err != nil {
+			return nil, err
+		}
+		return CommitsTable(commits, items), nil
+	}
+	return nil, fmt.Errorf("unknown dataset %q", dataset)
+}
+
+func ItemsTable(items []api.Item) *Table {
+	t := &Table{Name: Items, Header: []string{"id", "name", "base_unit", "min_qty", "max_qty", "barcodes"}}
+	sorted := append([]api.Item{}, items...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	for _, item := range sorted {
+		t.Rows = append(t.Rows, []interface{}{item.ID, item.Name, item.Base(), item.MinQty, item.MaxQty, strings.Join(item.Barcodes, ",")})
+	}
+	return t
+}
+
+func LocationsTable(locations []api.Location, f Filter) *Table {
+	t := &Table{Name: Locations, Header: []string{"location", "items", "capacity"}}
+	sorted := append([]api.Location{}, locations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LocationName < sorted[j].LocationName })
+	for _, loc := range sorted {
+		if f.location(loc.LocationName) {
+			t.Rows = append(t.Rows, []interface{}{loc.LocationName, api.ItemList(loc.Items), loc.Capacity})
+		}
+	}
+	return t
+}
+
+func StockTable(rows []api.StockLevel, items []api.Item, f Filter) *Table {
+	names := itemNames(items)
+	t := &Table{Name: Stock, Header: []string{"location", "item_id", "item", "qty"}}
+	sorted := append([]api.StockLevel{}, rows...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Location != sorted[j].Location {
+			return sorted[i].Location < sorted[j].Location
+		}
+		return sorted[i].ItemID < sorted[j].ItemID
+	})
+	for _, row := range sorted {
+		if f.location(row.Location) {
+			t.Rows = append(t.Rows, []interface{}{row.Location, row.ItemID, names[row.ItemID], row.Qty})
+		}
+	}
+	return t
+}
+
+// CommitsTable lists commit history. The API applies the filter.
+func CommitsTable(commits []api.CommitRecord, items []api.Item) *Table {
+	names := itemNames(items)
+	t := &Table{Name: Commits, Header: []string{"id", "created_at", "location", "item_id", "item", "delta", "uom", "uom_qty", "reference", "device_id"}}
+	for _, c := range commits {
+		t.Rows = append(t.Rows, []interface{}{c.ID, c.CreatedAt.Time, c.Location, c.ItemID, names[c.ItemID], c.Delta, c.UoM, c.UoMQty, c.Reference, c.DeviceID})
+	}
+	return t
+}
+
+func itemNames(items []api.Item) map[int]string {
+	names := make(map[int]string, len(items))
+	for _, item := range items {
+		names[item.ID] = item.Name
+	}
+	return names
+}
@@ -0,0 +1,111 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Write writes the tables in a format. CSV and JSON Lines take one table;
+// XLSX writes one sheet per table.
+func Write(w io.Writer, format string, tables ...*Table) error {
+	switch format {
+	case CSV, JSONL:
+		if len(tables) != 1 {
+			return fmt.Errorf("%s export takes one dataset, got %d", format, len(tables))
+		}
+		if format == CSV {
+			return writeCSV(w, tables[0])
+		}
+		return writeJSONL(w, tables[0])
+	case XLSX:
+		return writeXLSX(w, tables)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// WriteFile writes the tables to a file.
+func WriteFile(path, format string, tables ...*Table) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(file, format, tables...); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func cellText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+func writeCSV(w io.Writer, t *Table) error {
+	writer := csv.NewWriter(w)
+	writer.Write(t.Header)
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = cellText(v)
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeJSONL writes one JSON object per row, keyed by the header. Objects
+// are built by hand to keep the header's column order.
+func writeJSONL(w io.Writer, t *Table) error {
+	bw := bufio.NewWriter(w)
+	for _, row := range t.Rows {
+		bw.WriteByte('{')
+		for i, v := range row {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			if tm, ok := v.(time.Time); ok {
+				v = cellText(tm)
+			}
+			key, _ := marshal(t.Header[i])
+			value, err := marshal(v)
+			if err != nil {
+				return err
+			}
+			bw.Write(key)
+			bw.WriteByte(':')
+			bw.Write(value)
+		}
+		bw.WriteString("}\n")
+	}
+	return bw.Flush()
+}
+
+// marshal encodes v without escaping <, > and &, which are common in names.
+func marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
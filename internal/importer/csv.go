@@ -0,0 +1,215 @@
+// Package importer bulk-loads items, locations and opening stock from CSV
+// files, validating them and showing what would change before writing.
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/larkin1/wmsproject/internal/api"
+)
+
+// Files an import can contain, used to label problems and changes.
+const (
+	ItemsFile     = "items"
+	LocationsFile = "locations"
+	StockFile     = "stock"
+)
+
+// Problem is a row that can't be imported.
+type Problem struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (p Problem) Error() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.File, p.Msg)
+	}
+	return fmt.Sprintf("%s line %d: %s", p.File, p.Line, p.Msg)
+}
+
+// Row is a parsed value and the CSV line it came from.
+type Row[T any] struct {
+	Line  int
+	Value T
+}
+
+// Input is the parsed content of the import files.
+type Input struct {
+	Items     []Row[api.Item]
+	Locations []Row[api.Location]
+	Stock     []Row[api.StockLevel]
+	Problems  []Problem
+
+	// columns present in each file, so missing ones aren't treated as cleared
+	columns map[string]map[string]bool
+}
+
+func (in *Input) problem(file string, line int, format string, args ...interface{}) {
+	in.Problems = append(in.Problems, Problem{File: file, Line: line, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (in *Input) has(file, column string) bool {
+	return in.columns[file][column]
+}
+
+// readCSV reads a file with a header row and calls fn for each record with
+// its values by lower-cased column name. required columns must be present.
+func (in *Input) readCSV(file string, r io.Reader, required []string, fn func(line int, get func(string) string)) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		in.problem(file, 1, "cannot read header: %v", err)
+		return
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if in.columns == nil {
+		in.columns = make(map[string]map[string]bool)
+	}
+	in.columns[file] = make(map[string]bool)
+	for name := range index {
+		in.columns[file][name] = true
+	}
+	for _, name := range required {
+		if _, ok := index[name]; !ok {
+			in.problem(file, 1, "missing %q column", name)
+			return
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			in.problem(file, line, "%v", err)
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue // blank line
+		}
+
+		fn(line, func(column string) string {
+			i, ok := index[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		})
+	}
+}
+
+// ReadItems reads an items file: id and name, plus optional base_unit,
+// min_qty, max_qty and barcodes columns.
+func (in *Input) ReadItems(r io.Reader) {
+	in.readCSV(ItemsFile, r, []string{"id", "name"}, func(line int, get func(string) string) {
+		id, err := strconv.Atoi(get("id"))
+		if err != nil || id <= 0 {
+			in.problem(ItemsFile, line, "invalid item ID %q", get("id"))
+			return
+		}
+		item := api.Item{ID: id, Name: get("name"), BaseUnit: get("base_unit")}
+		if item.Name == "" {
+			in.problem(ItemsFile, line, "item %d has no name", id)
+			return
+		}
+		if item.MinQty, err = optionalInt(get("min_qty")); err != nil {
+			in.problem(ItemsFile, line, "invalid min_qty %q", get("min_qty"))
+			return
+		}
+		if item.MaxQty, err = optionalInt(get("max_qty")); err != nil {
+			in.problem(ItemsFile, line, "invalid max_qty %q", get("max_qty"))
+			return
+		}
+		item.Barcodes = splitList(get("barcodes"))
+		in.Items = append(in.Items, Row[api.Item]{Line: line, Value: item})
+	})
+}
+
+// ReadLocations reads a locations file: location and items, plus an
+// optional capacity column.
+func (in *Input) ReadLocations(r io.Reader) {
+	in.readCSV(LocationsFile, r, []string{"location", "items"}, func(line int, get func(string) string) {
+		loc := api.Location{LocationName: get("location")}
+		if loc.LocationName == "" {
+			in.problem(LocationsFile, line, "empty location code")
+			return
+		}
+		items, err := ParseItemList(get("items"))
+		if err != nil {
+			in.problem(LocationsFile, line, "location %s: %v", loc.LocationName, err)
+			return
+		}
+		loc.Items = items
+		if loc.Capacity, err = optionalInt(get("capacity")); err != nil || loc.Capacity < 0 {
+			in.problem(LocationsFile, line, "invalid capacity %q", get("capacity"))
+			return
+		}
+		in.Locations = append(in.Locations, Row[api.Location]{Line: line, Value: loc})
+	})
+}
+
+// ReadStock reads an opening stock file: location, item_id and qty.
+func (in *Input) ReadStock(r io.Reader) {
+	in.readCSV(StockFile, r, []string{"location", "item_id", "qty"}, func(line int, get func(string) string) {
+		level := api.StockLevel{Location: get("location")}
+		if level.Location == "" {
+			in.problem(StockFile, line, "empty location code")
+			return
+		}
+		var err error
+		if level.ItemID, err = strconv.Atoi(get("item_id")); err != nil || level.ItemID <= 0 {
+			in.problem(StockFile, line, "invalid item ID %q", get("item_id"))
+			return
+		}
+		if level.Qty, err = strconv.Atoi(get("qty")); err != nil || level.Qty < 0 {
+			in.problem(StockFile, line, "invalid quantity %q", get("qty"))
+			return
+		}
+		in.Stock = append(in.Stock, Row[api.StockLevel]{Line: line, Value: level})
+	})
+}
+
+// ParseItemList parses a location's item IDs. It accepts "1,2,3", "1 2 3",
+// "[1, 2, 3]" and the "[1 2 3]" form written by older exports.
+func ParseItemList(s string) ([]int, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+
+	ids := []int{}
+	for _, field := range splitList(s) {
+		id, err := strconv.Atoi(field)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("malformed item list %q", s)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// splitList splits on commas, semicolons and whitespace.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t'
+	})
+}
+
+func optionalInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
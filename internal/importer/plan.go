@@ -0,0 +1,355 @@
+package importer
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/larkin1/wmsproject/internal/api"
+)
+
+// BatchSize is how many rows are written per request.
+const BatchSize = 500
+
+// Reference is recorded on the commits that set opening stock.
+const Reference = "opening-stock"
+
+// Actions in a Change.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionAdjust = "adjust"
+)
+
+// Current is the data already in the database, to diff the import against.
+type Current struct {
+	Items     []api.Item
+	Locations []api.Location
+	Stock     []api.StockLevel
+}
+
+// Change is one row the import would write.
+type Change struct {
+	File   string
+	Action string
+	Key    string
+	Detail string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%-9s %-6s %s  %s", c.File, c.Action, c.Key, c.Detail)
+}
+
+// Plan is a validated import: what would be written and why.
+type Plan struct {
+	Items       []api.Item
+	Locations   []api.Location
+	Adjustments []api.CommitPayload // commits bringing stock to the opening quantities
+
+	// Optional columns present in the files, written even when empty so
+	// that emptying a value in the file clears it
+	ItemColumns     []string
+	LocationColumns []string
+
+	Changes   []Change
+	Unchanged int
+	Problems  []Problem
+}
+
+// OK reports whether the import can be applied.
+func (p *Plan) OK() bool {
+	return len(p.Problems) == 0
+}
+
+// Empty reports whether the import would change nothing.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// NewPlan validates the input against itself and the current data and
+// works out the changes. Rows equal to the current data are skipped.
+func NewPlan(in *Input, cur Current) *Plan {
+	p := &Plan{Problems: append([]Problem{}, in.Problems...)}
+	for _, column := range []string{"base_unit", "min_qty", "max_qty", "barcodes"} {
+		if in.has(ItemsFile, column) {
+			p.ItemColumns = append(p.ItemColumns, column)
+		}
+	}
+	if in.has(LocationsFile, "capacity") {
+		p.LocationColumns = append(p.LocationColumns, "capacity")
+	}
+	problem := func(file string, line int, format string, args ...interface{}) {
+		p.Problems = append(p.Problems, Problem{File: file, Line: line, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	existingItems := make(map[int]api.Item)
+	existingNames := make(map[string]int)
+	for _, item := range cur.Items {
+		existingItems[item.ID] = item
+		existingNames[strings.ToLower(item.Name)] = item.ID
+	}
+
+	// Items: unique IDs and names, within the file and against other items
+	knownItems := make(map[int]bool)
+	for id := range existingItems {
+		knownItems[id] = true
+	}
+	seenIDs := make(map[int]int)
+	seenNames := make(map[string]int)
+	renamed := make(map[int]bool)
+	for _, row := range in.Items {
+		renamed[row.Value.ID] = true
+	}
+	for _, row := range in.Items {
+		item := row.Value
+		name := strings.ToLower(item.Name)
+		if line, ok := seenIDs[item.ID]; ok {
+			problem(ItemsFile, row.Line, "duplicate item ID %d (first on line %d)", item.ID, line)
+			continue
+		}
+		if line, ok := seenNames[name]; ok {
+			problem(ItemsFile, row.Line, "duplicate item name %q (first on line %d)", item.Name, line)
+			continue
+		}
+		if other, ok := existingNames[name]; ok && other != item.ID && !renamed[other] {
+			problem(ItemsFile, row.Line, "name %q is already used by item %d", item.Name, other)
+			continue
+		}
+		seenIDs[item.ID] = row.Line
+		seenNames[name] = row.Line
+		knownItems[item.ID] = true
+
+		old, exists := existingItems[item.ID]
+		switch {
+		case !exists:
+			p.Items = append(p.Items, item)
+			p.Changes = append(p.Changes, Change{ItemsFile, ActionCreate, fmt.Sprint(item.ID), item.Name})
+		case itemDiff(in, old, item) != "":
+			p.Items = append(p.Items, item)
+			p.Changes = append(p.Changes, Change{ItemsFile, ActionUpdate, fmt.Sprint(item.ID), itemDiff(in, old, item)})
+		default:
+			p.Unchanged++
+		}
+	}
+
+	// Locations: unique codes, known items
+	existingLocs := make(map[string]api.Location)
+	knownLocs := make(map[string]bool)
+	for _, loc := range cur.Locations {
+		existingLocs[loc.LocationName] = loc
+		knownLocs[loc.LocationName] = true
+	}
+	seenLocs := make(map[string]int)
+	for _, row := range in.Locations {
+		loc := row.Value
+		if line, ok := seenLocs[loc.LocationName]; ok {
+			problem(LocationsFile, row.Line, "duplicate location %s (first on line %d)", loc.LocationName, line)
+			continue
+		}
+		seenLocs[loc.LocationName] = row.Line
+
+		var unknown []string
+		for _, id := range loc.Items {
+			if !knownItems[id] {
+				unknown = append(unknown, fmt.Sprint(id))
+			}
+		}
+		if len(unknown) > 0 {
+			problem(LocationsFile, row.Line, "location %s has unknown item IDs %s", loc.LocationName, strings.Join(unknown, ", "))
+			continue
+		}
+		knownLocs[loc.LocationName] = true
+
+		old, exists := existingLocs[loc.LocationName]
+		switch {
+		case !exists:
+			p.Locations = append(p.Locations, loc)
+			p.Changes = append(p.Changes, Change{LocationsFile, ActionCreate, loc.LocationName, fmt.Sprintf("items %v", loc.Items)})
+		case locationDiff(in, old, loc) != "":
+			p.Locations = append(p.Locations, loc)
+			p.Changes = append(p.Changes, Change{LocationsFile, ActionUpdate, loc.LocationName, locationDiff(in, old, loc)})
+		default:
+			p.Unchanged++
+		}
+	}
+
+	// Opening stock: one adjustment per location and item to reach the target
+	onHand := make(map[string]map[int]int)
+	for _, level := range cur.Stock {
+		if onHand[level.Location] == nil {
+			onHand[level.Location] = make(map[int]int)
+		}
+		onHand[level.Location][level.ItemID] += level.Qty
+	}
+	type key struct {
+		location string
+		itemID   int
+	}
+	seenStock := make(map[key]int)
+	for _, row := range in.Stock {
+		level := row.Value
+		k := key{level.Location, level.ItemID}
+		if line, ok := seenStock[k]; ok {
+			problem(StockFile, row.Line, "duplicate stock for item %d at %s (first on line %d)", level.ItemID, level.Location, line)
+			continue
+		}
+		seenStock[k] = row.Line
+		if !knownItems[level.ItemID] {
+			problem(StockFile, row.Line, "unknown item ID %d", level.ItemID)
+			continue
+		}
+		if !knownLocs[level.Location] {
+			problem(StockFile, row.Line, "unknown location %s", level.Location)
+			continue
+		}
+
+		current := onHand[level.Location][level.ItemID]
+		delta := level.Qty - current
+		if delta == 0 {
+			p.Unchanged++
+			continue
+		}
+		p.Adjustments = append(p.Adjustments, api.CommitPayload{
+			Location:  level.Location,
+			ItemID:    level.ItemID,
+			Delta:     delta,
+			Reference: Reference,
+		})
+		p.Changes = append(p.Changes, Change{StockFile, ActionAdjust, fmt.Sprintf("%s item %d", level.Location, level.ItemID),
+			fmt.Sprintf("%d -> %d (%+d)", current, level.Qty, delta)})
+	}
+
+	sort.SliceStable(p.Problems, func(i, j int) bool {
+		if p.Problems[i].File != p.Problems[j].File {
+			return fileOrder(p.Problems[i].File) < fileOrder(p.Problems[j].File)
+		}
+		return p.Problems[i].Line < p.Problems[j].Line
+	})
+	return p
+}
+
+func fileOrder(file string) int {
+	switch file {
+	case ItemsFile:
+		return 0
+	case LocationsFile:
+		return 1
+	}
+	return 2
+}
+
+// itemDiff describes how an imported item differs from the stored one,
+// considering only the columns present in the file.
+func itemDiff(in *Input, old, item api.Item) string {
+	var diffs []string
+	if old.Name != item.Name {
+		diffs = append(diffs, fmt.Sprintf("name %q -> %q", old.Name, item.Name))
+	}
+	if in.has(ItemsFile, "base_unit") && old.Base() != item.Base() {
+		diffs = append(diffs, fmt.Sprintf("unit %s -> %s", old.Base(), item.Base()))
+	}
+	if in.has(ItemsFile, "min_qty") && old.MinQty != item.MinQty {
+		diffs = append(diffs, fmt.Sprintf("min %d -> %d", old.MinQty, item.MinQty))
+	}
+	if in.has(ItemsFile, "max_qty") && old.MaxQty != item.MaxQty {
+		diffs = append(diffs, fmt.Sprintf("max %d -> %d", old.MaxQty, item.MaxQty))
+	}
+	if in.has(ItemsFile, "barcodes") && !slices.Equal(old.Barcodes, item.Barcodes) {
+		diffs = append(diffs, fmt.Sprintf("barcodes %v -> %v", old.Barcodes, item.Barcodes))
+	}
+	return strings.Join(diffs, ", ")
+}
+
+func locationDiff(in *Input, old, loc api.Location) string {
+	var diffs []string
+	a, b := slices.Clone(old.Items), slices.Clone(loc.Items)
+	slices.Sort(a)
+	slices.Sort(b)
+	if !slices.Equal(a, b) {
+		diffs = append(diffs, fmt.Sprintf("items %v -> %v", old.Items, loc.Items))
+	}
+	if in.has(LocationsFile, "capacity") && old.Capacity != loc.Capacity {
+		diffs = append(diffs, fmt.Sprintf("capacity %d -> %d", old.Capacity, loc.Capacity))
+	}
+	return strings.Join(diffs, ", ")
+}
+
+// Apply writes the plan in batches: items, then locations, then stock
+// adjustments, so rows only refer to ones already written. progress, if not
+// nil, is called after each batch with the rows written so far.
+func (p *Plan) Apply(client *api.Client, deviceID string, progress func(done, total int)) error {
+	if !p.OK() {
+		return fmt.Errorf("import has %d problems", len(p.Problems))
+	}
+
+	total := len(p.Items) + len(p.Locations) + len(p.Adjustments)
+	done := 0
+	report := func(n int) {
+		done += n
+		log.Printf("[Import] Written %d/%d rows\n", done, total)
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+
+	for _, batch := range batches(p.Items) {
+		if err := client.UpsertItems(batch, p.ItemColumns...); err != nil {
+			return fmt.Errorf("writing items: %w", err)
+		}
+		report(len(batch))
+	}
+	for _, batch := range batches(p.Locations) {
+		if err := client.UpsertLocations(batch, p.LocationColumns...); err != nil {
+			return fmt.Errorf("writing locations: %w", err)
+		}
+		report(len(batch))
+	}
+
+	commits := make([]api.CommitPayload, len(p.Adjustments))
+	for i, c := range p.Adjustments {
+		c.DeviceID = deviceID
+		commits[i] = c
+	}
+	for _, batch := range batches(commits) {
+		if err := client.PostCommits(batch); err != nil {
+			return fmt.Errorf("writing opening stock: %w", err)
+		}
+		report(len(batch))
+	}
+	return nil
+}
+
+func batches[T any](rows []T) [][]T {
+	var out [][]T
+	for len(rows) > 0 {
+		n := min(BatchSize, len(rows))
+		out = append(out, rows[:n])
+		rows = rows[n:]
+	}
+	return out
+}
+
+// FetchCurrent downloads the data to diff against. It fails when offline,
+// since a diff against cached data could undo newer changes.
+func FetchCurrent(client *api.Client) (Current, error) {
+	var cur Current
+	var err error
+	if cur.Items, err = client.FetchItems(); err != nil {
+		return cur, err
+	}
+	if cur.Locations, err = client.FetchLocations(); err != nil {
+		return cur, err
+	}
+	if cur.Stock, err = client.FetchOverview(); err != nil {
+		return cur, err
+	}
+
+	for _, table := range []string{"items", "locations", "overview"} {
+		if client.Freshness(table).Offline() {
+			return cur, fmt.Errorf("cannot import while offline (%s from cache)", table)
+		}
+	}
+	return cur, nil
+}
@@ -0,0 +1,224 @@
+// Package label renders location and item labels with Code 128 or QR
+// barcodes, as ZPL for Zebra printers or as PDF sheets for office printers.
+package label
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/barcode"
+	"github.com/larkin1/wmsproject/internal/catalog"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/pdf"
+)
+
+// Barcode symbologies a template can use.
+const (
+	Code128Barcode = "code128"
+	QRBarcode      = "qr"
+)
+
+// Templates are the built-in label layouts.
+var Templates = []config.LabelTemplate{
+	{Name: "location", Width: 100, Height: 50, Barcode: Code128Barcode},
+	{Name: "location-qr", Width: 60, Height: 40, Barcode: QRBarcode},
+	{Name: "item", Width: 60, Height: 30, Barcode: Code128Barcode},
+	{Name: "item-qr", Width: 50, Height: 25, Barcode: QRBarcode},
+}
+
+// AllTemplates returns the custom templates followed by the built-in ones
+// they don't replace.
+func AllTemplates(custom []config.LabelTemplate) []config.LabelTemplate {
+	all := append([]config.LabelTemplate{}, custom...)
+	for _, t := range Templates {
+		replaced := false
+		for _, c := range custom {
+			replaced = replaced || c.Name == t.Name
+		}
+		if !replaced {
+			all = append(all, t)
+		}
+	}
+	return all
+}
+
+// FindTemplate looks a template up by name.
+func FindTemplate(custom []config.LabelTemplate, name string) (config.LabelTemplate, error) {
+	for _, t := range AllTemplates(custom) {
+		if t.Name == name {
+			return t, validate(t)
+		}
+	}
+	return config.LabelTemplate{}, fmt.Errorf("unknown label template %q", name)
+}
+
+func validate(t config.LabelTemplate) error {
+	if t.Width <= 0 || t.Height <= 0 {
+		return fmt.Errorf("label template %q needs a width and height", t.Name)
+	}
+	if t.Barcode != Code128Barcode && t.Barcode != QRBarcode {
+		return fmt.Errorf("label template %q has unknown barcode %q (use %s or %s)", t.Name, t.Barcode, Code128Barcode, QRBarcode)
+	}
+	return nil
+}
+
+// Label is one label: Name is printed in bold and Code is encoded in the
+// barcode and printed under it, unless it is the same as the name.
+type Label struct {
+	Name string
+	Code string
+}
+
+// What labels can be made for.
+const (
+	Locations = "locations"
+	Items     = "items"
+)
+
+// Kinds lists what labels can be made for, in menu order.
+var Kinds = []string{Locations, Items}
+
+// Fetch makes labels for locations whose code starts with filter, or for
+// items matching filter as a search, best match first. An empty filter
+// selects everything.
+func Fetch(client *api.Client, kind, filter string) ([]Label, error) {
+	switch kind {
+	case Locations:
+		all, err := client.FetchLocations()
+		if err != nil {
+			return nil, err
+		}
+		var locations []api.Location
+		for _, loc := range all {
+			if strings.HasPrefix(loc.LocationName, filter) {
+				locations = append(locations, loc)
+			}
+		}
+		sort.Slice(locations, func(i, j int) bool {
+			return locations[i].LocationName < locations[j].LocationName
+		})
+		return ForLocations(locations), nil
+
+	case Items:
+		all, err := client.FetchItems()
+		if err != nil {
+			return nil, err
+		}
+		cat := catalog.New()
+		cat.SetItems(all)
+		var items []api.Item
+		for _, m := range cat.Search(filter, 0) {
+			items = append(items, m.Item)
+		}
+		return ForItems(items), nil
+	}
+	return nil, fmt.Errorf("unknown label kind %q", kind)
+}
+
+// ForLocations makes a label per location. The name is the code itself,
+// which is what CommitUI's scanner field expects, so it is printed once.
+func ForLocations(locations []api.Location) []Label {
+	labels := make([]Label, 0, len(locations))
+	for _, loc := range locations {
+		labels = append(labels, Label{Name: loc.LocationName, Code: loc.LocationName})
+	}
+	return labels
+}
+
+// ForItems makes a label per item.
+func ForItems(items []api.Item) []Label {
+	labels := make([]Label, 0, len(items))
+	for _, item := range items {
+		labels = append(labels, Label{Name: item.Name, Code: ItemCode(item)})
+	}
+	return labels
+}
+
+// ItemCode is what an item label encodes: its first barcode, or its ID
+// if it has none.
+func ItemCode(item api.Item) string {
+	if len(item.Barcodes) > 0 {
+		return item.Barcodes[0]
+	}
+	return strconv.Itoa(item.ID)
+}
+
+// box is an area of a label in millimetres from its top left corner.
+type box struct {
+	x, y, w, h float64
+}
+
+// layout places a template's parts. A zero height leaves a part out.
+type layout struct {
+	name    box
+	barcode box
+	code    box
+	center  bool // centre the text, under a linear barcode
+}
+
+func layoutFor(t config.LabelTemplate) layout {
+	m := math.Min(t.Width, t.Height) * 0.08
+
+	if t.Barcode == QRBarcode {
+		// Square code on the left, text to its right
+		side := t.Height - 2*m
+		text := box{x: side + 2*m, y: m, w: t.Width - side - 3*m}
+		l := layout{barcode: box{m, m, side, side}}
+		if text.w <= 0 {
+			return l
+		}
+		if !t.HideName {
+			l.name = box{text.x, text.y, text.w, side * 0.3}
+			text.y += side*0.3 + m/2
+		}
+		if !t.HideCode {
+			l.code = box{text.x, text.y, text.w, side * 0.2}
+		}
+		return l
+	}
+
+	l := layout{center: true}
+	top, bottom := m, t.Height-m
+	if !t.HideName {
+		l.name = box{m, top, t.Width - 2*m, (t.Height - 2*m) * 0.25}
+		top += l.name.h + m/2
+	}
+	if !t.HideCode {
+		l.code = box{m, 0, t.Width - 2*m, (t.Height - 2*m) * 0.16}
+		l.code.y = bottom - l.code.h
+		bottom = l.code.y - m/2
+	}
+	l.barcode = box{m, top, t.Width - 2*m, bottom - top}
+	return l
+}
+
+// minTextHeight is the smallest text, in millimetres, fitText shrinks to.
+const minTextHeight = 2.0
+
+// fitText returns the text height in millimetres at which s fits across
+// the area, at most the area's height.
+func fitText(area box, font pdf.Font, s string) float64 {
+	h := area.h * 0.9
+	// Widths scale with size, so millimetres work as well as points
+	if w := pdf.TextWidth(font, h, s); w > area.w {
+		h *= area.w / w
+	}
+	return math.Max(h, minTextHeight)
+}
+
+// symbol encodes a label's code for the template's barcode: one row of
+// modules for Code 128, a square of them for QR.
+func symbol(t config.LabelTemplate, code string) ([][]bool, error) {
+	if t.Barcode == QRBarcode {
+		return barcode.QR(code)
+	}
+	modules, err := barcode.Code128(code)
+	if err != nil {
+		return nil, err
+	}
+	return [][]bool{modules}, nil
+}
@@ -0,0 +1,115 @@
+package label
+
+import (
+	"fmt"
+
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/pdf"
+)
+
+// Sheet layout in millimetres.
+const (
+	sheetMargin = 10.0
+	sheetGap    = 2.0
+)
+
+// PDF lays labels out in a grid on A4 pages with light cut lines, for
+// printing on plain or sticker sheets.
+func PDF(labels []Label, t config.LabelTemplate) (*pdf.Document, error) {
+	if err := validate(t); err != nil {
+		return nil, err
+	}
+
+	pageW, pageH := pdf.A4Width, pdf.A4Height
+	usableW := pageW - 2*pdf.Mm(sheetMargin)
+	usableH := pageH - 2*pdf.Mm(sheetMargin)
+	w, h, gap := pdf.Mm(t.Width), pdf.Mm(t.Height), pdf.Mm(sheetGap)
+	cols := int((usableW + gap) / (w + gap))
+	rows := int((usableH + gap) / (h + gap))
+	if cols == 0 || rows == 0 {
+		return nil, fmt.Errorf("label template %s is larger than the page", t.Name)
+	}
+
+	// Centre the grid on the page
+	left := (pageW - float64(cols)*w - float64(cols-1)*gap) / 2
+	top := (pageH - float64(rows)*h - float64(rows-1)*gap) / 2
+
+	doc := pdf.New(pageW, pageH)
+	doc.Title = "Labels"
+	l := layoutFor(t)
+	var page *pdf.Page
+	for i, label := range labels {
+		slot := i % (cols * rows)
+		if slot == 0 {
+			page = doc.AddPage()
+		}
+		x := left + float64(slot%cols)*(w+gap)
+		y := top + float64(slot/cols)*(h+gap)
+
+		page.SetGray(0.8)
+		page.StrokeRect(x, y, w, h, 0.25)
+		page.SetGray(0)
+		if err := drawLabel(page, x, y, t, l, label); err != nil {
+			return nil, err
+		}
+	}
+	if len(labels) == 0 {
+		doc.AddPage()
+	}
+	return doc, nil
+}
+
+// drawLabel draws one label with its top left corner at x, y in points.
+func drawLabel(page *pdf.Page, x, y float64, t config.LabelTemplate, l layout, label Label) error {
+	sym, err := symbol(t, label.Code)
+	if err != nil {
+		return fmt.Errorf("label %s: %w", label.Code, err)
+	}
+
+	text := func(area box, font pdf.Font, s string) {
+		if area.h == 0 {
+			return
+		}
+		h := fitText(area, font, s)
+		size := pdf.Mm(h)
+		width := pdf.Mm(area.w)
+		s = pdf.Fit(font, size, s, width)
+		baseline := y + pdf.Mm(area.y+(area.h+h*0.7)/2)
+		if l.center {
+			page.TextCenter(x+pdf.Mm(area.x)+width/2, baseline, font, size, s)
+		} else {
+			page.Text(x+pdf.Mm(area.x), baseline, font, size, s)
+		}
+	}
+	text(l.name, pdf.HelveticaBold, label.Name)
+	if label.Code != label.Name {
+		text(l.code, pdf.Helvetica, label.Code)
+	}
+
+	// Modules narrower than this don't scan reliably
+	area := l.barcode
+	module := pdf.Mm(area.w) / float64(len(sym[0]))
+	if module < pdf.Mm(0.19) {
+		return fmt.Errorf("label %s: code too long for template %s", label.Code, t.Name)
+	}
+	height := pdf.Mm(area.h)
+	if t.Barcode == QRBarcode {
+		height = module
+	}
+
+	// Dark runs of each row become one rectangle
+	for r, row := range sym {
+		for c := 0; c < len(row); {
+			if !row[c] {
+				c++
+				continue
+			}
+			start := c
+			for c < len(row) && row[c] {
+				c++
+			}
+			page.Rect(x+pdf.Mm(area.x)+float64(start)*module, y+pdf.Mm(area.y)+float64(r)*height, float64(c-start)*module, height)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package label
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/pdf"
+)
+
+// DefaultDPI is the resolution of most Zebra desktop printers.
+const DefaultDPI = 203
+
+// ZPL renders labels for a printer of the given resolution. Barcodes use
+// the printer's own Code 128 and QR commands so they print sharply.
+func ZPL(labels []Label, t config.LabelTemplate, dpi int) ([]byte, error) {
+	if err := validate(t); err != nil {
+		return nil, err
+	}
+	if dpi <= 0 {
+		dpi = DefaultDPI
+	}
+	dots := func(mm float64) int {
+		return int(mm*float64(dpi)/25.4 + 0.5)
+	}
+	l := layoutFor(t)
+
+	var b bytes.Buffer
+	for _, label := range labels {
+		sym, err := symbol(t, label.Code)
+		if err != nil {
+			return nil, fmt.Errorf("label %s: %w", label.Code, err)
+		}
+
+		b.WriteString("^XA\n^CI28\n")
+		fmt.Fprintf(&b, "^PW%d\n^LL%d\n^LH0,0\n", dots(t.Width), dots(t.Height))
+
+		align := "L"
+		if l.center {
+			align = "C"
+		}
+		text := func(area box, font pdf.Font, s string) {
+			if area.h == 0 {
+				return
+			}
+			// The scalable font has no bold, so the name is set wider
+			mm := fitText(area, font, s)
+			h, w := dots(mm), dots(mm)
+			if font == pdf.HelveticaBold {
+				w = w * 11 / 10
+			}
+			fmt.Fprintf(&b, "^FO%d,%d^A0N,%d,%d^FB%d,1,0,%s^FH\\^FD%s^FS\n",
+				dots(area.x), dots(area.y+(area.h-mm)/2), h, w, dots(area.w), align, zplEscape(s))
+		}
+		text(l.name, pdf.HelveticaBold, label.Name)
+
+		area := l.barcode
+		if t.Barcode == QRBarcode {
+			mag := dots(area.w) / len(sym)
+			if mag < 1 {
+				return nil, fmt.Errorf("label %s: code too long for template %s", label.Code, t.Name)
+			}
+			fmt.Fprintf(&b, "^FO%d,%d^BQN,2,%d^FH\\^FDMA,%s^FS\n", dots(area.x), dots(area.y), min(mag, 10), zplEscape(label.Code))
+		} else {
+			modules := len(sym[0])
+			width := dots(area.w) / modules
+			if width < 1 {
+				return nil, fmt.Errorf("label %s: code too long for template %s", label.Code, t.Name)
+			}
+			x := dots(area.x) + (dots(area.w)-width*modules)/2
+			fmt.Fprintf(&b, "^BY%d,3^FO%d,%d^BCN,%d,N,N,N,A^FH\\^FD%s^FS\n",
+				min(width, 10), x, dots(area.y), dots(area.h), zplEscape(label.Code))
+		}
+
+		if label.Code != label.Name {
+			text(l.code, pdf.Helvetica, label.Code)
+		}
+		b.WriteString("^XZ\n")
+	}
+	return b.Bytes(), nil
+}
+
+// zplEscape hex-escapes the characters ZPL treats as commands, for use
+// after ^FH\.
+func zplEscape(s string) string {
+	return strings.NewReplacer(`\`, `\5C`, `^`, `\5E`, `~`, `\7E`).Replace(s)
+}
+
+// Send writes ZPL to a printer's raw port. addr is "host" or "host:port";
+// the port defaults to 9100.
+func Send(addr string, zpl []byte) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "9100")
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	if _, err := conn.Write(zpl); err != nil {
+		conn.Close()
+		return err
+	}
+	return conn.Close()
+}
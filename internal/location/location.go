@@ -0,0 +1,172 @@
+package location
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Levels are the hierarchy levels a location code can contain, outermost first.
+var Levels = []string{"site", "zone", "aisle", "rack", "bin"}
+
+// DefaultPattern matches codes like "A-01-02-03" (zone-aisle-rack-bin).
+const DefaultPattern = "{zone}-{aisle}-{rack}-{bin}"
+
+// Parser splits location codes into hierarchy levels.
+//
+// The pattern is either a template where each {level} placeholder matches
+// one segment, e.g. "{site}/{zone}-{aisle}", or a regular expression with
+// named groups, e.g. `^(?P<zone>[A-Z])(?P<aisle>\d{2})$`.
+type Parser struct {
+	pattern string
+	re      *regexp.Regexp
+	levels  []string
+}
+
+// Path is a parsed location code.
+type Path struct {
+	Code  string
+	Parts map[string]string
+}
+
+func NewParser(pattern string) (*Parser, error) {
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+
+	expr := pattern
+	if !strings.Contains(pattern, "(?P<") {
+		expr = templateToRegexp(pattern)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid location pattern %q: %w", pattern, err)
+	}
+
+	p := &Parser{pattern: pattern, re: re}
+	for _, level := range Levels {
+		if re.SubexpIndex(level) >= 0 {
+			p.levels = append(p.levels, level)
+		}
+	}
+	if len(p.levels) == 0 {
+		return nil, fmt.Errorf("location pattern %q has no levels (use %s)", pattern, strings.Join(Levels, ", "))
+	}
+
+	return p, nil
+}
+
+// templateToRegexp turns "{zone}-{aisle}" into an anchored regular expression.
+func templateToRegexp(template string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for template != "" {
+		start := strings.Index(template, "{")
+		end := strings.Index(template, "}")
+		if start < 0 || end < start {
+			b.WriteString(regexp.QuoteMeta(template))
+			break
+		}
+		b.WriteString(regexp.QuoteMeta(template[:start]))
+		b.WriteString("(?P<" + template[start+1:end] + ">.+?)")
+		template = template[end+1:]
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// Levels returns the levels this parser's pattern defines, outermost first.
+func (p *Parser) Levels() []string {
+	return p.levels
+}
+
+// Parse splits code into its levels. Codes that don't match the pattern
+// are flat and have no parts.
+func (p *Parser) Parse(code string) Path {
+	path := Path{Code: code, Parts: make(map[string]string)}
+
+	match := p.re.FindStringSubmatch(code)
+	if match == nil {
+		return path
+	}
+
+	for _, level := range p.levels {
+		if part := match[p.re.SubexpIndex(level)]; part != "" {
+			path.Parts[level] = part
+		}
+	}
+	return path
+}
+
+// Flat reports whether the code didn't match the hierarchy pattern.
+func (p Path) Flat() bool {
+	return len(p.Parts) == 0
+}
+
+// Level returns the part for the given level, or "" if it isn't set.
+func (p Path) Level(level string) string {
+	return p.Parts[level]
+}
+
+// Segments returns the parts in hierarchy order. A flat code is a single segment.
+func (p Path) Segments() []string {
+	if p.Flat() {
+		return []string{p.Code}
+	}
+
+	var segments []string
+	for _, level := range p.levels() {
+		segments = append(segments, p.Parts[level])
+	}
+	return segments
+}
+
+// levels returns the levels set on the path, outermost first.
+func (p Path) levels() []string {
+	var levels []string
+	for _, level := range Levels {
+		if _, ok := p.Parts[level]; ok {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// Key groups the path at the given level, e.g. "A/01" for aisle 01 in zone A.
+// Flat codes are their own group at every level.
+func (p Path) Key(level string) string {
+	if p.Flat() {
+		return p.Code
+	}
+
+	var segments []string
+	for _, l := range Levels {
+		if part, ok := p.Parts[l]; ok {
+			segments = append(segments, part)
+		}
+		if l == level {
+			break
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Less orders location codes by their path so a walk visits zones, aisles
+// and racks in sequence. Numeric segments compare by value.
+func (p *Parser) Less(a, b string) bool {
+	sa, sb := p.Parse(a).Segments(), p.Parse(b).Segments()
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		if sa[i] == sb[i] {
+			continue
+		}
+		na, errA := strconv.Atoi(sa[i])
+		nb, errB := strconv.Atoi(sb[i])
+		if errA == nil && errB == nil && na != nb {
+			return na < nb
+		}
+		return sa[i] < sb[i]
+	}
+	return len(sa) < len(sb)
+}
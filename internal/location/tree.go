@@ -0,0 +1,124 @@
+package location
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/larkin1/wmsproject/internal/api"
+)
+
+// Node is one level of the location tree. Leaves carry the full location code.
+type Node struct {
+	ID       string
+	Name     string
+	Level    string
+	Code     string
+	Children []string
+}
+
+// Tree is a browsable hierarchy of location codes. The root node has ID "".
+type Tree struct {
+	nodes map[string]*Node
+}
+
+// BuildTree arranges codes into a tree using the parser's levels.
+func BuildTree(p *Parser, codes []string) *Tree {
+	t := &Tree{nodes: map[string]*Node{"": {}}}
+
+	for _, code := range codes {
+		path := p.Parse(code)
+		segments := path.Segments()
+		levels := path.levels()
+		if path.Flat() {
+			levels = []string{""}
+		}
+
+		parent := ""
+		for i, segment := range segments {
+			id := strings.Join(segments[:i+1], "/")
+			node, ok := t.nodes[id]
+			if !ok {
+				node = &Node{ID: id, Name: segment, Level: levels[i]}
+				t.nodes[id] = node
+				t.nodes[parent].Children = append(t.nodes[parent].Children, id)
+			}
+			if i == len(segments)-1 {
+				node.Code = code
+			}
+			parent = id
+		}
+	}
+
+	for _, node := range t.nodes {
+		sort.Strings(node.Children)
+	}
+	return t
+}
+
+// Node returns the node with the given ID, or nil.
+func (t *Tree) Node(id string) *Node {
+	return t.nodes[id]
+}
+
+// Children returns the child IDs of a node.
+func (t *Tree) Children(id string) []string {
+	if node, ok := t.nodes[id]; ok {
+		return node.Children
+	}
+	return nil
+}
+
+// IsBranch reports whether the node has children.
+func (t *Tree) IsBranch(id string) bool {
+	return len(t.Children(id)) > 0
+}
+
+// Codes returns every location code at or below the node.
+func (t *Tree) Codes(id string) []string {
+	node, ok := t.nodes[id]
+	if !ok {
+		return nil
+	}
+
+	var codes []string
+	if node.Code != "" {
+		codes = append(codes, node.Code)
+	}
+	for _, child := range node.Children {
+		codes = append(codes, t.Codes(child)...)
+	}
+	return codes
+}
+
+// Total is the quantity of one item within a group of locations.
+type Total struct {
+	Group  string
+	ItemID int
+	Qty    int
+}
+
+// Aggregate sums overview rows per item for each group at the given level.
+func Aggregate(p *Parser, rows []api.StockLevel, level string) []Total {
+	type key struct {
+		group  string
+		itemID int
+	}
+
+	sums := make(map[key]int)
+	for _, row := range rows {
+		k := key{p.Parse(row.Location).Key(level), row.ItemID}
+		sums[k] += row.Qty
+	}
+
+	totals := make([]Total, 0, len(sums))
+	for k, qty := range sums {
+		totals = append(totals, Total{Group: k.group, ItemID: k.itemID, Qty: qty})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Group != totals[j].Group {
+			return totals[i].Group < totals[j].Group
+		}
+		return totals[i].ItemID < totals[j].ItemID
+	})
+	return totals
+}
@@ -0,0 +1,102 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/queue"
+)
+
+// Default topics; {site} and {device} are replaced from settings.
+const (
+	DefaultCommitTopic = "wms/{device}/commits"
+	DefaultQueueTopic  = "wms/{device}/queue"
+)
+
+// Events publishes a queue's activity: each commit once synced, and the
+// pending count whenever it changes. Queue status is retained so new
+// subscribers see the current count straight away.
+type Events struct {
+	pub         *Publisher
+	commitTopic string
+	queueTopic  string
+	qos         byte
+	site        string
+	deviceID    string
+}
+
+// CommitMessage is published for each synced commit.
+type CommitMessage struct {
+	queue.Commit
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// QueueMessage is published when the number of pending commits changes.
+type QueueMessage struct {
+	DeviceID string    `json:"device_id"`
+	Site     string    `json:"site,omitempty"`
+	Pending  int       `json:"pending"`
+	Time     time.Time `json:"time"`
+}
+
+// BufferFile holds messages not yet delivered, in the site's data directory.
+const BufferFile = "mqtt_buffer.json"
+
+// Start connects to the broker in settings and publishes q's activity
+// until Stop. Undelivered messages are kept in dataPath.
+func Start(s *config.Settings, q *queue.Queue, dataPath string) (*Events, error) {
+	if s.MQTTQoS < 0 || s.MQTTQoS > 2 {
+		return nil, fmt.Errorf("mqtt_qos must be 0, 1 or 2, not %d", s.MQTTQoS)
+	}
+	expand := strings.NewReplacer("{site}", s.Site, "{device}", s.DeviceID).Replace
+	e := &Events{
+		commitTopic: expand(s.MQTTCommitTopic),
+		queueTopic:  expand(s.MQTTQueueTopic),
+		qos:         byte(s.MQTTQoS),
+		site:        s.Site,
+		deviceID:    s.DeviceID,
+	}
+	if s.MQTTCommitTopic == "" {
+		e.commitTopic = expand(DefaultCommitTopic)
+	}
+	if s.MQTTQueueTopic == "" {
+		e.queueTopic = expand(DefaultQueueTopic)
+	}
+
+	opts := Options{
+		Broker:   s.MQTTBroker,
+		ClientID: s.MQTTClientID,
+		Username: s.MQTTUsername,
+		Password: s.MQTTPassword,
+
+		BufferPath: filepath.Join(dataPath, BufferFile),
+	}
+	if opts.ClientID == "" {
+		opts.ClientID = "wms-" + s.DeviceID
+	}
+	e.pub = NewPublisher(opts)
+
+	q.OnSynced(e.commitSynced)
+	q.OnPendingChange(e.pendingChanged)
+	e.pub.Start()
+	e.pendingChanged(len(q.Pending()))
+	return e, nil
+}
+
+func (e *Events) Stop() {
+	e.pub.Stop()
+}
+
+func (e *Events) commitSynced(commit queue.Commit) {
+	payload, _ := json.Marshal(CommitMessage{Commit: commit, SyncedAt: time.Now().UTC()})
+	e.pub.Publish(e.commitTopic, payload, e.qos, false)
+}
+
+func (e *Events) pendingChanged(pending int) {
+	payload, _ := json.Marshal(QueueMessage{DeviceID: e.deviceID, Site: e.site, Pending: pending, Time: time.Now().UTC()})
+	e.pub.Publish(e.queueTopic, payload, e.qos, true)
+}
@@ -0,0 +1,156 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types, in the high nibble of the first byte.
+const (
+	typeConnect    = 1
+	typeConnack    = 2
+	typePublish    = 3
+	typePuback     = 4
+	typePubrec     = 5
+	typePubrel     = 6
+	typePubcomp    = 7
+	typePingreq    = 12
+	typePingresp   = 13
+	typeDisconnect = 14
+)
+
+// connackErrors are the broker's reasons for refusing a connection.
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client ID rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// packet is a control packet as read from the broker.
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+// id returns the packet identifier of an acknowledgement.
+func (p packet) id() uint16 {
+	if len(p.body) < 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(p.body)
+}
+
+// frame prepends the fixed header to a packet's variable header and payload.
+func frame(first byte, body []byte) []byte {
+	b := []byte{first}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, body...)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func connectPacket(clientID, username, password string, keepAlive uint16) []byte {
+	body := appendString(nil, "MQTT")
+	flags := byte(0x02) // clean session
+	if username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+	body = append(body, 4, flags) // protocol level 4 is 3.1.1
+	body = binary.BigEndian.AppendUint16(body, keepAlive)
+	body = appendString(body, clientID)
+	if username != "" {
+		body = appendString(body, username)
+		if password != "" {
+			body = appendString(body, password)
+		}
+	}
+	return frame(typeConnect<<4, body)
+}
+
+func publishPacket(m *message, dup bool) []byte {
+	first := byte(typePublish<<4) | m.qos<<1
+	if dup {
+		first |= 0x08
+	}
+	if m.retain {
+		first |= 0x01
+	}
+	body := appendString(nil, m.topic)
+	if m.qos > 0 {
+		body = binary.BigEndian.AppendUint16(body, m.id)
+	}
+	return frame(first, append(body, m.payload...))
+}
+
+func ackPacket(kind byte, id uint16) []byte {
+	first := kind << 4
+	if kind == typePubrel {
+		first |= 0x02 // required by the spec
+	}
+	return frame(first, binary.BigEndian.AppendUint16(nil, id))
+}
+
+// readPacket reads one control packet.
+func readPacket(r *bufio.Reader) (packet, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errors.New("malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: first >> 4, flags: first & 0x0f, body: body}, nil
+}
+
+// checkConnack returns the broker's refusal, if any.
+func checkConnack(p packet) error {
+	if p.kind != typeConnack || len(p.body) < 2 {
+		return fmt.Errorf("expected CONNACK, got packet type %d", p.kind)
+	}
+	if code := p.body[1]; code != 0 {
+		if reason, ok := connackErrors[code]; ok {
+			return fmt.Errorf("connection refused: %s", reason)
+		}
+		return fmt.Errorf("connection refused: code %d", code)
+	}
+	return nil
+}
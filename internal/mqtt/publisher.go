@@ -0,0 +1,446 @@
+// Package mqtt publishes WMS events to an MQTT 3.1.1 broker. Messages are
+// buffered while the broker is unreachable, in a file so a restart loses
+// nothing, and sent in order once it is back; QoS 1 and 2 messages are
+// resent until the broker acknowledges them.
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// MaxBuffered is how many messages are kept while disconnected; beyond
+// it the oldest are dropped.
+const MaxBuffered = 10000
+
+// DefaultKeepAlive is how often an idle connection is checked.
+const DefaultKeepAlive = 60 * time.Second
+
+// Options configure the broker connection.
+type Options struct {
+	// Broker is "tcp://host:1883", "tls://host:8883" or "host:port".
+	Broker    string
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+
+	// BufferPath is the file holding messages not yet delivered. Empty
+	// keeps them in memory only.
+	BufferPath string
+}
+
+// message is a buffered PUBLISH.
+type message struct {
+	topic   string
+	payload []byte
+	qos     byte
+	retain  bool
+	id      uint16
+
+	sent     bool // written on the current connection
+	dup      bool // written on an earlier connection
+	released bool // QoS 2: PUBREC received, PUBREL is the next step
+}
+
+// savedMessage is a buffered message as kept in the buffer file.
+type savedMessage struct {
+	Topic    string `json:"topic"`
+	Payload  []byte `json:"payload"`
+	QoS      byte   `json:"qos"`
+	Retain   bool   `json:"retain,omitempty"`
+	ID       uint16 `json:"id,omitempty"`
+	Dup      bool   `json:"dup,omitempty"`
+	Released bool   `json:"released,omitempty"`
+}
+
+// Publisher keeps a connection to the broker and publishes to it.
+type Publisher struct {
+	opts Options
+
+	mu        sync.Mutex
+	buffer    []*message
+	nextID    uint16
+	connected bool
+
+	wake     chan struct{}
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewPublisher creates a publisher, picking up any messages left in the
+// buffer file by an earlier run.
+func NewPublisher(opts Options) *Publisher {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = DefaultKeepAlive
+	}
+	p := &Publisher{
+		opts:     opts,
+		wake:     make(chan struct{}, 1),
+		stopChan: make(chan struct{}),
+	}
+	p.load()
+	return p
+}
+
+// Start connects in the background, reconnecting until Stop.
+func (p *Publisher) Start() {
+	p.wg.Add(1)
+	go p.run()
+}
+
+// Stop disconnects. Messages not yet delivered stay in the buffer file.
+func (p *Publisher) Stop() {
+	close(p.stopChan)
+	p.wg.Wait()
+}
+
+// Connected reports whether the broker accepted the current connection.
+func (p *Publisher) Connected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.connected
+}
+
+// Buffered returns how many messages are waiting to be sent or acknowledged.
+func (p *Publisher) Buffered() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.buffer)
+}
+
+// Publish queues a message for the broker.
+func (p *Publisher) Publish(topic string, payload []byte, qos byte, retain bool) error {
+	if qos > 2 {
+		return fmt.Errorf("invalid QoS %d", qos)
+	}
+
+	p.mu.Lock()
+	if len(p.buffer) >= MaxBuffered {
+		log.Printf("[MQTT] Buffer full, dropping message to %s\n", p.buffer[0].topic)
+		p.buffer = p.buffer[1:]
+	}
+	m := &message{topic: topic, payload: payload, qos: qos, retain: retain}
+	if qos > 0 {
+		m.id = p.newID()
+	}
+	p.buffer = append(p.buffer, m)
+	p.save()
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// newID returns a packet identifier not used by a buffered message.
+func (p *Publisher) newID() uint16 {
+	for {
+		p.nextID++
+		if p.nextID == 0 {
+			continue
+		}
+		inUse := false
+		for _, m := range p.buffer {
+			inUse = inUse || m.id == p.nextID
+		}
+		if !inUse {
+			return p.nextID
+		}
+	}
+}
+
+func (p *Publisher) run() {
+	defer p.wg.Done()
+
+	wait := time.Second
+	for {
+		connected, err := p.session()
+		if err == nil {
+			return
+		}
+		if connected {
+			wait = time.Second
+		}
+		log.Printf("[MQTT] %v, reconnecting in %s\n", err, wait)
+		select {
+		case <-p.stopChan:
+			return
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, time.Minute)
+	}
+}
+
+// session runs one connection until it fails or the publisher is stopped,
+// which returns a nil error.
+func (p *Publisher) session() (connected bool, err error) {
+	conn, err := p.dial()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	keepAlive := uint16(p.opts.KeepAlive / time.Second)
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn.Write(connectPacket(p.opts.ClientID, p.opts.Username, p.opts.Password, keepAlive)); err != nil {
+		return false, err
+	}
+	ack, err := readPacket(r)
+	if err != nil {
+		return false, err
+	}
+	if err := checkConnack(ack); err != nil {
+		return false, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	log.Printf("[MQTT] Connected to %s\n", p.opts.Broker)
+	p.setConnected(true)
+	defer p.setConnected(false)
+
+	packets := make(chan packet)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			pk, err := readPacket(r)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case packets <- pk:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(p.opts.KeepAlive / 2)
+	defer ping.Stop()
+	var pingSent time.Time
+
+	if err := p.flush(conn); err != nil {
+		return true, err
+	}
+	for {
+		select {
+		case <-p.stopChan:
+			write(conn, frame(typeDisconnect<<4, nil))
+			return true, nil
+
+		case <-p.wake:
+			if err := p.flush(conn); err != nil {
+				return true, err
+			}
+
+		case pk := <-packets:
+			switch pk.kind {
+			case typePingresp:
+				pingSent = time.Time{}
+			case typePuback, typePubcomp:
+				p.acknowledged(pk.id())
+			case typePubrec:
+				p.received(pk.id())
+				if err := write(conn, ackPacket(typePubrel, pk.id())); err != nil {
+					return true, err
+				}
+			}
+
+		case err := <-readErr:
+			return true, err
+
+		case <-ping.C:
+			if !pingSent.IsZero() {
+				if time.Since(pingSent) > p.opts.KeepAlive {
+					return true, errors.New("broker stopped responding")
+				}
+				continue
+			}
+			if err := write(conn, frame(typePingreq<<4, nil)); err != nil {
+				return true, err
+			}
+			pingSent = time.Now()
+		}
+	}
+}
+
+func (p *Publisher) setConnected(connected bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.connected = connected
+	if !connected {
+		// Everything unacknowledged goes again on the next connection
+		for _, m := range p.buffer {
+			if m.sent {
+				m.sent, m.dup = false, true
+			}
+		}
+	}
+}
+
+// flush writes every buffered message not yet sent on this connection.
+// QoS 0 messages leave the buffer once written.
+func (p *Publisher) flush(conn net.Conn) error {
+	p.mu.Lock()
+	var out []byte
+	kept := p.buffer[:0]
+	for _, m := range p.buffer {
+		if !m.sent {
+			if m.released {
+				out = append(out, ackPacket(typePubrel, m.id)...)
+			} else {
+				out = append(out, publishPacket(m, m.dup)...)
+			}
+			m.sent = true
+		}
+		if m.qos > 0 {
+			kept = append(kept, m)
+		}
+	}
+	if len(kept) != len(p.buffer) {
+		p.save()
+	}
+	p.buffer = kept
+	p.mu.Unlock()
+
+	if len(out) == 0 {
+		return nil
+	}
+	return write(conn, out)
+}
+
+// acknowledged removes the message the broker has finished with.
+func (p *Publisher) acknowledged(id uint16) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, m := range p.buffer {
+		if m.id == id {
+			p.buffer = append(p.buffer[:i], p.buffer[i+1:]...)
+			p.save()
+			return
+		}
+	}
+}
+
+// received marks a QoS 2 message as stored by the broker.
+func (p *Publisher) received(id uint16) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range p.buffer {
+		if m.id == id {
+			m.released = true
+		}
+	}
+	p.save()
+}
+
+// load reads the buffer file. Messages in it may have been written to the
+// broker before the restart, so QoS 1 and 2 ones are resent as duplicates.
+func (p *Publisher) load() {
+	if p.opts.BufferPath == "" {
+		return
+	}
+	data, err := os.ReadFile(p.opts.BufferPath)
+	if err != nil {
+		return
+	}
+	var saved []savedMessage
+	if err := json.Unmarshal(data, &saved); err != nil {
+		log.Printf("[MQTT] Ignoring unreadable buffer %s: %v\n", p.opts.BufferPath, err)
+		return
+	}
+	for _, sm := range saved {
+		p.buffer = append(p.buffer, &message{
+			topic:    sm.Topic,
+			payload:  sm.Payload,
+			qos:      sm.QoS,
+			retain:   sm.Retain,
+			id:       sm.ID,
+			dup:      sm.Dup && sm.QoS > 0,
+			released: sm.Released,
+		})
+		p.nextID = max(p.nextID, sm.ID)
+	}
+	if len(p.buffer) > 0 {
+		log.Printf("[MQTT] %d buffered messages from the last run\n", len(p.buffer))
+	}
+}
+
+// save writes the buffer file, replacing it atomically. Callers hold mu.
+func (p *Publisher) save() {
+	if p.opts.BufferPath == "" {
+		return
+	}
+	saved := make([]savedMessage, 0, len(p.buffer))
+	for _, m := range p.buffer {
+		saved = append(saved, savedMessage{
+			Topic:    m.topic,
+			Payload:  m.payload,
+			QoS:      m.qos,
+			Retain:   m.retain,
+			ID:       m.id,
+			Dup:      m.sent || m.dup,
+			Released: m.released,
+		})
+	}
+	data, _ := json.Marshal(saved)
+	tmp := p.opts.BufferPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Printf("[MQTT] Failed to save buffer: %v\n", err)
+		return
+	}
+	if err := os.Rename(tmp, p.opts.BufferPath); err != nil {
+		log.Printf("[MQTT] Failed to save buffer: %v\n", err)
+	}
+}
+
+func write(conn net.Conn, b []byte) error {
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := conn.Write(b)
+	return err
+}
+
+// dial opens the network connection, with TLS for tls://, ssl:// and
+// mqtts:// brokers.
+func (p *Publisher) dial() (net.Conn, error) {
+	addr, useTLS := p.opts.Broker, false
+	if u, err := url.Parse(addr); err == nil && u.Host != "" {
+		switch u.Scheme {
+		case "tcp", "mqtt":
+		case "tls", "ssl", "mqtts":
+			useTLS = true
+		default:
+			return nil, fmt.Errorf("unknown broker scheme %q", u.Scheme)
+		}
+		addr = u.Host
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		port := "1883"
+		if useTLS {
+			port = "8883"
+		}
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		return tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: host})
+	}
+	return dialer.Dial("tcp", addr)
+}
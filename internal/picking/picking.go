@@ -0,0 +1,165 @@
+package picking
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/location"
+)
+
+// Line is a pick line with the picker's progress on it.
+type Line struct {
+	api.PickLine
+	Picked int  `json:"picked"`
+	Done   bool `json:"done"`
+}
+
+// Short reports whether fewer units were picked than ordered.
+func (l *Line) Short() bool {
+	return l.Done && l.Picked < l.Qty
+}
+
+// Session walks a picker through one pick list in location order.
+// Progress is saved after every line so a restart or going offline never
+// re-posts a line that was already confirmed.
+type Session struct {
+	List  api.PickList
+	Lines []*Line
+
+	progress *Progress
+}
+
+// Progress persists confirmed lines for all pick lists on the device.
+type Progress struct {
+	filePath string
+	mu       sync.Mutex
+	lists    map[int]map[int]Line // list ID -> line ID -> progress
+}
+
+func LoadProgress(basePath string) *Progress {
+	p := &Progress{
+		filePath: filepath.Join(basePath, "pick_progress.json"),
+		lists:    make(map[int]map[int]Line),
+	}
+
+	data, err := os.ReadFile(p.filePath)
+	if err != nil {
+		return p
+	}
+
+	var saved map[int][]Line
+	if err := json.Unmarshal(data, &saved); err != nil {
+		log.Printf("[Picking] Failed to parse progress: %v\n", err)
+		return p
+	}
+	for listID, lines := range saved {
+		p.lists[listID] = make(map[int]Line)
+		for _, line := range lines {
+			p.lists[listID][line.ID] = line
+		}
+	}
+	return p
+}
+
+func (p *Progress) save() {
+	saved := make(map[int][]Line)
+	for listID, lines := range p.lists {
+		for _, line := range lines {
+			saved[listID] = append(saved[listID], line)
+		}
+	}
+
+	data, _ := json.MarshalIndent(saved, "", "  ")
+	if err := os.WriteFile(p.filePath, data, 0644); err != nil {
+		log.Printf("[Picking] Failed to save progress: %v\n", err)
+	}
+}
+
+// Complete reports whether every line of the list has been confirmed.
+func (p *Progress) Complete(list api.PickList) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, line := range list.Lines {
+		if !p.lists[list.ID][line.ID].Done {
+			return false
+		}
+	}
+	return len(list.Lines) > 0
+}
+
+// NewSession orders the list's lines by location path and restores any
+// progress already made on it.
+func NewSession(list api.PickList, parser *location.Parser, progress *Progress) *Session {
+	s := &Session{List: list, progress: progress}
+
+	progress.mu.Lock()
+	saved := progress.lists[list.ID]
+	progress.mu.Unlock()
+
+	for _, pl := range list.Lines {
+		line := &Line{PickLine: pl}
+		if done, ok := saved[pl.ID]; ok {
+			line.Picked = done.Picked
+			line.Done = done.Done
+		}
+		s.Lines = append(s.Lines, line)
+	}
+
+	sort.SliceStable(s.Lines, func(i, j int) bool {
+		return parser.Less(s.Lines[i].Location, s.Lines[j].Location)
+	})
+	return s
+}
+
+// Current returns the next line to pick, or nil when the list is finished.
+func (s *Session) Current() *Line {
+	for _, line := range s.Lines {
+		if !line.Done {
+			return line
+		}
+	}
+	return nil
+}
+
+// Remaining counts lines not yet confirmed.
+func (s *Session) Remaining() int {
+	n := 0
+	for _, line := range s.Lines {
+		if !line.Done {
+			n++
+		}
+	}
+	return n
+}
+
+// Confirm records picked units for the current line; picked may be less
+// than the ordered quantity for a short pick.
+func (s *Session) Confirm(line *Line, picked int) {
+	line.Picked = picked
+	line.Done = true
+
+	s.progress.mu.Lock()
+	defer s.progress.mu.Unlock()
+
+	if s.progress.lists[s.List.ID] == nil {
+		s.progress.lists[s.List.ID] = make(map[int]Line)
+	}
+	s.progress.lists[s.List.ID][line.ID] = *line
+	s.progress.save()
+}
+
+// Skip moves a line to the end of the walk without confirming it.
+func (s *Session) Skip(line *Line) {
+	for i, l := range s.Lines {
+		if l == line {
+			s.Lines = append(append(s.Lines[:i:i], s.Lines[i+1:]...), line)
+			return
+		}
+	}
+}
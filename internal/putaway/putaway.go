@@ -0,0 +1,152 @@
+package putaway
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/location"
+)
+
+// Suggestion is a candidate location for putting stock away.
+type Suggestion struct {
+	Location string
+	OnHand   int  // units of the item already there
+	Free     int  // remaining capacity, only meaningful when Limited
+	Limited  bool // false when the location has no capacity limit
+	Reason   string
+	score    int
+}
+
+// Fits reports whether qty units fit in the location.
+func (s Suggestion) Fits(qty int) bool {
+	return !s.Limited || s.Free >= qty
+}
+
+// Engine ranks locations for put-away. Locations already holding the item
+// come first, then locations assigned to it, then empty ones; locations
+// outside the item's zones are never suggested.
+type Engine struct {
+	Parser *location.Parser
+	Rules  []config.ZoneRule
+
+	// Free returns the remaining capacity for the item at a location and
+	// whether the location is limited at all. Nil means unlimited everywhere.
+	Free func(location string, itemID int) (free int, limited bool)
+}
+
+func NewEngine(parser *location.Parser, rules []config.ZoneRule) *Engine {
+	return &Engine{Parser: parser, Rules: rules}
+}
+
+// WithFree returns a copy of the engine that checks capacity with free.
+func (e *Engine) WithFree(free func(location string, itemID int) (int, bool)) *Engine {
+	c := *e
+	c.Free = free
+	return &c
+}
+
+// zones returns the zones the item may go in, or nil for anywhere.
+func (e *Engine) zones(itemID int) []string {
+	var fallback []string
+	for _, rule := range e.Rules {
+		if len(rule.ItemIDs) == 0 {
+			fallback = rule.Zones
+			continue
+		}
+		if slices.Contains(rule.ItemIDs, itemID) {
+			return rule.Zones
+		}
+	}
+	return fallback
+}
+
+// Allowed reports whether the zone rules let the item go in the location.
+// Flat location codes have no zone and are only allowed without a rule.
+func (e *Engine) Allowed(itemID int, code string) bool {
+	zones := e.zones(itemID)
+	if len(zones) == 0 {
+		return true
+	}
+	return slices.Contains(zones, e.Parser.Parse(code).Level("zone"))
+}
+
+// Suggest returns up to limit locations for qty units of the item, best first.
+func (e *Engine) Suggest(itemID, qty int, stock []api.StockLevel, locations []api.Location, limit int) []Suggestion {
+	itemQty := make(map[string]int)
+	totalQty := make(map[string]int)
+	for _, row := range stock {
+		totalQty[row.Location] += row.Qty
+		if row.ItemID == itemID {
+			itemQty[row.Location] += row.Qty
+		}
+	}
+
+	assigned := make(map[string]bool)
+	reserved := make(map[string]bool) // assigned to other items only
+	var codes []string
+	known := make(map[string]bool)
+	for _, loc := range locations {
+		codes = append(codes, loc.LocationName)
+		known[loc.LocationName] = true
+		if slices.Contains(loc.Items, itemID) {
+			assigned[loc.LocationName] = true
+		} else if len(loc.Items) > 0 {
+			reserved[loc.LocationName] = true
+		}
+	}
+	for code := range totalQty {
+		if !known[code] {
+			codes = append(codes, code)
+		}
+	}
+
+	var suggestions []Suggestion
+	for _, code := range codes {
+		if !e.Allowed(itemID, code) {
+			continue
+		}
+
+		s := Suggestion{Location: code, OnHand: itemQty[code]}
+		switch {
+		case s.OnHand > 0:
+			s.score = 100
+			s.Reason = "already stocked"
+		case assigned[code]:
+			s.score = 50
+			s.Reason = "assigned to item"
+		case totalQty[code] <= 0 && !reserved[code]:
+			s.score = 20
+			s.Reason = "empty"
+		default:
+			// Holds or is kept for other items
+			continue
+		}
+
+		if e.Free != nil {
+			s.Free, s.Limited = e.Free(code, itemID)
+			if s.Limited && s.Free <= 0 {
+				continue
+			}
+			if !s.Fits(qty) {
+				s.score -= 60
+				s.Reason += ", partial fit"
+			}
+		}
+
+		suggestions = append(suggestions, s)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].score != suggestions[j].score {
+			return suggestions[i].score > suggestions[j].score
+		}
+		return e.Parser.Less(suggestions[i].Location, suggestions[j].Location)
+	})
+
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
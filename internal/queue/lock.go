@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrBusy is returned by Flush when another process, such as wms-cli run
+// alongside the app, is already sending the queue.
+var ErrBusy = errors.New("another process is sending the queue")
+
+// Lock files are created exclusively, which works the same on every
+// platform. One left behind by a process that died holding it is taken
+// over once it hasn't been touched for staleAfter.
+const (
+	fileStaleAfter  = 10 * time.Second // held only to read and rewrite the file
+	flushStaleAfter = time.Minute      // touched after each commit is posted
+	lockRetry       = 20 * time.Millisecond
+)
+
+// fileLock is a lock shared between processes.
+type fileLock struct {
+	path       string
+	staleAfter time.Duration
+}
+
+// tryLock takes the lock if it is free or stale.
+func (l fileLock) tryLock() (bool, error) {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err == nil {
+		f.WriteString(strconv.Itoa(os.Getpid()))
+		f.Close()
+		return true, nil
+	}
+	if !os.IsExist(err) {
+		return false, fmt.Errorf("lock %s: %w", l.path, err)
+	}
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return false, nil // released meanwhile; the next try takes it
+	}
+	if time.Since(info.ModTime()) > l.staleAfter {
+		// Only one process can rename it away, so only one takes it over
+		stale := l.path + ".stale" + strconv.Itoa(os.Getpid())
+		if os.Rename(l.path, stale) != nil {
+			return false, nil
+		}
+		// Another process may have taken it over between the checks
+		if info, err := os.Stat(stale); err == nil && time.Since(info.ModTime()) <= l.staleAfter {
+			os.Rename(stale, l.path)
+			return false, nil
+		}
+		os.Remove(stale)
+	}
+	return false, nil
+}
+
+// lock waits for the lock. Holders of file locks never wait on anything
+// else, so this doesn't block for long.
+func (l fileLock) lock() error {
+	for {
+		ok, err := l.tryLock()
+		if ok || err != nil {
+			return err
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+// touch tells other processes the holder is still alive.
+func (l fileLock) touch() {
+	now := time.Now()
+	os.Chtimes(l.path, now, now)
+}
+
+func (l fileLock) unlock() {
+	os.Remove(l.path)
+}
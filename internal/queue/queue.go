@@ -2,7 +2,7 @@ package queue
 
 import (
 	"encoding/json"
-	"fmt"
+	"log"
 	"net"
 	"os"
 	"path/filepath"
@@ -13,10 +13,31 @@ import (
 )
 
 type Commit struct {
-	DeviceID string `json:"device_id"`
-	Location string `json:"location"`
-	Delta    int    `json:"delta"`
-	ItemID   int    `json:"item_id"`
+	DeviceID  string `json:"device_id"`
+	Location  string `json:"location"`
+	Delta     int    `json:"delta"`
+	ItemID    int    `json:"item_id"`
+	UoM       string `json:"uom,omitempty"`
+	UoMQty    int    `json:"uom_qty,omitempty"`
+	Site      string `json:"site,omitempty"`
+	Reference string `json:"reference,omitempty"`
+	POLineID  int    `json:"po_line_id,omitempty"`
+	Operator  string `json:"operator,omitempty"`
+}
+
+func (c Commit) payload() api.CommitPayload {
+	return api.CommitPayload{
+		DeviceID:  c.DeviceID,
+		Location:  c.Location,
+		Delta:     c.Delta,
+		ItemID:    c.ItemID,
+		UoM:       c.UoM,
+		UoMQty:    c.UoMQty,
+		Site:      c.Site,
+		Reference: c.Reference,
+		POLineID:  c.POLineID,
+		Operator:  c.Operator,
+	}
 }
 
 type Queue struct {
@@ -26,14 +47,23 @@ type Queue struct {
 	stopChan      chan struct{}
 	wg            sync.WaitGroup
 	mu            sync.RWMutex
+	sending       sync.Mutex
+	fileLock      fileLock // guards the file against other processes
+	flushLock     fileLock // held by the process sending the queue
+	listeners     []func(Commit)
+	batchers      []func([]Commit)
+	watchers      []func(pending int)
 }
 
 func NewQueue(apiClient *api.Client, basePath string) *Queue {
+	filePath := filepath.Join(basePath, "pending_commits.json")
 	return &Queue{
 		api:           apiClient,
-		filePath:      filepath.Join(basePath, "pending_commits.json"),
+		filePath:      filePath,
 		checkInterval: 5 * time.Second,
 		stopChan:      make(chan struct{}),
+		fileLock:      fileLock{filePath + ".lock", fileStaleAfter},
+		flushLock:     fileLock{filePath + ".flush", flushStaleAfter},
 	}
 }
 
@@ -47,22 +77,63 @@ func (q *Queue) Stop() {
 	q.wg.Wait()
 }
 
-func (q *Queue) SubmitCommit(deviceID, location string, delta, itemID int) {
+// OnSynced registers a listener called with each commit once the server
+// has accepted it. Listeners run on the sending goroutine, after the queue
+// is unlocked, so they may call Pending.
+func (q *Queue) OnSynced(listener func(Commit)) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
+	q.listeners = append(q.listeners, listener)
+}
 
-	commit := Commit{
+// OnBatchSynced registers a listener called once per flush with all the
+// commits the server accepted in it, for checks that must see the batch
+// as a whole. It runs like OnSynced listeners, after them.
+func (q *Queue) OnBatchSynced(listener func([]Commit)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.batchers = append(q.batchers, listener)
+}
+
+// OnPendingChange registers a watcher called with the number of pending
+// commits after one is queued or sent.
+func (q *Queue) OnPendingChange(watcher func(pending int)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.watchers = append(q.watchers, watcher)
+}
+
+func (q *Queue) SubmitCommit(deviceID, location string, delta, itemID int) {
+	q.Submit(Commit{
 		DeviceID: deviceID,
 		Location: location,
 		Delta:    delta,
 		ItemID:   itemID,
-	}
+	})
+}
 
+func (q *Queue) Submit(commit Commit) {
+	q.mu.Lock()
+	unlock := q.lockFile()
 	queue := q.loadQueue()
 	queue = append(queue, commit)
 	q.saveQueue(queue)
+	unlock()
+	watchers := q.watchers
+	q.mu.Unlock()
 
-	fmt.Printf("Commit queued: %+v\n", commit)
+	log.Printf("[Queue] Commit queued: %+v\n", commit)
+	for _, watcher := range watchers {
+		watcher(len(queue))
+	}
+}
+
+// Pending returns the commits still waiting to be sent.
+func (q *Queue) Pending() []Commit {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return q.loadQueue()
 }
 
 func (q *Queue) worker() {
@@ -77,7 +148,9 @@ func (q *Queue) worker() {
 			return
 		case <-ticker.C:
 			if q.internetAvailable() {
-				q.processQueue()
+				if _, _, err := q.processQueue(); err != nil && err != ErrBusy {
+					log.Printf("[Queue] %v\n", err)
+				}
 			}
 		}
 	}
@@ -93,29 +166,113 @@ func (q *Queue) internetAvailable() bool {
 	return true
 }
 
-func (q *Queue) processQueue() {
-	q.mu.Lock()
-	defer q.mu.Unlock()
+// Flush sends pending commits now, without waiting for the worker, and
+// returns how many were sent and how many are still pending. It returns
+// ErrBusy, sending nothing, while another process is sending.
+func (q *Queue) Flush() (sent, remaining int, err error) {
+	return q.processQueue()
+}
 
-	queue := q.loadQueue()
+func (q *Queue) processQueue() (sent, remaining int, err error) {
+	synced, remaining, err := q.send()
+
+	q.mu.RLock()
+	listeners, batchers, watchers := q.listeners, q.batchers, q.watchers
+	q.mu.RUnlock()
+	for _, commit := range synced {
+		for _, listener := range listeners {
+			listener(commit)
+		}
+	}
+	if len(synced) > 0 {
+		for _, batcher := range batchers {
+			batcher(synced)
+		}
+		for _, watcher := range watchers {
+			watcher(remaining)
+		}
+	}
+	return len(synced), remaining, err
+}
+
+// send posts the pending commits and returns those the server accepted.
+// Only one process sends at a time, so no commit is posted twice; others
+// may append while it does, which the final rewrite keeps.
+func (q *Queue) send() (synced []Commit, remaining int, err error) {
+	q.sending.Lock()
+	defer q.sending.Unlock()
+
+	ok, err := q.flushLock.tryLock()
+	if !ok {
+		if err == nil {
+			err = ErrBusy
+		}
+		return nil, len(q.Pending()), err
+	}
+	defer q.flushLock.unlock()
+
+	queue := q.Pending()
 	if len(queue) == 0 {
-		return
+		return nil, 0, nil
 	}
 
-	fmt.Printf("Processing %d pending commits...\n", len(queue))
+	log.Printf("[Queue] Processing %d pending commits...\n", len(queue))
 
-	var newQueue []Commit
 	for _, commit := range queue {
-		_, err := q.api.SendCommit(commit.DeviceID, commit.Location, commit.Delta, commit.ItemID)
+		_, err := q.api.PostCommit(commit.payload())
+		q.flushLock.touch()
 		if err != nil {
-			fmt.Printf("Failed to send commit: %v\n", err)
-			newQueue = append(newQueue, commit)
+			log.Printf("[Queue] Failed to send commit: %v\n", err)
 		} else {
-			fmt.Printf("Committed: %s@%s delta=%d\n", commit.Location, commit.DeviceID, commit.Delta)
+			log.Printf("[Queue] Committed: %s@%s delta=%d\n", commit.Location, commit.DeviceID, commit.Delta)
+			q.releaseReservations(commit)
+			synced = append(synced, commit)
 		}
 	}
 
+	// Re-read the file so commits queued meanwhile are kept
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.lockFile()()
+	newQueue := removeCommits(q.loadQueue(), synced)
 	q.saveQueue(newQueue)
+	return synced, len(newQueue), nil
+}
+
+// lockFile takes the file lock and returns its release. If the lock can't
+// be created the file is used unlocked, as before locking existed.
+func (q *Queue) lockFile() func() {
+	if err := q.fileLock.lock(); err != nil {
+		log.Printf("[Queue] %v\n", err)
+		return func() {}
+	}
+	return q.fileLock.unlock
+}
+
+// removeCommits returns queue without one copy of each sent commit.
+func removeCommits(queue, sent []Commit) []Commit {
+	remaining := append([]Commit{}, queue...)
+	for _, commit := range sent {
+		for i, c := range remaining {
+			if c == commit {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return remaining
+}
+
+// releaseReservations frees the stock held for an order that its pick
+// just took, once the pick is posted.
+func (q *Queue) releaseReservations(commit Commit) {
+	if commit.Reference == "" || commit.Delta >= 0 {
+		return
+	}
+	err := q.api.ReleaseReserved(commit.Reference, commit.ItemID, commit.Location, -commit.Delta)
+	if err != nil {
+		log.Printf("[Queue] Failed to release reservations for %s: %v\n", commit.Reference, err)
+	}
 }
 
 func (q *Queue) loadQueue() []Commit {
@@ -133,7 +290,16 @@ func (q *Queue) loadQueue() []Commit {
 	return commits
 }
 
+// saveQueue replaces the file in one step, so readers in other processes
+// never see it half written.
 func (q *Queue) saveQueue(commits []Commit) {
 	data, _ := json.MarshalIndent(commits, "", "  ")
-	os.WriteFile(q.filePath, data, 0644)
+	tmp := q.filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Printf("[Queue] Failed to save queue: %v\n", err)
+		return
+	}
+	if err := os.Rename(tmp, q.filePath); err != nil {
+		log.Printf("[Queue] Failed to save queue: %v\n", err)
+	}
 }
@@ -0,0 +1,110 @@
+package receiving
+
+import (
+	"fmt"
+
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/queue"
+)
+
+// Line statuses, from nothing received to more than ordered.
+const (
+	StatusOpen     = "open"
+	StatusUnder    = "under"
+	StatusComplete = "complete"
+	StatusOver     = "over"
+)
+
+// Line is a purchase order line with receipts made on this device that
+// haven't synced yet added to the server's received quantity.
+type Line struct {
+	api.POLine
+	Pending int
+}
+
+// Total is everything received against the line so far.
+func (l Line) Total() int {
+	return l.Received + l.Pending
+}
+
+// Status compares the received total with the ordered quantity.
+func (l Line) Status() string {
+	switch total := l.Total(); {
+	case total == 0:
+		return StatusOpen
+	case total < l.Qty:
+		return StatusUnder
+	case total == l.Qty:
+		return StatusComplete
+	default:
+		return StatusOver
+	}
+}
+
+// Outstanding is the quantity still expected, never negative.
+func (l Line) Outstanding() int {
+	if l.Total() >= l.Qty {
+		return 0
+	}
+	return l.Qty - l.Total()
+}
+
+// Receipt tracks receiving progress on one purchase order.
+type Receipt struct {
+	Order api.PurchaseOrder
+	Lines []*Line
+}
+
+// NewReceipt combines the order with receipts still waiting in the queue.
+func NewReceipt(order api.PurchaseOrder, pending []queue.Commit) *Receipt {
+	r := &Receipt{Order: order}
+
+	byLine := make(map[int]int)
+	for _, commit := range pending {
+		if commit.POLineID != 0 {
+			byLine[commit.POLineID] += commit.Delta
+		}
+	}
+
+	for _, pl := range order.Lines {
+		r.Lines = append(r.Lines, &Line{POLine: pl, Pending: byLine[pl.ID]})
+	}
+	return r
+}
+
+// LineForItem finds the line to receive an item against, preferring one
+// that still has units outstanding.
+func (r *Receipt) LineForItem(itemID int) (*Line, error) {
+	var match *Line
+	for _, line := range r.Lines {
+		if line.ItemID != itemID {
+			continue
+		}
+		if line.Outstanding() > 0 {
+			return line, nil
+		}
+		if match == nil {
+			match = line
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("item %d is not on purchase order %s", itemID, r.Order.Reference)
+	}
+	return match, nil
+}
+
+// Record adds a receipt made on this device to a line.
+func (r *Receipt) Record(line *Line, qty int) {
+	line.Pending += qty
+}
+
+// Discrepancies returns lines received over or under the ordered quantity.
+func (r *Receipt) Discrepancies() []*Line {
+	var lines []*Line
+	for _, line := range r.Lines {
+		if s := line.Status(); s == StatusOver || s == StatusUnder {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,176 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/larkin1/wmsproject/internal/pdf"
+)
+
+// Page layout in points.
+const (
+	margin     = 40.0
+	rowHeight  = 14.0
+	fontSize   = 9.0
+	titleSize  = 16.0
+	headerSize = 11.0
+)
+
+// column is a table column; width is a fraction of the printable width.
+type column struct {
+	title   string
+	width   float64
+	right   bool // numbers are right-aligned
+	writeIn bool // left blank with a line to write on
+}
+
+// sheet lays out a titled table over as many pages as it needs, repeating
+// the column headings on each page.
+type sheet struct {
+	doc      *pdf.Document
+	page     *pdf.Page
+	title    string
+	subtitle string
+	columns  []column
+	y        float64
+	section  string // current section heading, repeated after a page break
+	shade    bool
+}
+
+func newSheet(title, subtitle string, columns []column) *sheet {
+	doc := pdf.New(pdf.A4Width, pdf.A4Height)
+	doc.Title = title
+	return &sheet{doc: doc, title: title, subtitle: subtitle, columns: columns}
+}
+
+func (s *sheet) width() float64 {
+	return pdf.A4Width - 2*margin
+}
+
+func (s *sheet) bottom() float64 {
+	return pdf.A4Height - margin - rowHeight
+}
+
+// newPage starts a page with the title.
+func (s *sheet) newPage() {
+	s.page = s.doc.AddPage()
+	s.page.Text(margin, margin+titleSize, pdf.HelveticaBold, titleSize, s.title)
+	s.page.Text(margin, margin+titleSize+14, pdf.Helvetica, fontSize, s.subtitle)
+	s.y = margin + titleSize + 24
+}
+
+// pageBreak continues the table on a new page.
+func (s *sheet) pageBreak() {
+	s.newPage()
+	if s.section != "" {
+		s.heading(s.section + " (continued)")
+	}
+	s.columnHeadings()
+}
+
+// ensure starts a new page unless n more rows fit.
+func (s *sheet) ensure(n int) {
+	if s.page == nil || s.y+float64(n)*rowHeight > s.bottom() {
+		s.pageBreak()
+	}
+}
+
+// startSection starts a group of rows under a heading, optionally on a
+// fresh page. A section is never started at the very bottom of a page.
+func (s *sheet) startSection(name string, newPage bool) {
+	if newPage || s.page == nil || s.y+4*rowHeight > s.bottom() {
+		s.newPage()
+	} else {
+		s.y += rowHeight / 2
+	}
+	s.heading(name)
+	s.section = name
+	s.columnHeadings()
+	s.shade = false
+}
+
+func (s *sheet) heading(text string) {
+	s.y += headerSize + 4
+	s.page.Text(margin, s.y, pdf.HelveticaBold, headerSize, text)
+	s.y += 6
+}
+
+func (s *sheet) columnHeadings() {
+	s.y += rowHeight
+	s.cells(pdf.HelveticaBold, s.titles())
+	s.page.Line(margin, s.y+4, margin+s.width(), s.y+4, 0.75)
+	s.y += 4
+}
+
+func (s *sheet) titles() []string {
+	titles := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		titles[i] = c.title
+	}
+	return titles
+}
+
+// row adds a table row, shading every other one.
+func (s *sheet) row(values ...string) {
+	s.ensure(1)
+	s.y += rowHeight
+	if s.shade {
+		s.page.SetGray(0.93)
+		s.page.Rect(margin, s.y-rowHeight+4, s.width(), rowHeight)
+		s.page.SetGray(0)
+	}
+	s.shade = !s.shade
+	s.cells(pdf.Helvetica, values)
+
+	x := margin
+	for _, c := range s.columns {
+		w := c.width * s.width()
+		if c.writeIn {
+			s.page.Line(x+4, s.y+2, x+w-4, s.y+2, 0.5)
+		}
+		x += w
+	}
+}
+
+// total adds a bold row under a rule.
+func (s *sheet) total(values ...string) {
+	s.ensure(1)
+	s.page.Line(margin, s.y+4, margin+s.width(), s.y+4, 0.75)
+	s.y += rowHeight + 2
+	s.cells(pdf.HelveticaBold, values)
+}
+
+// note adds a line of text across the table.
+func (s *sheet) note(text string) {
+	s.ensure(1)
+	s.y += rowHeight
+	s.page.Text(margin, s.y, pdf.Helvetica, fontSize, text)
+}
+
+func (s *sheet) cells(font pdf.Font, values []string) {
+	x := margin
+	for i, c := range s.columns {
+		w := c.width * s.width()
+		if i < len(values) && values[i] != "" {
+			text := pdf.Fit(font, fontSize, values[i], w-8)
+			if c.right {
+				s.page.TextRight(x+w-4, s.y, font, fontSize, text)
+			} else {
+				s.page.Text(x+4, s.y, font, fontSize, text)
+			}
+		}
+		x += w
+	}
+}
+
+// finish numbers the pages and returns the document.
+func (s *sheet) finish() *pdf.Document {
+	if s.page == nil {
+		s.newPage()
+		s.note("Nothing to report.")
+	}
+	pages := s.doc.Pages()
+	for i, p := range pages {
+		p.TextRight(pdf.A4Width-margin, pdf.A4Height-margin/2, pdf.Helvetica, 8, fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+	}
+	return s.doc
+}
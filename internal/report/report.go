@@ -0,0 +1,418 @@
+// Package report builds printable PDF reports from stock levels and commit
+// history: stock sheets by zone, movements by day and cycle count variance.
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/location"
+	"github.com/larkin1/wmsproject/internal/pdf"
+)
+
+// Report kinds.
+const (
+	Stock     = "stock"
+	Movements = "movements"
+	Variance  = "variance"
+)
+
+// Kinds lists every report, in menu order.
+var Kinds = []string{Stock, Movements, Variance}
+
+// Title returns the heading of a report kind.
+func Title(kind string) string {
+	switch kind {
+	case Stock:
+		return "Stock by Location"
+	case Movements:
+		return "Stock Movements"
+	case Variance:
+		return "Cycle Count Variance"
+	}
+	return kind
+}
+
+// CountReference is the default reference prefix of cycle count
+// adjustments, e.g. "cycle-count" or "cycle-count-2024-03".
+const CountReference = "cycle-count"
+
+// Options narrow a report. From and To bound commit history (To is
+// exclusive); Location keeps only codes starting with it.
+type Options struct {
+	Site      string
+	From      time.Time
+	To        time.Time
+	Location  string
+	Reference string // cycle count reference prefix, default CountReference
+}
+
+// Data is what the reports are built from.
+type Data struct {
+	Items     []api.Item
+	Locations []api.Location
+	Stock     []api.StockLevel
+	Commits   []api.CommitRecord
+}
+
+// Fetch loads the data a report kind needs. Stock levels come from the
+// local store when offline; commit history needs a connection.
+func Fetch(client *api.Client, kind string, opts Options) (*Data, error) {
+	data := &Data{}
+	var err error
+	if data.Items, err = client.FetchItems(); err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case Stock:
+		if data.Locations, err = client.FetchLocations(); err != nil {
+			return nil, err
+		}
+		if data.Stock, err = client.FetchOverview(); err != nil {
+			return nil, err
+		}
+	case Movements:
+		if data.Commits, err = client.FetchCommits(opts.From, opts.To, opts.Location); err != nil {
+			return nil, err
+		}
+	case Variance:
+		if data.Stock, err = client.FetchOverview(); err != nil {
+			return nil, err
+		}
+		if data.Commits, err = client.FetchCommits(opts.From, opts.To, opts.Location); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown report %q", kind)
+	}
+	return data, nil
+}
+
+// Generate fetches the data for a report and builds it.
+func Generate(client *api.Client, parser *location.Parser, kind string, opts Options) (*pdf.Document, error) {
+	if opts.Site == "" {
+		opts.Site = client.Site
+	}
+	data, err := Fetch(client, kind, opts)
+	if err != nil {
+		return nil, err
+	}
+	return Build(kind, parser, opts, data)
+}
+
+// Build lays out a report.
+func Build(kind string, parser *location.Parser, opts Options, data *Data) (*pdf.Document, error) {
+	switch kind {
+	case Stock:
+		return stockReport(parser, opts, data), nil
+	case Movements:
+		return movementsReport(opts, data), nil
+	case Variance:
+		return varianceReport(parser, opts, data), nil
+	}
+	return nil, fmt.Errorf("unknown report %q", kind)
+}
+
+// Dir returns where reports are saved under a data directory.
+func Dir(dataPath string) string {
+	return filepath.Join(dataPath, "reports")
+}
+
+// Save writes a report to Dir(dataPath) with a timestamped name and
+// returns its path.
+func Save(doc *pdf.Document, dataPath, kind string) (string, error) {
+	dir := Dir(dataPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, kind+"-"+time.Now().Format("20060102-150405")+".pdf")
+	if err := doc.WriteFile(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Saved lists the reports in Dir(dataPath), newest first.
+func Saved(dataPath string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(Dir(dataPath), "*.pdf"))
+	if err != nil {
+		return nil, err
+	}
+	modified := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			modified[path] = info.ModTime()
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool { return modified[paths[i]].After(modified[paths[j]]) })
+	return paths, nil
+}
+
+// subtitle describes the site, filters and generation time.
+func subtitle(opts Options, dates bool) string {
+	var parts []string
+	if opts.Site != "" {
+		parts = append(parts, "Site "+opts.Site)
+	}
+	if opts.Location != "" {
+		parts = append(parts, "Locations "+opts.Location+"*")
+	}
+	if dates {
+		parts = append(parts, period(opts.From, opts.To))
+	}
+	parts = append(parts, "Generated "+time.Now().Format("2 Jan 2006 15:04"))
+	return strings.Join(parts, "  •  ")
+}
+
+func period(from, to time.Time) string {
+	const layout = "2 Jan 2006"
+	switch {
+	case from.IsZero() && to.IsZero():
+		return "All dates"
+	case to.IsZero():
+		return "From " + from.Format(layout)
+	}
+	last := to.Add(-time.Nanosecond)
+	if from.IsZero() {
+		return "Up to " + last.Format(layout)
+	}
+	return from.Format(layout) + " – " + last.Format(layout)
+}
+
+func itemNames(items []api.Item) map[int]string {
+	names := make(map[int]string, len(items))
+	for _, item := range items {
+		names[item.ID] = item.Name
+	}
+	return names
+}
+
+// zone returns the zone heading of a location code.
+func zone(parser *location.Parser, code string) string {
+	if z := parser.Parse(code).Level("zone"); z != "" {
+		return "Zone " + z
+	}
+	return "Other locations"
+}
+
+// less orders location codes by path, with codes outside any zone last so
+// each zone is a single run.
+func less(parser *location.Parser, a, b string) bool {
+	za, zb := parser.Parse(a).Level("zone"), parser.Parse(b).Level("zone")
+	if (za == "") != (zb == "") {
+		return zb == ""
+	}
+	return parser.Less(a, b)
+}
+
+func signed(n int) string {
+	if n > 0 {
+		return "+" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// stockReport prints on-hand stock one zone per page, with a column to
+// write counted quantities in. Empty locations are listed so they get
+// checked too.
+func stockReport(parser *location.Parser, opts Options, data *Data) *pdf.Document {
+	names := itemNames(data.Items)
+	byLocation := make(map[string][]api.StockLevel)
+	for _, row := range data.Stock {
+		if row.Qty != 0 && strings.HasPrefix(row.Location, opts.Location) {
+			byLocation[row.Location] = append(byLocation[row.Location], row)
+		}
+	}
+	for _, loc := range data.Locations {
+		if _, ok := byLocation[loc.LocationName]; !ok && strings.HasPrefix(loc.LocationName, opts.Location) {
+			byLocation[loc.LocationName] = nil
+		}
+	}
+
+	codes := make([]string, 0, len(byLocation))
+	for code := range byLocation {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return less(parser, codes[i], codes[j]) })
+
+	s := newSheet(Title(Stock), subtitle(opts, false), []column{
+		{title: "Location", width: 0.2},
+		{title: "Item ID", width: 0.1, right: true},
+		{title: "Item", width: 0.4},
+		{title: "On hand", width: 0.12, right: true},
+		{title: "Counted", width: 0.18, writeIn: true},
+	})
+
+	current, units, locations := "", 0, 0
+	endZone := func() {
+		if current != "" {
+			s.total(fmt.Sprintf("%d locations", locations), "", "", strconv.Itoa(units))
+		}
+	}
+	for _, code := range codes {
+		if z := zone(parser, code); z != current {
+			endZone()
+			s.startSection(z, true)
+			current, units, locations = z, 0, 0
+		}
+		locations++
+
+		rows := byLocation[code]
+		if len(rows) == 0 {
+			s.row(code, "", "(empty)", "0")
+			continue
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i].ItemID < rows[j].ItemID })
+		for _, row := range rows {
+			s.row(code, strconv.Itoa(row.ItemID), names[row.ItemID], strconv.Itoa(row.Qty))
+			units += row.Qty
+		}
+	}
+	endZone()
+	return s.finish()
+}
+
+// movementsReport lists commits grouped by day with in, out and net totals.
+func movementsReport(opts Options, data *Data) *pdf.Document {
+	names := itemNames(data.Items)
+	s := newSheet(Title(Movements), subtitle(opts, true), []column{
+		{title: "Time", width: 0.09},
+		{title: "Location", width: 0.17},
+		{title: "Item", width: 0.3},
+		{title: "Qty", width: 0.1, right: true},
+		{title: "Reference", width: 0.19},
+		{title: "Device", width: 0.15},
+	})
+
+	type totals struct{ in, out, count int }
+	var day, all totals
+	current := ""
+	endDay := func() {
+		if current != "" {
+			s.total(fmt.Sprintf("%d moves", day.count), "", fmt.Sprintf("In %d, out %d", day.in, day.out), signed(day.in-day.out))
+		}
+	}
+	for _, c := range data.Commits {
+		created := c.CreatedAt.Local()
+		if d := created.Format("Monday 2 January 2006"); d != current {
+			endDay()
+			s.startSection(d, false)
+			current, day = d, totals{}
+		}
+
+		s.row(created.Format("15:04"), c.Location, names[c.ItemID], signed(c.Delta), c.Reference, c.DeviceID)
+		for _, t := range []*totals{&day, &all} {
+			t.count++
+			if c.Delta > 0 {
+				t.in += c.Delta
+			} else {
+				t.out -= c.Delta
+			}
+		}
+	}
+	endDay()
+
+	if all.count > 0 {
+		s.section = ""
+		s.ensure(3)
+		s.y += rowHeight
+		s.total(fmt.Sprintf("%d moves", all.count), "", fmt.Sprintf("Period: in %d, out %d", all.in, all.out), signed(all.in-all.out))
+	}
+	return s.finish()
+}
+
+// varianceReport sums cycle count adjustments per location and item. Each
+// adjustment is the counted quantity minus what was on record, so the sum
+// is the variance found.
+func varianceReport(parser *location.Parser, opts Options, data *Data) *pdf.Document {
+	reference := opts.Reference
+	if reference == "" {
+		reference = CountReference
+	}
+	names := itemNames(data.Items)
+
+	type key struct {
+		location string
+		itemID   int
+	}
+	type line struct {
+		key
+		variance int
+		counted  time.Time
+	}
+	lines := make(map[key]*line)
+	for _, c := range data.Commits {
+		if !strings.HasPrefix(c.Reference, reference) {
+			continue
+		}
+		k := key{c.Location, c.ItemID}
+		if lines[k] == nil {
+			lines[k] = &line{key: k}
+		}
+		lines[k].variance += c.Delta
+		if c.CreatedAt.After(lines[k].counted) {
+			lines[k].counted = c.CreatedAt.Time
+		}
+	}
+	onHand := make(map[key]int)
+	for _, row := range data.Stock {
+		onHand[key{row.Location, row.ItemID}] += row.Qty
+	}
+
+	sorted := make([]*line, 0, len(lines))
+	for _, l := range lines {
+		sorted = append(sorted, l)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].location != sorted[j].location {
+			return less(parser, sorted[i].location, sorted[j].location)
+		}
+		return sorted[i].itemID < sorted[j].itemID
+	})
+
+	s := newSheet(Title(Variance), subtitle(opts, true)+"  •  Reference "+reference+"*", []column{
+		{title: "Location", width: 0.18},
+		{title: "Item ID", width: 0.09, right: true},
+		{title: "Item", width: 0.33},
+		{title: "Counted", width: 0.18},
+		{title: "Variance", width: 0.11, right: true},
+		{title: "On hand", width: 0.11, right: true},
+	})
+
+	current := ""
+	net, gross, short, over := 0, 0, 0, 0
+	for _, l := range sorted {
+		if z := zone(parser, l.location); z != current {
+			s.startSection(z, false)
+			current = z
+		}
+		s.row(l.location, strconv.Itoa(l.itemID), names[l.itemID], l.counted.Local().Format("2 Jan 15:04"),
+			signed(l.variance), strconv.Itoa(onHand[l.key]))
+
+		net += l.variance
+		switch {
+		case l.variance < 0:
+			gross -= l.variance
+			short++
+		case l.variance > 0:
+			gross += l.variance
+			over++
+		}
+	}
+
+	if len(sorted) > 0 {
+		s.section = ""
+		s.ensure(4)
+		s.y += rowHeight
+		s.total(fmt.Sprintf("%d lines", len(sorted)), "", fmt.Sprintf("%d short, %d over, %d exact", short, over, len(sorted)-short-over), "", signed(net))
+		s.note(fmt.Sprintf("Gross variance: %d units", gross))
+	}
+	return s.finish()
+}
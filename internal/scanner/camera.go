@@ -0,0 +1,114 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/larkin1/wmsproject/internal/barcode"
+)
+
+// captureTimeout bounds one run of the capture command.
+const captureTimeout = 10 * time.Second
+
+// Camera reads barcodes through a camera. Frames come from a command that
+// writes one PNG or JPEG image to stdout, for example
+//
+//	ffmpeg -loglevel error -f v4l2 -i /dev/video0 -frames:v 1 -f image2pipe -c:v png -
+//	fswebcam --no-banner --png 0 -
+//	libcamera-still -n -t 500 -e png -o -
+//
+// which keeps the app free of platform camera libraries.
+type Camera struct {
+	command []string
+}
+
+// NewCamera returns nil when command is empty. Arguments are split on
+// spaces; double quotes keep one with spaces together.
+func NewCamera(command string) *Camera {
+	args := splitCommand(command)
+	if len(args) == 0 {
+		return nil
+	}
+	return &Camera{command: args}
+}
+
+// Capture takes one frame.
+func (c *Camera) Capture() (image.Image, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), captureTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %v: %s", c.command[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %v", c.command[0], err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("%s did not write a PNG or JPEG image: %v", c.command[0], err)
+	}
+	return img, nil
+}
+
+// ErrStopped is returned by Scan when it is stopped before finding a code.
+var ErrStopped = errors.New("camera scan stopped")
+
+// Scan captures frames until one holds a barcode, returning what it
+// decoded, or until stop is closed. onFrame, if set, is called with each
+// frame for a preview.
+func (c *Camera) Scan(stop <-chan struct{}, onFrame func(image.Image)) ([]barcode.Result, error) {
+	for {
+		select {
+		case <-stop:
+			return nil, ErrStopped
+		default:
+		}
+
+		img, err := c.Capture()
+		if err != nil {
+			return nil, err
+		}
+		if onFrame != nil {
+			onFrame(img)
+		}
+		if results := barcode.Decode(img); len(results) > 0 {
+			return results, nil
+		}
+	}
+}
+
+func splitCommand(command string) []string {
+	var args []string
+	var arg strings.Builder
+	quoted, inArg := false, false
+	for _, r := range command {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
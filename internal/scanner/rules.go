@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/larkin1/wmsproject/internal/config"
+)
+
+// Scan is a scanned code classified by the scan rules.
+type Scan struct {
+	Code  string // as scanned
+	Kind  string // a config.Scan* kind, or "" when there are no rules
+	Value string // the code with any stripped prefix removed
+}
+
+type rule struct {
+	config.ScanRule
+	re *regexp.Regexp
+}
+
+// Classifier sorts scanned codes into locations, items, operator badges
+// and commands by the configured rules.
+type Classifier struct {
+	rules []rule
+}
+
+// NewClassifier checks and compiles rules. It returns nil when there are
+// none, and a nil Classifier leaves every scan unclassified.
+func NewClassifier(rules []config.ScanRule) (*Classifier, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	c := &Classifier{}
+	for i, r := range rules {
+		switch r.Kind {
+		case config.ScanLocation, config.ScanItem, config.ScanBadge, config.ScanCommand:
+		default:
+			return nil, fmt.Errorf("scan rule %d: unknown kind %q (use location, item, badge or command)", i+1, r.Kind)
+		}
+		if r.Prefix == "" && r.Pattern == "" {
+			return nil, fmt.Errorf("scan rule %d: needs a prefix or pattern", i+1)
+		}
+		compiled := rule{ScanRule: r}
+		if r.Pattern != "" {
+			re, err := regexp.Compile("^(?:" + r.Pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("scan rule %d: invalid pattern %q: %w", i+1, r.Pattern, err)
+			}
+			compiled.re = re
+		}
+		c.rules = append(c.rules, compiled)
+	}
+	return c, nil
+}
+
+// Classify returns the first rule's reading of code, or an error naming
+// the expected kinds when no rule matches.
+func (c *Classifier) Classify(code string) (Scan, error) {
+	code = strings.TrimSpace(code)
+	scan := Scan{Code: code, Value: code}
+	if c == nil {
+		return scan, nil
+	}
+
+	var kinds []string
+	for _, r := range c.rules {
+		rest, ok := strings.CutPrefix(code, r.Prefix)
+		if ok && (r.re == nil || r.re.MatchString(rest)) {
+			scan.Kind = r.Kind
+			if r.StripPrefix {
+				scan.Value = rest
+			}
+			return scan, nil
+		}
+		if !slices.Contains(kinds, r.Kind) {
+			kinds = append(kinds, r.Kind)
+		}
+	}
+	expected := kinds[len(kinds)-1]
+	if len(kinds) > 1 {
+		expected = strings.Join(kinds[:len(kinds)-1], ", ") + " or " + expected
+	}
+	return scan, fmt.Errorf("'%s' is not a valid %s code", code, expected)
+}
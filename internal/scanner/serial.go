@@ -0,0 +1,114 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retryDelay is how long Serial waits before reopening a device that is
+// missing or was unplugged.
+const retryDelay = 2 * time.Second
+
+// Serial reads scans from a serial or USB-CDC scanner, one code per line
+// ending in CR, LF or both. Such scanners appear as a device path, e.g.
+// /dev/ttyACM0 on Linux or COM3 on Windows; set the port's baud rate
+// with the OS tools if the scanner isn't USB-CDC.
+type Serial struct {
+	path   string
+	onScan func(code string)
+
+	mu       sync.Mutex
+	file     *os.File
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSerial creates a reader calling onScan, from its own goroutine, with
+// each code read from path.
+func NewSerial(path string, onScan func(code string)) *Serial {
+	return &Serial{
+		path:     path,
+		onScan:   onScan,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start reads in the background until Stop, reopening the device if it
+// goes away.
+func (s *Serial) Start() {
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *Serial) Stop() {
+	close(s.stopChan)
+	s.mu.Lock()
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Serial) run() {
+	defer s.wg.Done()
+
+	for {
+		f, err := os.OpenFile(s.path, os.O_RDONLY, 0)
+		if err != nil {
+			log.Printf("[Scanner] Cannot open %s: %v\n", s.path, err)
+		} else {
+			s.mu.Lock()
+			s.file = f
+			s.mu.Unlock()
+
+			log.Printf("[Scanner] Reading scans from %s\n", s.path)
+			err = s.read(f)
+			f.Close()
+			select {
+			case <-s.stopChan:
+				return
+			default:
+			}
+			log.Printf("[Scanner] %s closed: %v\n", s.path, err)
+		}
+
+		select {
+		case <-s.stopChan:
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (s *Serial) read(f *os.File) error {
+	lines := bufio.NewScanner(f)
+	lines.Split(splitLines)
+	for lines.Scan() {
+		if code := strings.TrimSpace(lines.Text()); code != "" {
+			s.onScan(code)
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// splitLines is bufio.ScanLines accepting a lone CR as a line end too,
+// which is what most scanners send.
+func splitLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,90 @@
+// Package scanner reads barcode scans from hardware scanners: keyboard-wedge
+// scanners, picked out of typed input by a prefix and suffix, serial or
+// USB-CDC scanners read from a device, and cameras read through a capture
+// command. A Classifier sorts the scanned codes by kind.
+package scanner
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is the longest gap between the characters of one
+// keyboard-wedge scan. Scanners type far faster than people.
+const DefaultTimeout = 100 * time.Millisecond
+
+// Wedge picks keyboard-wedge scans out of typed input. The scanner must be
+// programmed to send Prefix before each code and Suffix after it, so a
+// scan is recognised whichever field has focus. Typed characters are fed
+// one at a time with Type; Enter is '\n'.
+type Wedge struct {
+	Prefix  string
+	Suffix  string // "" means Enter
+	Timeout time.Duration
+
+	held    []rune // prefix characters and code typed so far
+	matched bool   // the whole prefix has been seen
+	last    time.Time
+}
+
+// NewWedge returns a wedge decoder, or nil if prefix is empty: without one
+// scans can't be told apart from typing.
+func NewWedge(prefix, suffix string) *Wedge {
+	if prefix == "" {
+		return nil
+	}
+	return &Wedge{Prefix: prefix, Suffix: suffix, Timeout: DefaultTimeout}
+}
+
+// Type feeds one typed character. pass is what the focused field should
+// receive after all: the character itself if it isn't part of a scan, or
+// held-back characters that turned out not to be one. scan is set when
+// the character completes a code.
+func (w *Wedge) Type(r rune) (pass, scan string) {
+	now := time.Now()
+	if len(w.held) > 0 && now.Sub(w.last) > w.Timeout {
+		// Too slow to be the scanner, so someone typed it
+		pass = w.release()
+	}
+	w.last = now
+
+	if !w.matched {
+		candidate := string(append(w.held, r))
+		if strings.HasPrefix(w.Prefix, candidate) {
+			w.held = append(w.held, r)
+			w.matched = candidate == w.Prefix
+			return pass, ""
+		}
+		pass += w.release()
+		if strings.HasPrefix(w.Prefix, string(r)) {
+			w.held = []rune{r}
+			w.matched = string(r) == w.Prefix
+			return pass, ""
+		}
+		return pass + string(r), ""
+	}
+
+	w.held = append(w.held, r)
+	suffix := w.Suffix
+	if suffix == "" {
+		suffix = "\n"
+	}
+	text := string(w.held)
+	if strings.HasSuffix(text, suffix) {
+		code := strings.TrimSuffix(strings.TrimPrefix(text, w.Prefix), suffix)
+		w.held, w.matched = nil, false
+		return pass, strings.TrimSpace(code)
+	}
+	return pass, ""
+}
+
+// Scanning reports whether a scan has started and not yet finished.
+func (w *Wedge) Scanning() bool {
+	return len(w.held) > 0 && time.Since(w.last) <= w.Timeout
+}
+
+func (w *Wedge) release() string {
+	s := string(w.held)
+	w.held, w.matched = nil, false
+	return s
+}
@@ -0,0 +1,353 @@
+// Package server exposes the device's commit queue and local data to other
+// tools on the network as a small authenticated REST API, so they get the
+// same offline-first behaviour as the app:
+//
+//	GET  /api/v1/health              no authentication
+//	GET  /api/v1/stock?location=&item=
+//	POST /api/v1/commits             {"location", "item", "delta", "uom", "reference"}
+//	GET  /api/v1/queue
+//
+// Requests need an "Authorization: Bearer <token>" header.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/catalog"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/queue"
+	"github.com/larkin1/wmsproject/internal/stock"
+)
+
+// DefaultRefreshInterval is how often the caches are refreshed from the API.
+const DefaultRefreshInterval = time.Minute
+
+// maxBody caps request bodies.
+const maxBody = 1 << 20
+
+// Server serves the API. Set the exported fields before Start.
+type Server struct {
+	DeviceID        string // device_id for commits that don't name one
+	Site            string
+	CapacityPolicy  string // config.CapacityWarn, CapacityBlock or CapacityOff
+	RefreshInterval time.Duration
+
+	client  *api.Client
+	queue   *queue.Queue
+	token   string
+	catalog *catalog.Catalog
+
+	http     *http.Server
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates a server backed by the client's local store and the queue.
+func New(client *api.Client, q *queue.Queue, token string) *Server {
+	return &Server{
+		DeviceID:        config.DefaultDeviceID,
+		Site:            client.Site,
+		RefreshInterval: DefaultRefreshInterval,
+		client:          client,
+		queue:           q,
+		token:           token,
+		catalog:         catalog.New(),
+		stopChan:        make(chan struct{}),
+	}
+}
+
+// Handler returns the API's HTTP handler.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/health", s.handleHealth)
+	mux.Handle("/api/v1/stock", s.auth(http.HandlerFunc(s.handleStock)))
+	mux.Handle("/api/v1/commits", s.auth(http.HandlerFunc(s.handleCommits)))
+	mux.Handle("/api/v1/queue", s.auth(http.HandlerFunc(s.handleQueue)))
+	return mux
+}
+
+// Start listens on addr and serves in the background. It refuses to run
+// without a token.
+func (s *Server) Start(addr string) error {
+	if s.token == "" {
+		return errors.New("server_token must be set to start the API server")
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.catalog.Refresh(s.client)
+	s.client.FetchOverview()
+	s.http = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		log.Printf("[Server] Listening on %s\n", ln.Addr())
+		if err := s.http.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("[Server] Stopped: %v\n", err)
+		}
+	}()
+	go s.refresh()
+	return nil
+}
+
+// Stop shuts the server down, letting requests in flight finish.
+func (s *Server) Stop() {
+	close(s.stopChan)
+	if s.http != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.http.Shutdown(ctx)
+	}
+	s.wg.Wait()
+}
+
+// refresh keeps the caches current; offline, the fetches fall back to
+// the local store and nothing changes.
+func (s *Server) refresh() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.catalog.Refresh(s.client)
+			s.client.FetchOverview()
+		}
+	}
+}
+
+func (s *Server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="wms"`)
+			writeError(w, http.StatusUnauthorized, "missing or wrong bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// allow rejects requests with a method other than the given one.
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, r.Method+" not allowed")
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "site": s.Site})
+}
+
+// StockRow is one location and item, including commits not yet synced.
+type StockRow struct {
+	Location string `json:"location"`
+	ItemID   int    `json:"item_id"`
+	Item     string `json:"item"`
+	Qty      int    `json:"qty"`
+	Pending  int    `json:"pending,omitempty"`
+}
+
+// StockResponse is the body of GET /api/v1/stock.
+type StockResponse struct {
+	Rows      []StockRow `json:"rows"`
+	FetchedAt time.Time  `json:"fetched_at"`
+	Offline   bool       `json:"offline"`
+}
+
+func (s *Server) handleStock(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	location := r.URL.Query().Get("location")
+	itemID := 0
+	if arg := r.URL.Query().Get("item"); arg != "" {
+		item, ok := s.catalog.Find(arg)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("no item matches %q", arg))
+			return
+		}
+		itemID = item.ID
+	}
+
+	overview, err := s.client.Store.Overview()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "no stock levels cached yet")
+		return
+	}
+
+	type key struct {
+		location string
+		itemID   int
+	}
+	rows := make(map[key]*StockRow)
+	row := func(location string, itemID int) *StockRow {
+		k := key{location, itemID}
+		if rows[k] == nil {
+			item, _ := s.catalog.Item(itemID)
+			rows[k] = &StockRow{Location: location, ItemID: itemID, Item: item.Name}
+		}
+		return rows[k]
+	}
+	for _, level := range overview {
+		row(level.Location, level.ItemID).Qty += level.Qty
+	}
+	for _, commit := range s.queue.Pending() {
+		r := row(commit.Location, commit.ItemID)
+		r.Qty += commit.Delta
+		r.Pending += commit.Delta
+	}
+
+	freshness := s.client.Freshness("overview")
+	resp := StockResponse{Rows: []StockRow{}, FetchedAt: freshness.FetchedAt, Offline: freshness.Offline()}
+	for _, r := range rows {
+		if (location == "" || r.Location == location) && (itemID == 0 || r.ItemID == itemID) {
+			resp.Rows = append(resp.Rows, *r)
+		}
+	}
+	sort.Slice(resp.Rows, func(i, j int) bool {
+		if resp.Rows[i].Location != resp.Rows[j].Location {
+			return resp.Rows[i].Location < resp.Rows[j].Location
+		}
+		return resp.Rows[i].ItemID < resp.Rows[j].ItemID
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CommitRequest is the body of POST /api/v1/commits. Item is an ID,
+// barcode or exact name; Delta is in UoM, or the item's base unit.
+type CommitRequest struct {
+	Location  string          `json:"location"`
+	Item      json.RawMessage `json:"item"`
+	Delta     int             `json:"delta"`
+	UoM       string          `json:"uom,omitempty"`
+	Reference string          `json:"reference,omitempty"`
+	DeviceID  string          `json:"device_id,omitempty"`
+}
+
+// CommitResponse is the body of a successful POST /api/v1/commits.
+type CommitResponse struct {
+	Commit   queue.Commit `json:"commit"`
+	Pending  int          `json:"pending"`
+	Warnings []string     `json:"warnings,omitempty"`
+}
+
+func (s *Server) handleCommits(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+
+	var req CommitRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	req.Location = strings.TrimSpace(req.Location)
+	if req.Location == "" || len(req.Item) == 0 || req.Delta == 0 {
+		writeError(w, http.StatusUnprocessableEntity, "location, item and a non-zero delta are required")
+		return
+	}
+
+	// Items may be sent as a number or a string
+	arg := strings.Trim(string(req.Item), `"`)
+	item, ok := s.catalog.Find(arg)
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("no item matches %q", arg))
+		return
+	}
+	delta, err := item.ToBaseUnits(req.UoM, req.Delta)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	commit := queue.Commit{
+		DeviceID:  req.DeviceID,
+		Location:  req.Location,
+		Delta:     delta,
+		ItemID:    item.ID,
+		UoM:       req.UoM,
+		UoMQty:    req.Delta,
+		Site:      s.Site,
+		Reference: req.Reference,
+	}
+	if commit.DeviceID == "" {
+		commit.DeviceID = s.DeviceID
+	}
+	if req.UoM == "" {
+		commit.UoMQty = 0
+	}
+
+	var warnings []string
+	loc, known := s.catalog.Location(commit.Location)
+	if !known {
+		warnings = append(warnings, fmt.Sprintf("location %s is not known yet", commit.Location))
+	} else if delta > 0 && s.CapacityPolicy != config.CapacityOff {
+		overview, _ := s.client.Store.Overview()
+		if err := stock.NewLevels(overview, s.queue.Pending()).CheckCapacity(loc, item.ID, delta); err != nil {
+			if s.CapacityPolicy == config.CapacityBlock {
+				writeError(w, http.StatusConflict, err.Error())
+				return
+			}
+			warnings = append(warnings, err.Error())
+		}
+	}
+
+	s.queue.Submit(commit)
+	log.Printf("[Server] Queued %+d x item %d at %s from %s\n", commit.Delta, commit.ItemID, commit.Location, r.RemoteAddr)
+	writeJSON(w, http.StatusAccepted, CommitResponse{Commit: commit, Pending: len(s.queue.Pending()), Warnings: warnings})
+}
+
+// QueueResponse is the body of GET /api/v1/queue.
+type QueueResponse struct {
+	Pending int            `json:"pending"`
+	Commits []queue.Commit `json:"commits"`
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	commits := append([]queue.Commit{}, s.queue.Pending()...)
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit >= 0 && limit < len(commits) {
+		commits = commits[:limit]
+	}
+	writeJSON(w, http.StatusOK, QueueResponse{Pending: len(s.queue.Pending()), Commits: commits})
+}
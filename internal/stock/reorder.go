@@ -0,0 +1,56 @@
+package stock
+
+import (
+	"sort"
+
+	"github.com/larkin1/wmsproject/internal/api"
+)
+
+// Shortage is an item whose on-hand quantity is below its reorder point.
+type Shortage struct {
+	Item    api.Item
+	OnHand  int
+	Reorder int // suggested quantity to bring the item back up to its max
+}
+
+// BelowReorderPoint reports whether qty is under the item's minimum level.
+func BelowReorderPoint(item api.Item, qty int) bool {
+	return item.MinQty > 0 && qty < item.MinQty
+}
+
+// ReorderQty suggests how many units to order to restore the item's level.
+func ReorderQty(item api.Item, qty int) int {
+	target := item.MaxQty
+	if target < item.MinQty {
+		target = item.MinQty
+	}
+	if qty >= target {
+		return 0
+	}
+	return target - qty
+}
+
+// LowStock lists items below their reorder point, largest shortfall first.
+func LowStock(items []api.Item, levels *Levels) []Shortage {
+	var shortages []Shortage
+	for _, item := range items {
+		onHand := levels.Item(item.ID)
+		if BelowReorderPoint(item, onHand) {
+			shortages = append(shortages, Shortage{
+				Item:    item,
+				OnHand:  onHand,
+				Reorder: ReorderQty(item, onHand),
+			})
+		}
+	}
+
+	sort.Slice(shortages, func(i, j int) bool {
+		si := shortages[i].Item.MinQty - shortages[i].OnHand
+		sj := shortages[j].Item.MinQty - shortages[j].OnHand
+		if si != sj {
+			return si > sj
+		}
+		return shortages[i].Item.Name < shortages[j].Item.Name
+	})
+	return shortages
+}
@@ -0,0 +1,149 @@
+package stock
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/queue"
+)
+
+// Levels is on-hand stock from the overview view plus commits made on this
+// device that haven't synced yet.
+type Levels struct {
+	qty      map[string]map[int]int // location -> item ID -> qty
+	reserved map[string]map[int]int
+}
+
+func NewLevels(rows []api.StockLevel, pending []queue.Commit) *Levels {
+	l := &Levels{
+		qty:      make(map[string]map[int]int),
+		reserved: make(map[string]map[int]int),
+	}
+	for _, row := range rows {
+		add(l.qty, row.Location, row.ItemID, row.Qty)
+	}
+	for _, commit := range pending {
+		add(l.qty, commit.Location, commit.ItemID, commit.Delta)
+	}
+	return l
+}
+
+func add(m map[string]map[int]int, location string, itemID, qty int) {
+	if m[location] == nil {
+		m[location] = make(map[int]int)
+	}
+	m[location][itemID] += qty
+}
+
+// Reserve holds back stock for reservations still active at time now.
+func (l *Levels) Reserve(reservations []api.Reservation, now time.Time) {
+	for _, r := range reservations {
+		if r.Active(now) {
+			add(l.reserved, r.Location, r.ItemID, r.Qty)
+		}
+	}
+}
+
+// Reserved returns the quantity of an item held at a location.
+func (l *Levels) Reserved(location string, itemID int) int {
+	return l.reserved[location][itemID]
+}
+
+// Available returns the available-to-promise quantity at a location:
+// on-hand minus reserved.
+func (l *Levels) Available(location string, itemID int) int {
+	return l.At(location, itemID) - l.Reserved(location, itemID)
+}
+
+// ReservedItem returns the quantity of an item held across all locations.
+func (l *Levels) ReservedItem(itemID int) int {
+	total := 0
+	for _, items := range l.reserved {
+		total += items[itemID]
+	}
+	return total
+}
+
+// AvailableItem returns the available-to-promise quantity of an item across
+// all locations.
+func (l *Levels) AvailableItem(itemID int) int {
+	return l.Item(itemID) - l.ReservedItem(itemID)
+}
+
+// At returns the quantity of an item at a location.
+func (l *Levels) At(location string, itemID int) int {
+	return l.qty[location][itemID]
+}
+
+// Total returns the quantity of all items at a location.
+func (l *Levels) Total(location string) int {
+	total := 0
+	for _, qty := range l.qty[location] {
+		total += qty
+	}
+	return total
+}
+
+// Item returns the quantity of an item across all locations.
+func (l *Levels) Item(itemID int) int {
+	total := 0
+	for _, items := range l.qty {
+		total += items[itemID]
+	}
+	return total
+}
+
+// Free returns how many more units of the item fit in the location, taking
+// the tighter of the per-item and whole-location limits.
+func (l *Levels) Free(loc api.Location, itemID int) (free int, limited bool) {
+	if limit, ok := loc.CapacityFor(itemID); ok {
+		free, limited = limit-l.At(loc.LocationName, itemID), true
+	}
+	if loc.Capacity > 0 {
+		locFree := loc.Capacity - l.Total(loc.LocationName)
+		if !limited || locFree < free {
+			free, limited = locFree, true
+		}
+	}
+	return free, limited
+}
+
+// FreeFunc adapts Free to look locations up by code, for the put-away engine.
+func (l *Levels) FreeFunc(locations []api.Location) func(string, int) (int, bool) {
+	byCode := make(map[string]api.Location)
+	for _, loc := range locations {
+		byCode[loc.LocationName] = loc
+	}
+	return func(code string, itemID int) (int, bool) {
+		loc, ok := byCode[code]
+		if !ok {
+			return 0, false
+		}
+		return l.Free(loc, itemID)
+	}
+}
+
+// CapacityError reports an ADD that would overfill a location.
+type CapacityError struct {
+	Location string
+	Free     int
+	Adding   int
+}
+
+func (e *CapacityError) Error() string {
+	if e.Free <= 0 {
+		return fmt.Sprintf("location %s is full (adding %d)", e.Location, e.Adding)
+	}
+	return fmt.Sprintf("location %s only has room for %d more (adding %d)", e.Location, e.Free, e.Adding)
+}
+
+// CheckCapacity returns a *CapacityError if adding qty units of the item
+// would exceed the location's capacity.
+func (l *Levels) CheckCapacity(loc api.Location, itemID, qty int) error {
+	free, limited := l.Free(loc, itemID)
+	if !limited || qty <= free {
+		return nil
+	}
+	return &CapacityError{Location: loc.LocationName, Free: free, Adding: qty}
+}
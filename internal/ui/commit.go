@@ -1,32 +1,38 @@
 package ui
 
 import (
-	"encoding/csv"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/catalog"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/putaway"
 	"github.com/larkin1/wmsproject/internal/queue"
+	"github.com/larkin1/wmsproject/internal/scanner"
+	"github.com/larkin1/wmsproject/internal/stock"
 )
 
 type CommitUI struct {
 	widget.BaseWidget
 
-	scannerInput  *widget.Entry
+	scannerInput  *scanEntry
+	cameraBtn     *widget.Button
 	locationLabel *widget.Label
-	deltaInput    *widget.Entry
+	deltaInput    *scanEntry
+	unitSelect    *widget.Select
 	toggleBtn     *widget.Button
 	commitBtn     *widget.Button
 	changeItemBtn *widget.Button
+	suggestions   *fyne.Container
+	offline       *widget.Label
 	error         *widget.RichText
 
 	mode      string
@@ -35,22 +41,34 @@ type CommitUI struct {
 	locations map[string][]int
 	items     map[string]int
 	items_r   map[int]string
+	itemDefs  map[int]api.Item
+	locDefs   []api.Location
 
-	api       *api.Client
-	queue     *queue.Queue
-	basePath  string
-	window    fyne.Window // Store the window for dialogs
+	refreshing   bool             // a background location sync is running
+	putawayStock []api.StockLevel // on-hand stock put-away suggestions are based on
+
+	api      *api.Client
+	queue    *queue.Queue
+	deviceID string
+	window   fyne.Window // Store the window for dialogs
+	putaway  *putaway.Engine
+	catalog  *catalog.Catalog
+	camera   *scanner.Camera
+
+	capacityPolicy string
 }
 
-func NewCommitUI(apiClient *api.Client, commitQueue *queue.Queue, basePath string) *CommitUI {
+func NewCommitUI(apiClient *api.Client, commitQueue *queue.Queue, deviceID string) *CommitUI {
 	c := &CommitUI{
 		api:       apiClient,
 		queue:     commitQueue,
-		basePath:  basePath,
+		deviceID:  deviceID,
 		mode:      "ADD",
 		items:     make(map[string]int),
 		items_r:   make(map[int]string),
+		itemDefs:  make(map[int]api.Item),
 		locations: make(map[string][]int),
+		catalog:   catalog.New(),
 	}
 
 	return c
@@ -58,115 +76,191 @@ func NewCommitUI(apiClient *api.Client, commitQueue *queue.Queue, basePath strin
 
 func (c *CommitUI) loadItems() {
 	log.Println("[CommitUI] loadItems() called")
-	// Clear old data
-	c.items = make(map[string]int)
-	c.items_r = make(map[int]string)
-
-	itemsCSV := filepath.Join(c.basePath, "items.csv")
-	log.Printf("[CommitUI] Loading items from CSV: %s\n", itemsCSV)
 
-	// Always try to fetch fresh data from API
-	err := c.api.ExportItemsToCSV(itemsCSV)
+	// FetchItems falls back to the local store when offline
+	itemsData, err := c.api.FetchItems()
+	c.updateOfflineBanner()
 	if err != nil {
-		log.Printf("[CommitUI] ExportItemsToCSV error: %v (will use cached JSON)\n", err)
-		// Try to load from cache instead
-		c.loadItemsFromCache()
+		log.Printf("[CommitUI] FetchItems error: %v\n", err)
 		return
 	}
 
-	log.Println("[CommitUI] ExportItemsToCSV succeeded")
+	c.setItems(itemsData)
+	log.Printf("[CommitUI] Total items loaded: %d\n", len(c.items))
+}
 
-	// Load from CSV (fresh from API)
-	if !c.loadItemsFromCSV(itemsCSV) {
-		log.Println("[CommitUI] CSV load failed, trying cache")
-		c.loadItemsFromCache()
+func (c *CommitUI) setItems(itemsData []api.Item) {
+	c.catalog.SetItems(itemsData)
+	c.items = make(map[string]int)
+	c.items_r = make(map[int]string)
+	// Keep full definitions for unit of measure conversion
+	c.itemDefs = make(map[int]api.Item)
+	for _, item := range itemsData {
+		c.items[item.Name] = item.ID
+		c.items_r[item.ID] = item.Name
+		c.itemDefs[item.ID] = item
 	}
 }
 
-func (c *CommitUI) loadItemsFromCSV(itemsCSV string) bool {
-	file, err := os.Open(itemsCSV)
+func (c *CommitUI) loadLocations() {
+	log.Println("[CommitUI] loadLocations() called")
+	locationsData, err := c.api.FetchLocations()
+	c.updateOfflineBanner()
 	if err != nil {
-		log.Printf("[CommitUI] Cannot open items.csv: %v\n", err)
-		return false
+		log.Printf("[CommitUI] FetchLocations error: %v\n", err)
+		return
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		log.Printf("[CommitUI] CSV read error: %v\n", err)
-		return false
+	c.setLocations(locationsData)
+	log.Printf("[CommitUI] Total locations loaded: %d\n", len(c.locations))
+}
+
+func (c *CommitUI) setLocations(locationsData []api.Location) {
+	c.catalog.SetLocations(locationsData)
+	c.locDefs = locationsData
+	c.locations = make(map[string][]int)
+	for _, loc := range locationsData {
+		c.locations[loc.LocationName] = loc.Items
 	}
+}
 
-	log.Printf("[CommitUI] CSV has %d records (including header)\n", len(records))
+// refreshLocations syncs locations in the background so scans don't wait on the network
+func (c *CommitUI) refreshLocations() {
+	if c.refreshing {
+		return
+	}
+	c.refreshing = true
+
+	go func() {
+		locationsData, err := c.api.FetchLocations()
+		fyne.Do(func() {
+			c.refreshing = false
+			c.updateOfflineBanner()
+			if err != nil {
+				log.Printf("[CommitUI] Background FetchLocations error: %v\n", err)
+				return
+			}
+			c.setLocations(locationsData)
+			log.Printf("[CommitUI] Background sync: %d locations\n", len(c.locations))
+		})
+	}()
+}
 
-	if len(records) == 0 {
-		log.Println("[CommitUI] CSV is empty")
-		return false
+// updateOfflineBanner shows when items or locations were served from the cache
+func (c *CommitUI) updateOfflineBanner() {
+	if c.offline == nil {
+		return
 	}
 
-	for i, record := range records {
-		if i == 0 {
-			log.Printf("[CommitUI] Header: %v\n", record)
-			continue // skip header
-		}
-		if len(record) < 2 {
-			log.Printf("[CommitUI] Record %d has %d fields, skipping\n", i, len(record))
-			continue
-		}
-		id, err := strconv.Atoi(strings.TrimSpace(record[0]))
-		if err != nil {
-			log.Printf("[CommitUI] Cannot parse ID '%s': %v\n", record[0], err)
-			continue
-		}
-		name := strings.TrimSpace(record[1])
-		if name != "" {
-			c.items[name] = id
-			c.items_r[id] = name
-			log.Printf("[CommitUI] Loaded item: %s (ID: %d)\n", name, id)
+	var oldest time.Time
+	for _, table := range []string{"items", "locations"} {
+		f := c.api.Freshness(table)
+		if f.Offline() && (oldest.IsZero() || f.FetchedAt.Before(oldest)) {
+			oldest = f.FetchedAt
 		}
 	}
 
-	log.Printf("[CommitUI] Total items loaded from CSV: %d\n", len(c.items))
-	return len(c.items) > 0
+	if oldest.IsZero() {
+		c.offline.Hide()
+		return
+	}
+	msg := "Offline data from " + oldest.Format("Mon 2 Jan 15:04")
+	if c.api.MaxCacheAge > 0 && time.Since(oldest) > c.api.MaxCacheAge {
+		msg += " is too old to use - reconnect to refresh"
+	}
+	c.offline.SetText(msg)
+	c.offline.Show()
 }
 
-func (c *CommitUI) loadItemsFromCache() {
-	log.Println("[CommitUI] loadItemsFromCache() called")
-	cachePath := filepath.Join(c.basePath, "items.cache.json")
-
-	data, err := os.ReadFile(cachePath)
+func (c *CommitUI) onScanned(text string) {
+	log.Printf("[CommitUI] onScanned: '%s'\n", text)
+	scan, err := scanRules.Classify(text)
 	if err != nil {
-		log.Printf("[CommitUI] Cache not found: %v\n", err)
+		c.setError(err.Error())
 		return
 	}
 
-	// Minimal parsing of cache JSON
-	// Instead of full unmarshal, we'll use the API method that already handles this
-	log.Printf("[CommitUI] Cache file exists (%d bytes), will reload items from API\n", len(data))
+	switch scan.Kind {
+	case config.ScanBadge:
+		signIn(scan.Value)
+		c.setError("Signed in as " + scan.Value)
+	case config.ScanCommand:
+		c.runCommand(scan.Value)
+	case config.ScanItem:
+		c.scanItem(scan.Value)
+	case config.ScanLocation:
+		c.scanLocation(scan.Value)
+	default:
+		// Without rules, a known item barcode is taken as an item rather
+		// than a new location
+		if _, ok := c.locations[scan.Value]; !ok {
+			if _, ok := c.catalog.ItemByBarcode(scan.Value); ok {
+				c.scanItem(scan.Value)
+				return
+			}
+		}
+		c.scanLocation(scan.Value)
+	}
 }
 
-func (c *CommitUI) loadLocations() {
-	log.Println("[CommitUI] loadLocations() called")
-	locationsData, err := c.api.FetchLocations()
-	if err != nil {
-		log.Printf("[CommitUI] FetchLocations error: %v\n", err)
+// scanItem makes a scanned item current. Scanned before a location, it is
+// kept for the location scanned next.
+func (c *CommitUI) scanItem(code string) {
+	item, ok := c.catalog.Find(code)
+	if !ok {
+		c.setError(fmt.Sprintf("Unknown item '%s'", code))
 		return
 	}
-
-	c.locations = make(map[string][]int)
-	for _, loc := range locationsData {
-		c.locations[loc.LocationName] = loc.Items
-		log.Printf("[CommitUI] Loaded location: %s with items %v\n", loc.LocationName, loc.Items)
+	log.Printf("[CommitUI] Item scanned: %d\n", item.ID)
+	c.itemID = item.ID
+	if c.location == "" {
+		c.updateUnits()
+		c.showPutaway()
+		c.setError(fmt.Sprintf("Item %s - scan a location", item.Name))
+		return
 	}
+	c.updateLocationLabel()
+}
 
-	log.Printf("[CommitUI] Total locations loaded: %d\n", len(c.locations))
+// runCommand carries out a scanned command: ADD or SUB to set the mode,
+// COMMIT, or CLEAR to start over.
+func (c *CommitUI) runCommand(command string) {
+	log.Printf("[CommitUI] Command scanned: %s\n", command)
+	switch strings.ToUpper(command) {
+	case "ADD", "SUB":
+		c.setMode(strings.ToUpper(command))
+	case "COMMIT":
+		c.commit()
+	case "CLEAR":
+		c.location = ""
+		c.itemID = 0
+		c.deltaInput.SetText("")
+		c.locationLabel.SetText("Location: (waiting for scan)")
+		c.updateUnits()
+		c.showPutaway()
+		c.setError("")
+	default:
+		c.setError(fmt.Sprintf("Unknown command '%s'", command))
+	}
 }
 
-func (c *CommitUI) onScanned(text string) {
-	log.Printf("[CommitUI] onScanned: '%s'\n", text)
-	c.location = strings.TrimSpace(text)
-	c.loadLocations()
+func (c *CommitUI) scanLocation(code string) {
+	// An item scanned before any location stays selected
+	itemFirst := c.location == "" && c.itemID != 0
+	c.location = code
+	if _, ok := c.locations[c.location]; ok {
+		// Resolve against the local cache and sync in the background
+		c.refreshLocations()
+	} else {
+		// Unknown locally, so sync now in case it was just created
+		c.loadLocations()
+	}
+
+	if itemFirst {
+		log.Printf("[CommitUI] Keeping scanned item %d for %s\n", c.itemID, c.location)
+		c.updateLocationLabel()
+		return
+	}
 
 	if itemIDs, ok := c.locations[c.location]; ok {
 		log.Printf("[CommitUI] Location found with items: %v\n", itemIDs)
@@ -196,6 +290,11 @@ func (c *CommitUI) onScanned(text string) {
 	c.updateLocationLabel()
 }
 
+// Scanned takes a scan from a hardware scanner as if typed into the scanner field
+func (c *CommitUI) Scanned(code string) {
+	c.onScanned(code)
+}
+
 func (c *CommitUI) updateLocationLabel() {
 	if c.location != "" {
 		itemName := c.items_r[c.itemID]
@@ -205,15 +304,105 @@ func (c *CommitUI) updateLocationLabel() {
 		c.locationLabel.SetText(fmt.Sprintf("Location: %s\nItem: %s", c.location, itemName))
 		c.setError("")
 	}
+	c.updateUnits()
+	c.showPutaway()
+}
+
+// showPutaway offers put-away locations for the current item in ADD mode;
+// tapping one makes it the current location. Stock comes from the stored
+// overview, which realtime updates keep current, so scans never wait on
+// the network.
+func (c *CommitUI) showPutaway() {
+	if c.suggestions == nil {
+		return
+	}
+	c.putawayStock = nil
+	if c.putaway != nil && c.mode == "ADD" && c.itemID != 0 {
+		rows, err := c.api.Store.Overview()
+		if err != nil {
+			log.Printf("[CommitUI] No stored overview: %v (suggesting without stock)\n", err)
+		}
+		c.putawayStock = rows
+	}
+	c.suggestPutaway()
+}
+
+// suggestPutaway lists put-away locations for the quantity entered, using
+// the stock read by showPutaway, so it is cheap enough to re-run as the
+// quantity or unit changes.
+func (c *CommitUI) suggestPutaway() {
+	if c.suggestions == nil {
+		return
+	}
+	c.suggestions.RemoveAll()
+	if c.putaway == nil || c.mode != "ADD" || c.itemID == 0 {
+		return
+	}
+
+	rows := c.putawayStock
+	levels := stock.NewLevels(rows, c.queue.Pending())
+
+	qty, _ := strconv.Atoi(strings.TrimSpace(c.deltaInput.Text))
+	if item, ok := c.itemDefs[c.itemID]; ok {
+		if base, err := item.ToBaseUnits(c.unitSelect.Selected, qty); err == nil {
+			qty = base
+		}
+	}
+
+	engine := c.putaway.WithFree(levels.FreeFunc(c.locDefs))
+	suggested := engine.Suggest(c.itemID, qty, rows, c.locDefs, 4)
+	log.Printf("[CommitUI] %d put-away suggestions for item %d\n", len(suggested), c.itemID)
+	if len(suggested) == 0 {
+		return
+	}
+
+	c.suggestions.Add(widget.NewLabel("Put away:"))
+	for _, s := range suggested {
+		if s.Location == c.location {
+			continue
+		}
+		code := s.Location
+		btn := widget.NewButton(fmt.Sprintf("%s (%s)", code, s.Reason), func() {
+			log.Printf("[CommitUI] Put-away suggestion accepted: %s\n", code)
+			c.location = code
+			c.updateLocationLabel()
+		})
+		c.suggestions.Add(btn)
+	}
+}
+
+// SetPutaway sets the engine used to suggest put-away locations
+func (c *CommitUI) SetPutaway(engine *putaway.Engine) {
+	c.putaway = engine
+}
+
+// updateUnits refreshes the unit selector for the current item
+func (c *CommitUI) updateUnits() {
+	if c.unitSelect == nil {
+		return
+	}
+
+	item, ok := c.itemDefs[c.itemID]
+	if !ok {
+		item = api.Item{ID: c.itemID}
+	}
+
+	c.unitSelect.Options = item.UnitNames()
+	c.unitSelect.SetSelected(item.Base())
 }
 
 func (c *CommitUI) toggleMode() {
 	if c.mode == "ADD" {
-		c.mode = "SUB"
+		c.setMode("SUB")
 	} else {
-		c.mode = "ADD"
+		c.setMode("ADD")
 	}
+}
+
+func (c *CommitUI) setMode(mode string) {
+	c.mode = mode
 	c.toggleBtn.SetText("Mode: " + c.mode)
+	c.showPutaway()
 }
 
 func (c *CommitUI) commit() {
@@ -228,47 +417,95 @@ func (c *CommitUI) commit() {
 		return
 	}
 
+	item, ok := c.itemDefs[c.itemID]
+	if !ok {
+		item = api.Item{ID: c.itemID}
+	}
+
+	unit := c.unitSelect.Selected
+	delta, err := item.ToBaseUnits(unit, qty)
+	if err != nil {
+		c.setError(err.Error())
+		return
+	}
+
 	if c.mode == "SUB" {
 		qty = -qty
+		delta = -delta
 	}
 
-	log.Printf("[CommitUI] Submitting commit: location=%s, itemID=%d, qty=%d\n", c.location, c.itemID, qty)
-	c.queue.SubmitCommit("TOUGHPAD01", c.location, qty, c.itemID)
-	c.deltaInput.SetText("")
-	c.setError("")
-}
+	commit := queue.Commit{
+		DeviceID: c.deviceID,
+		Location: c.location,
+		Delta:    delta,
+		ItemID:   c.itemID,
+		UoM:      unit,
+		UoMQty:   qty,
+		Site:     c.api.Site,
+		Operator: operator,
+	}
 
-func (c *CommitUI) setError(msg string) {
-	log.Printf("[CommitUI] setError: %s\n", msg)
-	if msg == "" {
-		c.error.ParseMarkdown("")
-	} else {
-		c.error.ParseMarkdown("**Status:** " + msg)
+	if c.mode == "ADD" && c.capacityPolicy != config.CapacityOff {
+		if err := c.checkCapacity(commit); err != nil {
+			if c.capacityPolicy == config.CapacityBlock {
+				c.setError(err.Error())
+				return
+			}
+			dialog.ShowConfirm("Over capacity", err.Error()+"\nCommit anyway?", func(ok bool) {
+				if ok {
+					c.submit(commit)
+				}
+			}, c.window)
+			return
+		}
 	}
+
+	c.submit(commit)
 }
 
-// fuzzyMatch checks if query matches name with fuzzy matching
-func (c *CommitUI) fuzzyMatch(query, name string) bool {
-	query = strings.ToLower(strings.TrimSpace(query))
-	name = strings.ToLower(name)
+func (c *CommitUI) submit(commit queue.Commit) {
+	log.Printf("[CommitUI] Submitting commit: location=%s, itemID=%d, qty=%d %s (delta=%d)\n", commit.Location, commit.ItemID, commit.UoMQty, commit.UoM, commit.Delta)
+	c.queue.Submit(commit)
+	c.deltaInput.SetText("")
+	c.setError("")
 
-	if query == "" {
-		return true // empty query matches everything
+	if msg := checkReorderPoint(c.api, c.queue, c.itemDefs[commit.ItemID], commit); msg != "" {
+		c.setError("Low stock - " + msg)
 	}
+}
 
-	// Exact match
-	if strings.Contains(name, query) {
-		return true
+// stockLevels combines the stored overview with commits still waiting in
+// the queue, so checking a commit never waits on the network.
+func (c *CommitUI) stockLevels() *stock.Levels {
+	rows, err := c.api.Store.Overview()
+	if err != nil {
+		log.Printf("[CommitUI] No stored overview: %v (using pending commits only)\n", err)
 	}
+	return stock.NewLevels(rows, c.queue.Pending())
+}
 
-	// Fuzzy: check if all query chars appear in name in order
-	queryIdx := 0
-	for _, char := range name {
-		if queryIdx < len(query) && char == rune(query[queryIdx]) {
-			queryIdx++
+// checkCapacity returns an error if the commit would overfill its location
+func (c *CommitUI) checkCapacity(commit queue.Commit) error {
+	for _, loc := range c.locDefs {
+		if loc.LocationName == commit.Location {
+			return c.stockLevels().CheckCapacity(loc, commit.ItemID, commit.Delta)
 		}
 	}
-	return queryIdx == len(query)
+	return nil
+}
+
+// SetCapacityPolicy sets how over-capacity ADD commits are handled
+func (c *CommitUI) SetCapacityPolicy(policy string) {
+	c.capacityPolicy = policy
+}
+
+func (c *CommitUI) setError(msg string) {
+	log.Printf("[CommitUI] setError: %s\n", msg)
+	if msg == "" {
+		c.error.ParseMarkdown("")
+	} else {
+		c.error.ParseMarkdown("**Status:** " + msg)
+	}
 }
 
 func (c *CommitUI) showItemSelectDialog(itemIDs []int) {
@@ -317,17 +554,20 @@ func (c *CommitUI) showItemSelectDialog(itemIDs []int) {
 	log.Println("[CommitUI] Dialog shown")
 }
 
+// searchLimit caps how many ranked matches the item search offers
+const searchLimit = 50
+
 func (c *CommitUI) showItemSearch() {
 	log.Println("[CommitUI] showItemSearch called")
-	// Ensure items are loaded
-	c.loadItems()
+	// Search the in-memory catalog; only load if it is still empty
+	if n, _ := c.catalog.Len(); n == 0 {
+		c.loadItems()
+	}
 
-	// Build sorted list of item names
 	var itemNames []string
-	for name := range c.items {
-		itemNames = append(itemNames, name)
+	for _, item := range c.catalog.Items() {
+		itemNames = append(itemNames, item.Name)
 	}
-	sort.Strings(itemNames)
 
 	log.Printf("[CommitUI] showItemSearch: found %d items\n", len(itemNames))
 
@@ -338,27 +578,25 @@ func (c *CommitUI) showItemSearch() {
 	}
 
 	// Create search entry
-	searchEntry := widget.NewEntry()
+	searchEntry := newScanEntry()
 	searchEntry.SetPlaceHolder("Type to search...")
 
 	// Create select widget (will be filtered)
 	selectWidget := widget.NewSelect(itemNames, func(value string) {
 		log.Printf("[CommitUI] Item selected from search: %s\n", value)
-		if id, ok := c.items[value]; ok {
-			c.itemID = id
+		if item, ok := c.catalog.ItemByName(value); ok {
+			c.itemID = item.ID
 			log.Printf("[CommitUI] Item ID set to: %d\n", c.itemID)
 			c.updateLocationLabel()
 		}
 	})
 	selectWidget.PlaceHolder = "Search results..."
 
-	// Update select options when search changes
+	// Update select options with ranked matches on name, ID or barcode
 	searchEntry.OnChanged = func(s string) {
 		var filtered []string
-		for _, name := range itemNames {
-			if c.fuzzyMatch(s, name) {
-				filtered = append(filtered, name)
-			}
+		for _, m := range c.catalog.Search(s, searchLimit) {
+			filtered = append(filtered, m.Item.Name)
 		}
 		log.Printf("[CommitUI] Search '%s' filtered to %d items\n", s, len(filtered))
 		selectWidget.Options = filtered
@@ -390,17 +628,36 @@ func (c *CommitUI) CreateRenderer() fyne.WidgetRenderer {
 	c.loadItems()
 	c.loadLocations()
 
-	c.scannerInput = widget.NewEntry()
-	c.scannerInput.SetPlaceHolder("Scan location code...")
+	c.scannerInput = newScanEntry()
+	c.scannerInput.SetPlaceHolder("Scan location or item code...")
 	c.scannerInput.OnSubmitted = func(s string) {
 		c.onScanned(s)
 		c.scannerInput.SetText("")
 	}
 
+	c.cameraBtn = widget.NewButton("Camera", func() {
+		scanCamera(c.window, c.camera, c.onScanned, c.setError)
+	})
+	if c.camera == nil {
+		c.cameraBtn.Hide()
+	}
+	imageBtn := widget.NewButton("Scan Image...", func() {
+		scanImageFile(c.window, c.onScanned, c.setError)
+	})
+
 	c.locationLabel = widget.NewLabel("Location: (waiting for scan)")
 
-	c.deltaInput = widget.NewEntry()
+	c.deltaInput = newScanEntry()
 	c.deltaInput.SetPlaceHolder("Enter quantity")
+	// Suggestions depend on how much is being put away
+	c.deltaInput.OnChanged = func(string) {
+		c.suggestPutaway()
+	}
+
+	c.unitSelect = widget.NewSelect([]string{api.DefaultBaseUnit}, func(string) {
+		c.suggestPutaway()
+	})
+	c.unitSelect.SetSelected(api.DefaultBaseUnit)
 
 	c.toggleBtn = widget.NewButton("Mode: ADD", func() {
 		c.toggleMode()
@@ -416,6 +673,11 @@ func (c *CommitUI) CreateRenderer() fyne.WidgetRenderer {
 	})
 
 	c.error = widget.NewRichTextFromMarkdown("")
+	c.suggestions = container.NewVBox()
+	c.offline = widget.NewLabel("")
+	c.offline.Importance = widget.WarningImportance
+	c.offline.Hide()
+	c.updateOfflineBanner()
 
 	buttons := container.NewHBox(
 		c.toggleBtn,
@@ -424,9 +686,11 @@ func (c *CommitUI) CreateRenderer() fyne.WidgetRenderer {
 	)
 
 	vbox := container.NewVBox(
-		c.scannerInput,
+		c.offline,
+		container.NewBorder(nil, nil, nil, container.NewHBox(c.cameraBtn, imageBtn), c.scannerInput),
 		c.locationLabel,
-		c.deltaInput,
+		c.suggestions,
+		container.NewBorder(nil, nil, nil, c.unitSelect, c.deltaInput),
 		buttons,
 		c.error,
 	)
@@ -435,6 +699,49 @@ func (c *CommitUI) CreateRenderer() fyne.WidgetRenderer {
 	return widget.NewSimpleRenderer(vbox)
 }
 
+// Reload refreshes items or locations from the local caches after a realtime change
+func (c *CommitUI) Reload(table string) {
+	switch table {
+	case "items":
+		items, err := c.api.Store.Items()
+		if err != nil {
+			return
+		}
+		c.setItems(items)
+	case "locations":
+		locationsData, err := c.api.Store.Locations()
+		if err != nil {
+			return
+		}
+		c.setLocations(locationsData)
+	default:
+		return
+	}
+
+	log.Printf("[CommitUI] Reloaded %s from cache\n", table)
+	if c.locationLabel != nil && c.location != "" {
+		c.updateLocationLabel()
+	}
+}
+
+// SetCatalog shares the app-wide catalog instead of the screen's own
+func (c *CommitUI) SetCatalog(cat *catalog.Catalog) {
+	c.catalog = cat
+}
+
+// SetCamera enables scanning with a camera; nil disables it
+func (c *CommitUI) SetCamera(cam *scanner.Camera) {
+	c.camera = cam
+	if c.cameraBtn == nil {
+		return
+	}
+	if cam == nil {
+		c.cameraBtn.Hide()
+	} else {
+		c.cameraBtn.Show()
+	}
+}
+
 // SetWindow allows main to pass the window reference
 func (c *CommitUI) SetWindow(w fyne.Window) {
 	log.Printf("[CommitUI] SetWindow called, window is nil: %v\n", w == nil)
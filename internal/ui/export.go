@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/export"
+)
+
+// ExportUI saves items, locations, stock or commit history to a CSV,
+// JSON Lines or XLSX file.
+type ExportUI struct {
+	widget.BaseWidget
+
+	dataSelect    *widget.Select
+	formatSelect  *widget.Select
+	fromInput     *widget.Entry
+	toInput       *widget.Entry
+	locationInput *widget.Entry
+	exportBtn     *widget.Button
+	backBtn       *widget.Button
+	error         *widget.RichText
+
+	api    *api.Client
+	window fyne.Window
+	onBack func()
+}
+
+func NewExportUI(apiClient *api.Client, onBack func()) *ExportUI {
+	e := &ExportUI{
+		api:    apiClient,
+		onBack: onBack,
+	}
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+// SetWindow sets the window the save dialog opens over
+func (e *ExportUI) SetWindow(w fyne.Window) {
+	e.window = w
+}
+
+func (e *ExportUI) filter() (export.Filter, error) {
+	f := export.Filter{Location: e.locationInput.Text}
+	var err error
+	if f.From, err = export.ParseDate(e.fromInput.Text); err != nil {
+		return f, err
+	}
+	if f.To, err = export.ParseDate(e.toInput.Text); err != nil {
+		return f, err
+	}
+	if !f.To.IsZero() {
+		f.To = f.To.AddDate(0, 0, 1) // include the whole day
+	}
+	return f, nil
+}
+
+func (e *ExportUI) export() {
+	dataset, format := e.dataSelect.Selected, e.formatSelect.Selected
+	f, err := e.filter()
+	if err != nil {
+		e.setError(err.Error())
+		return
+	}
+
+	table, err := export.Fetch(e.api, dataset, f)
+	if err != nil {
+		log.Printf("[ExportUI] Fetch %s error: %v\n", dataset, err)
+		e.setError(fmt.Sprintf("Could not load %s: %v", dataset, err))
+		return
+	}
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if err := export.Write(writer, format, table); err != nil {
+			log.Printf("[ExportUI] Write error: %v\n", err)
+			e.setError("Export failed: " + err.Error())
+			return
+		}
+		log.Printf("[ExportUI] Exported %d %s rows to %s\n", len(table.Rows), dataset, writer.URI())
+		e.setError(fmt.Sprintf("Exported %d rows to %s", len(table.Rows), writer.URI().Name()))
+	}, e.window)
+	save.SetFileName(dataset + "." + format)
+	save.SetFilter(storage.NewExtensionFileFilter([]string{"." + format}))
+	save.Show()
+}
+
+func (e *ExportUI) setError(msg string) {
+	if e.error == nil {
+		return
+	}
+	if msg == "" {
+		e.error.ParseMarkdown("")
+	} else {
+		e.error.ParseMarkdown("**Status:** " + msg)
+	}
+}
+
+func (e *ExportUI) CreateRenderer() fyne.WidgetRenderer {
+	log.Println("[ExportUI] CreateRenderer called")
+	e.error = widget.NewRichTextFromMarkdown("")
+
+	e.fromInput = widget.NewEntry()
+	e.fromInput.SetPlaceHolder("YYYY-MM-DD")
+	e.toInput = widget.NewEntry()
+	e.toInput.SetPlaceHolder("YYYY-MM-DD")
+	e.locationInput = widget.NewEntry()
+	e.locationInput.SetPlaceHolder("All locations")
+
+	e.dataSelect = widget.NewSelect(export.Datasets, func(dataset string) {
+		// Dates only apply to commit history
+		if dataset == export.Commits {
+			e.fromInput.Enable()
+			e.toInput.Enable()
+		} else {
+			e.fromInput.Disable()
+			e.toInput.Disable()
+		}
+	})
+	e.dataSelect.SetSelected(export.Stock)
+
+	e.formatSelect = widget.NewSelect(export.Formats, nil)
+	e.formatSelect.SetSelected(export.CSV)
+
+	e.exportBtn = widget.NewButton("Export...", func() {
+		e.export()
+	})
+	e.exportBtn.Importance = widget.HighImportance
+
+	e.backBtn = widget.NewButton("Back", func() {
+		e.onBack()
+	})
+
+	title := widget.NewLabel("Export")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Data", e.dataSelect),
+		widget.NewFormItem("Format", e.formatSelect),
+		widget.NewFormItem("From", e.fromInput),
+		widget.NewFormItem("To", e.toInput),
+		widget.NewFormItem("Location", e.locationInput),
+	)
+
+	vbox := container.NewVBox(
+		title,
+		form,
+		container.NewHBox(e.exportBtn, e.backBtn),
+		e.error,
+	)
+
+	return widget.NewSimpleRenderer(vbox)
+}
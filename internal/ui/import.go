@@ -0,0 +1,245 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/importer"
+)
+
+// ImportUI bulk-imports items, locations and opening stock from CSV files:
+// choose the files, preview the changes and problems, then import.
+type ImportUI struct {
+	widget.BaseWidget
+
+	fileLabels map[string]*widget.Label
+	results    *widget.List
+	progress   *widget.ProgressBar
+	previewBtn *widget.Button
+	importBtn  *widget.Button
+	backBtn    *widget.Button
+	error      *widget.RichText
+
+	files map[string][]byte // file kind -> contents
+	names map[string]string // file kind -> file name
+	plan  *importer.Plan
+	lines []string
+
+	api      *api.Client
+	deviceID string
+	window   fyne.Window
+	onBack   func()
+}
+
+func NewImportUI(apiClient *api.Client, deviceID string, onBack func()) *ImportUI {
+	i := &ImportUI{
+		api:        apiClient,
+		deviceID:   deviceID,
+		onBack:     onBack,
+		files:      make(map[string][]byte),
+		names:      make(map[string]string),
+		fileLabels: make(map[string]*widget.Label),
+	}
+	i.ExtendBaseWidget(i)
+	return i
+}
+
+// SetWindow sets the window the file dialogs open over
+func (i *ImportUI) SetWindow(w fyne.Window) {
+	i.window = w
+}
+
+func (i *ImportUI) chooseFile(kind string) {
+	open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			i.setError(fmt.Sprintf("Cannot read %s: %v", reader.URI().Name(), err))
+			return
+		}
+		i.files[kind] = data
+		i.names[kind] = reader.URI().Name()
+		i.fileLabels[kind].SetText(reader.URI().Name())
+		log.Printf("[ImportUI] Selected %s file %s (%d bytes)\n", kind, reader.URI().Name(), len(data))
+
+		// A new file invalidates the previous preview
+		i.plan = nil
+		i.lines = nil
+		i.results.Refresh()
+		i.importBtn.Disable()
+	}, i.window)
+	open.SetFilter(storage.NewExtensionFileFilter([]string{".csv"}))
+	open.Show()
+}
+
+// preview parses the files and diffs them against the database
+func (i *ImportUI) preview() {
+	if len(i.files) == 0 {
+		i.setError("Choose at least one file")
+		return
+	}
+
+	in := &importer.Input{}
+	if data, ok := i.files[importer.ItemsFile]; ok {
+		in.ReadItems(bytes.NewReader(data))
+	}
+	if data, ok := i.files[importer.LocationsFile]; ok {
+		in.ReadLocations(bytes.NewReader(data))
+	}
+	if data, ok := i.files[importer.StockFile]; ok {
+		in.ReadStock(bytes.NewReader(data))
+	}
+
+	cur, err := importer.FetchCurrent(i.api)
+	if err != nil {
+		log.Printf("[ImportUI] FetchCurrent error: %v\n", err)
+		i.setError(err.Error())
+		return
+	}
+
+	i.plan = importer.NewPlan(in, cur)
+	i.lines = nil
+	for _, p := range i.plan.Problems {
+		i.lines = append(i.lines, "Problem: "+p.Error())
+	}
+	for _, c := range i.plan.Changes {
+		i.lines = append(i.lines, fmt.Sprintf("%s %s %s: %s", c.Action, c.File, c.Key, c.Detail))
+	}
+	i.results.Refresh()
+
+	summary := fmt.Sprintf("%d changes, %d unchanged, %d problems", len(i.plan.Changes), i.plan.Unchanged, len(i.plan.Problems))
+	log.Printf("[ImportUI] Preview: %s\n", summary)
+	switch {
+	case !i.plan.OK():
+		i.setError(summary + " - fix the files to import")
+		i.importBtn.Disable()
+	case i.plan.Empty():
+		i.setError("Nothing to import - " + summary)
+		i.importBtn.Disable()
+	default:
+		i.setError(summary)
+		i.importBtn.Enable()
+	}
+}
+
+// apply writes the previewed plan in the background
+func (i *ImportUI) apply() {
+	plan := i.plan
+	if plan == nil || !plan.OK() {
+		return
+	}
+
+	i.importBtn.Disable()
+	i.previewBtn.Disable()
+	i.progress.SetValue(0)
+	i.progress.Show()
+
+	go func() {
+		err := plan.Apply(i.api, i.deviceID, func(done, total int) {
+			fyne.Do(func() {
+				i.progress.SetValue(float64(done) / float64(total))
+			})
+		})
+
+		fyne.Do(func() {
+			i.previewBtn.Enable()
+			i.plan = nil
+			if err != nil {
+				log.Printf("[ImportUI] Import error: %v\n", err)
+				i.setError("Import failed: " + err.Error() + " - preview again to retry")
+				return
+			}
+			i.setError(fmt.Sprintf("Imported %d changes", len(plan.Changes)))
+		})
+	}()
+}
+
+func (i *ImportUI) setError(msg string) {
+	if i.error == nil {
+		return
+	}
+	if msg == "" {
+		i.error.ParseMarkdown("")
+	} else {
+		i.error.ParseMarkdown("**Status:** " + msg)
+	}
+}
+
+func (i *ImportUI) CreateRenderer() fyne.WidgetRenderer {
+	log.Println("[ImportUI] CreateRenderer called")
+	i.error = widget.NewRichTextFromMarkdown("")
+
+	files := container.NewVBox()
+	for _, f := range []struct{ kind, title, columns string }{
+		{importer.ItemsFile, "Items", "id, name [, base_unit, min_qty, max_qty, barcodes]"},
+		{importer.LocationsFile, "Locations", "location, items [, capacity]"},
+		{importer.StockFile, "Opening stock", "location, item_id, qty"},
+	} {
+		kind := f.kind
+		i.fileLabels[kind] = widget.NewLabel("(none)")
+		choose := widget.NewButton("Choose...", func() {
+			i.chooseFile(kind)
+		})
+		files.Add(container.NewBorder(nil, nil,
+			widget.NewLabelWithStyle(f.title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), choose,
+			container.NewVBox(i.fileLabels[kind], widget.NewLabel(f.columns))))
+	}
+
+	i.results = widget.NewList(
+		func() int {
+			return len(i.lines)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(i.lines[id])
+		},
+	)
+
+	i.progress = widget.NewProgressBar()
+	i.progress.Hide()
+
+	i.previewBtn = widget.NewButton("Preview", func() {
+		i.preview()
+	})
+
+	i.importBtn = widget.NewButton("Import", func() {
+		dialog.ShowConfirm("Import", fmt.Sprintf("Write %d changes to the database?", len(i.plan.Changes)), func(ok bool) {
+			if ok {
+				i.apply()
+			}
+		}, i.window)
+	})
+	i.importBtn.Importance = widget.HighImportance
+	i.importBtn.Disable()
+
+	i.backBtn = widget.NewButton("Back", func() {
+		i.onBack()
+	})
+
+	title := widget.NewLabel("Import CSV")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	content := container.NewBorder(
+		container.NewVBox(title, files, container.NewHBox(i.previewBtn, i.importBtn, i.backBtn), i.progress, i.error),
+		nil,
+		nil,
+		nil,
+		i.results,
+	)
+
+	return widget.NewSimpleRenderer(content)
+}
@@ -0,0 +1,276 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/label"
+)
+
+// LabelUI prints location and item labels to a Zebra printer or saves
+// them as ZPL or a PDF sheet.
+type LabelUI struct {
+	widget.BaseWidget
+
+	kindSelect     *widget.Select
+	filterInput    *widget.Entry
+	templateSelect *widget.Select
+	list           *widget.List
+	printBtn       *widget.Button
+	zplBtn         *widget.Button
+	pdfBtn         *widget.Button
+	backBtn        *widget.Button
+	error          *widget.RichText
+
+	labels []label.Label
+
+	api       *api.Client
+	printer   string
+	dpi       int
+	templates []config.LabelTemplate
+	window    fyne.Window
+	onBack    func()
+}
+
+func NewLabelUI(apiClient *api.Client, onBack func()) *LabelUI {
+	l := &LabelUI{
+		api:       apiClient,
+		templates: label.Templates,
+		onBack:    onBack,
+	}
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+// SetWindow sets the window the save dialogs open over
+func (l *LabelUI) SetWindow(w fyne.Window) {
+	l.window = w
+}
+
+// SetPrinter enables printing to a Zebra printer's raw port at the given resolution
+func (l *LabelUI) SetPrinter(addr string, dpi int) {
+	l.printer = addr
+	l.dpi = dpi
+}
+
+// SetTemplates adds custom label templates to the built-in ones
+func (l *LabelUI) SetTemplates(custom []config.LabelTemplate) {
+	l.templates = label.AllTemplates(custom)
+}
+
+func (l *LabelUI) load() {
+	labels, err := label.Fetch(l.api, l.kindSelect.Selected, l.filterInput.Text)
+	if err != nil {
+		log.Printf("[LabelUI] Fetch error: %v\n", err)
+		l.setError("Could not load " + l.kindSelect.Selected)
+		return
+	}
+	l.labels = labels
+	l.list.Refresh()
+	l.setError(fmt.Sprintf("%d labels", len(labels)))
+}
+
+func (l *LabelUI) template() (config.LabelTemplate, error) {
+	for _, t := range l.templates {
+		if t.Name == l.templateSelect.Selected {
+			return t, nil
+		}
+	}
+	return config.LabelTemplate{}, fmt.Errorf("choose a template")
+}
+
+func (l *LabelUI) print() {
+	t, err := l.template()
+	if err != nil {
+		l.setError(err.Error())
+		return
+	}
+	zpl, err := label.ZPL(l.labels, t, l.dpi)
+	if err != nil {
+		l.setError(err.Error())
+		return
+	}
+
+	l.setError(fmt.Sprintf("Printing %d labels...", len(l.labels)))
+	count := len(l.labels)
+	go func() {
+		err := label.Send(l.printer, zpl)
+		fyne.Do(func() {
+			if err != nil {
+				log.Printf("[LabelUI] Print error: %v\n", err)
+				l.setError("Printer unreachable: " + err.Error())
+				return
+			}
+			log.Printf("[LabelUI] Sent %d labels to %s\n", count, l.printer)
+			l.setError(fmt.Sprintf("Sent %d labels to %s", count, l.printer))
+		})
+	}()
+}
+
+// save renders the labels as ZPL or PDF to a file the user picks.
+func (l *LabelUI) save(ext string) {
+	t, err := l.template()
+	if err != nil {
+		l.setError(err.Error())
+		return
+	}
+
+	var data []byte
+	if ext == ".pdf" {
+		doc, err := label.PDF(l.labels, t)
+		if err != nil {
+			l.setError(err.Error())
+			return
+		}
+		var b bytes.Buffer
+		if _, err := doc.WriteTo(&b); err != nil {
+			l.setError(err.Error())
+			return
+		}
+		data = b.Bytes()
+	} else {
+		if data, err = label.ZPL(l.labels, t, l.dpi); err != nil {
+			l.setError(err.Error())
+			return
+		}
+	}
+
+	count := len(l.labels)
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if _, err := writer.Write(data); err != nil {
+			log.Printf("[LabelUI] Save error: %v\n", err)
+			l.setError("Save failed: " + err.Error())
+			return
+		}
+		l.setError(fmt.Sprintf("Saved %d labels to %s", count, writer.URI().Name()))
+	}, l.window)
+	save.SetFileName(l.kindSelect.Selected + "-labels" + ext)
+	save.SetFilter(storage.NewExtensionFileFilter([]string{ext}))
+	save.Show()
+}
+
+func (l *LabelUI) updateButtons() {
+	if len(l.labels) == 0 {
+		l.printBtn.Disable()
+		l.zplBtn.Disable()
+		l.pdfBtn.Disable()
+		return
+	}
+	if l.printer == "" {
+		l.printBtn.Disable()
+	} else {
+		l.printBtn.Enable()
+	}
+	l.zplBtn.Enable()
+	l.pdfBtn.Enable()
+}
+
+func (l *LabelUI) setError(msg string) {
+	if l.error == nil {
+		return
+	}
+	if msg == "" {
+		l.error.ParseMarkdown("")
+	} else {
+		l.error.ParseMarkdown("**Status:** " + msg)
+	}
+}
+
+func (l *LabelUI) CreateRenderer() fyne.WidgetRenderer {
+	log.Println("[LabelUI] CreateRenderer called")
+	l.error = widget.NewRichTextFromMarkdown("")
+
+	l.printBtn = widget.NewButton("Print", func() {
+		l.print()
+	})
+	l.printBtn.Importance = widget.HighImportance
+	l.zplBtn = widget.NewButton("Save ZPL...", func() {
+		l.save(".zpl")
+	})
+	l.pdfBtn = widget.NewButton("Save PDF...", func() {
+		l.save(".pdf")
+	})
+	l.backBtn = widget.NewButton("Back", func() {
+		l.onBack()
+	})
+
+	l.list = widget.NewList(
+		func() int {
+			return len(l.labels)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			lb := l.labels[id]
+			text := lb.Name
+			if lb.Code != lb.Name {
+				text += " (" + lb.Code + ")"
+			}
+			obj.(*widget.Label).SetText(text)
+		},
+	)
+
+	var names []string
+	for _, t := range l.templates {
+		names = append(names, t.Name)
+	}
+	l.templateSelect = widget.NewSelect(names, nil)
+
+	l.filterInput = widget.NewEntry()
+	l.filterInput.OnSubmitted = func(string) {
+		l.load()
+		l.updateButtons()
+	}
+
+	l.kindSelect = widget.NewSelect(label.Kinds, func(kind string) {
+		if kind == label.Locations {
+			l.filterInput.SetPlaceHolder("Location code prefix")
+		} else {
+			l.filterInput.SetPlaceHolder("Search items")
+		}
+		// Default to the kind's built-in template
+		l.templateSelect.SetSelected(strings.TrimSuffix(kind, "s"))
+		l.load()
+		l.updateButtons()
+	})
+	l.kindSelect.SetSelected(label.Locations)
+
+	title := widget.NewLabel("Labels")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Labels for", l.kindSelect),
+		widget.NewFormItem("Filter", l.filterInput),
+		widget.NewFormItem("Template", l.templateSelect),
+	)
+
+	printer := "No label printer set"
+	if l.printer != "" {
+		printer = "Printer: " + l.printer
+	}
+
+	content := container.NewBorder(
+		container.NewVBox(title, form, widget.NewLabel(printer), l.error),
+		container.NewHBox(l.printBtn, l.zplBtn, l.pdfBtn, l.backBtn),
+		nil,
+		nil,
+		l.list,
+	)
+
+	return widget.NewSimpleRenderer(content)
+}
@@ -0,0 +1,258 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/location"
+)
+
+// LocationBrowser shows locations as a site/zone/aisle/rack/bin tree
+// with stock totals for the selected branch.
+type LocationBrowser struct {
+	widget.BaseWidget
+
+	tree     *widget.Tree
+	details  *widget.Label
+	groupBy  *widget.Select
+	totals   *widget.Label
+	backBtn  *widget.Button
+	errLabel *widget.RichText
+
+	locations    *location.Tree
+	overview     []api.StockLevel
+	reservations []api.Reservation
+	itemNames    map[int]string
+
+	api    *api.Client
+	parser *location.Parser
+	onBack func()
+}
+
+func NewLocationBrowser(apiClient *api.Client, parser *location.Parser, onBack func()) *LocationBrowser {
+	b := &LocationBrowser{
+		api:       apiClient,
+		parser:    parser,
+		onBack:    onBack,
+		itemNames: make(map[int]string),
+	}
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+func (b *LocationBrowser) load() {
+	log.Println("[LocationBrowser] load() called")
+	locationsData, err := b.api.FetchLocations()
+	if err != nil {
+		log.Printf("[LocationBrowser] FetchLocations error: %v\n", err)
+		b.setError("Could not load locations")
+	}
+
+	itemsData, err := b.api.FetchItems()
+	if err != nil {
+		log.Printf("[LocationBrowser] FetchItems error: %v\n", err)
+	}
+
+	overview, err := b.api.FetchOverview()
+	if err != nil {
+		log.Printf("[LocationBrowser] FetchOverview error: %v\n", err)
+		b.setError("Stock totals unavailable offline")
+	}
+
+	b.reservations, err = b.api.FetchReservations()
+	if err != nil {
+		log.Printf("[LocationBrowser] FetchReservations error: %v\n", err)
+	}
+
+	b.build(locationsData, itemsData, overview)
+}
+
+// Reload rebuilds the tree from the local caches after a realtime change
+func (b *LocationBrowser) Reload(table string) {
+	locationsData, _ := b.api.Store.Locations()
+	itemsData, _ := b.api.Store.Items()
+	overview, _ := b.api.Store.Overview()
+	b.build(locationsData, itemsData, overview)
+
+	log.Printf("[LocationBrowser] Reloaded after %s change\n", table)
+	if b.tree != nil {
+		b.tree.Refresh()
+	}
+}
+
+func (b *LocationBrowser) build(locationsData []api.Location, itemsData []api.Item, overview []api.StockLevel) {
+	var codes []string
+	for _, loc := range locationsData {
+		codes = append(codes, loc.LocationName)
+	}
+
+	for _, item := range itemsData {
+		b.itemNames[item.ID] = item.Name
+	}
+
+	b.overview = overview
+
+	// Include locations that only appear in the overview
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		seen[code] = true
+	}
+	for _, row := range b.overview {
+		if !seen[row.Location] {
+			seen[row.Location] = true
+			codes = append(codes, row.Location)
+		}
+	}
+
+	b.locations = location.BuildTree(b.parser, codes)
+	log.Printf("[LocationBrowser] Loaded %d locations, %d overview rows\n", len(codes), len(b.overview))
+}
+
+func (b *LocationBrowser) itemName(id int) string {
+	if name, ok := b.itemNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("ID: %d", id)
+}
+
+func (b *LocationBrowser) showNode(id string) {
+	node := b.locations.Node(id)
+	if node == nil {
+		return
+	}
+
+	codes := b.locations.Codes(id)
+	inNode := make(map[string]bool)
+	for _, code := range codes {
+		inNode[code] = true
+	}
+
+	qty := make(map[int]int)
+	var itemIDs []int
+	for _, row := range b.overview {
+		if !inNode[row.Location] {
+			continue
+		}
+		if _, ok := qty[row.ItemID]; !ok {
+			itemIDs = append(itemIDs, row.ItemID)
+		}
+		qty[row.ItemID] += row.Qty
+	}
+
+	reserved := make(map[int]int)
+	now := time.Now()
+	for _, r := range b.reservations {
+		if inNode[r.Location] && r.Active(now) {
+			reserved[r.ItemID] += r.Qty
+		}
+	}
+
+	var lines []string
+	header := node.Name
+	if node.Level != "" {
+		header = fmt.Sprintf("%s%s %s", strings.ToUpper(node.Level[:1]), node.Level[1:], node.Name)
+	}
+	lines = append(lines, fmt.Sprintf("%s (%d locations)", header, len(codes)))
+	for _, itemID := range itemIDs {
+		line := fmt.Sprintf("  %s: %d", b.itemName(itemID), qty[itemID])
+		if reserved[itemID] > 0 {
+			line += fmt.Sprintf(" (%d reserved, %d available)", reserved[itemID], qty[itemID]-reserved[itemID])
+		}
+		lines = append(lines, line)
+	}
+	if len(itemIDs) == 0 {
+		lines = append(lines, "  No stock")
+	}
+	b.details.SetText(strings.Join(lines, "\n"))
+}
+
+func (b *LocationBrowser) showTotals(level string) {
+	var lines []string
+	group := ""
+	for _, total := range location.Aggregate(b.parser, b.overview, level) {
+		if total.Group != group {
+			group = total.Group
+			lines = append(lines, group)
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %d", b.itemName(total.ItemID), total.Qty))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No stock")
+	}
+	b.totals.SetText(strings.Join(lines, "\n"))
+}
+
+func (b *LocationBrowser) setError(msg string) {
+	if b.errLabel == nil {
+		return
+	}
+	if msg == "" {
+		b.errLabel.ParseMarkdown("")
+	} else {
+		b.errLabel.ParseMarkdown("**Status:** " + msg)
+	}
+}
+
+func (b *LocationBrowser) CreateRenderer() fyne.WidgetRenderer {
+	log.Println("[LocationBrowser] CreateRenderer called")
+	b.errLabel = widget.NewRichTextFromMarkdown("")
+	b.load()
+
+	b.details = widget.NewLabel("Select a location")
+	b.totals = widget.NewLabel("")
+
+	b.tree = widget.NewTree(
+		func(id widget.TreeNodeID) []widget.TreeNodeID {
+			return b.locations.Children(id)
+		},
+		func(id widget.TreeNodeID) bool {
+			return b.locations.IsBranch(id)
+		},
+		func(branch bool) fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.TreeNodeID, branch bool, obj fyne.CanvasObject) {
+			if node := b.locations.Node(id); node != nil {
+				obj.(*widget.Label).SetText(node.Name)
+			}
+		},
+	)
+	b.tree.OnSelected = func(id widget.TreeNodeID) {
+		b.showNode(id)
+	}
+
+	b.groupBy = widget.NewSelect(b.parser.Levels(), func(level string) {
+		b.showTotals(level)
+	})
+	b.groupBy.PlaceHolder = "Totals by level..."
+
+	b.backBtn = widget.NewButton("Back", func() {
+		b.onBack()
+	})
+
+	title := widget.NewLabel("Locations")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	summary := container.NewVScroll(container.NewVBox(
+		b.details,
+		widget.NewSeparator(),
+		b.groupBy,
+		b.totals,
+	))
+
+	content := container.NewBorder(
+		container.NewVBox(title, b.errLabel),
+		b.backBtn,
+		nil,
+		nil,
+		container.NewVSplit(b.tree, summary),
+	)
+
+	return widget.NewSimpleRenderer(content)
+}
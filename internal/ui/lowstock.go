@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/queue"
+	"github.com/larkin1/wmsproject/internal/stock"
+)
+
+// LowStockUI lists items below their reorder point.
+type LowStockUI struct {
+	widget.BaseWidget
+
+	list       *widget.List
+	refreshBtn *widget.Button
+	backBtn    *widget.Button
+	error      *widget.RichText
+
+	shortages    []stock.Shortage
+	levels       *stock.Levels
+	reservations []api.Reservation
+
+	api    *api.Client
+	queue  *queue.Queue
+	onBack func()
+}
+
+func NewLowStockUI(apiClient *api.Client, commitQueue *queue.Queue, onBack func()) *LowStockUI {
+	l := &LowStockUI{
+		api:    apiClient,
+		queue:  commitQueue,
+		onBack: onBack,
+	}
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+func (l *LowStockUI) load() {
+	log.Println("[LowStockUI] load() called")
+	l.setError("")
+
+	items, err := l.api.FetchItems()
+	if err != nil {
+		log.Printf("[LowStockUI] FetchItems error: %v\n", err)
+		l.setError("Could not load items")
+		return
+	}
+
+	rows, err := l.api.FetchOverview()
+	if err != nil {
+		log.Printf("[LowStockUI] FetchOverview error: %v\n", err)
+		l.setError("Stock levels unavailable offline")
+		return
+	}
+
+	reservations, err := l.api.FetchReservations()
+	if err != nil {
+		log.Printf("[LowStockUI] FetchReservations error: %v\n", err)
+	}
+
+	l.compute(items, rows, reservations)
+}
+
+// Reload recomputes shortages from the local caches after a realtime change
+func (l *LowStockUI) Reload(table string) {
+	if table != "items" && table != "commits" {
+		return
+	}
+
+	items, err := l.api.Store.Items()
+	if err != nil {
+		return
+	}
+	rows, err := l.api.Store.Overview()
+	if err != nil {
+		return
+	}
+
+	l.compute(items, rows, l.reservations)
+	if l.list != nil {
+		l.list.Refresh()
+	}
+}
+
+func (l *LowStockUI) compute(items []api.Item, rows []api.StockLevel, reservations []api.Reservation) {
+	l.reservations = reservations
+	l.levels = stock.NewLevels(rows, l.queue.Pending())
+	l.levels.Reserve(reservations, time.Now())
+
+	l.shortages = stock.LowStock(items, l.levels)
+	log.Printf("[LowStockUI] %d items below reorder point\n", len(l.shortages))
+	if len(l.shortages) == 0 {
+		l.setError("No items below their reorder point")
+	} else {
+		l.setError("")
+	}
+}
+
+func (l *LowStockUI) setError(msg string) {
+	if l.error == nil {
+		return
+	}
+	if msg == "" {
+		l.error.ParseMarkdown("")
+	} else {
+		l.error.ParseMarkdown("**Status:** " + msg)
+	}
+}
+
+func (l *LowStockUI) CreateRenderer() fyne.WidgetRenderer {
+	log.Println("[LowStockUI] CreateRenderer called")
+	l.error = widget.NewRichTextFromMarkdown("")
+	l.load()
+
+	l.list = widget.NewList(
+		func() int {
+			return len(l.shortages)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			s := l.shortages[id]
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s: %d on hand, %d available (min %d), reorder %d %s",
+				s.Item.Name, s.OnHand, l.levels.AvailableItem(s.Item.ID), s.Item.MinQty, s.Reorder, s.Item.Base()))
+		},
+	)
+
+	l.refreshBtn = widget.NewButton("Refresh", func() {
+		l.load()
+		l.list.Refresh()
+	})
+
+	l.backBtn = widget.NewButton("Back", func() {
+		l.onBack()
+	})
+
+	title := widget.NewLabel("Low Stock")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	content := container.NewBorder(
+		container.NewVBox(title, l.error),
+		container.NewHBox(l.refreshBtn, l.backBtn),
+		nil,
+		nil,
+		l.list,
+	)
+
+	return widget.NewSimpleRenderer(content)
+}
+
+// checkReorderPoint notifies when a SUB commit just queued on this device
+// takes its item below the reorder point, returning the message, or ""
+// when it doesn't.
+func checkReorderPoint(client *api.Client, q *queue.Queue, item api.Item, commit queue.Commit) string {
+	if commit.Delta >= 0 || item.MinQty == 0 {
+		return ""
+	}
+
+	// Levels include the commit just queued. The stored overview keeps
+	// this off the network, so picks and commits never wait on it.
+	rows, err := client.Store.Overview()
+	if err != nil {
+		log.Printf("[LowStock] No stored overview: %v (using pending commits only)\n", err)
+	}
+	after := stock.NewLevels(rows, q.Pending()).Item(commit.ItemID)
+	before := after - commit.Delta
+	if stock.BelowReorderPoint(item, before) || !stock.BelowReorderPoint(item, after) {
+		return ""
+	}
+
+	msg := fmt.Sprintf("%s is down to %d (reorder point %d)", item.Name, after, item.MinQty)
+	log.Printf("[LowStock] Low stock: %s\n", msg)
+	fyne.CurrentApp().SendNotification(fyne.NewNotification("Low stock", msg))
+	return msg
+}
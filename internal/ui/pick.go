@@ -0,0 +1,347 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/catalog"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/location"
+	"github.com/larkin1/wmsproject/internal/picking"
+	"github.com/larkin1/wmsproject/internal/queue"
+)
+
+// PickUI guides a picker through an open pick list: scan the location,
+// scan the item, confirm the quantity, and a negative commit is queued.
+type PickUI struct {
+	widget.BaseWidget
+
+	listSelect *widget.Select
+	stepLabel  *widget.Label
+	scanInput  *scanEntry
+	qtyInput   *scanEntry
+	confirmBtn *widget.Button
+	skipBtn    *widget.Button
+	backBtn    *widget.Button
+	error      *widget.RichText
+
+	lists     []api.PickList
+	itemNames map[int]string
+	itemDefs  map[int]api.Item
+	catalog   *catalog.Catalog
+	session   *picking.Session
+	line      *picking.Line
+	stage     string // "location", "item" or "qty"
+
+	api      *api.Client
+	queue    *queue.Queue
+	parser   *location.Parser
+	progress *picking.Progress
+	deviceID string
+	onBack   func()
+}
+
+func NewPickUI(apiClient *api.Client, commitQueue *queue.Queue, parser *location.Parser, deviceID, basePath string, onBack func()) *PickUI {
+	p := &PickUI{
+		api:       apiClient,
+		queue:     commitQueue,
+		parser:    parser,
+		progress:  picking.LoadProgress(basePath),
+		deviceID:  deviceID,
+		onBack:    onBack,
+		itemNames: make(map[int]string),
+		itemDefs:  make(map[int]api.Item),
+		catalog:   catalog.New(),
+	}
+	p.ExtendBaseWidget(p)
+	return p
+}
+
+func (p *PickUI) load() {
+	log.Println("[PickUI] load() called")
+	itemsData, err := p.api.FetchItems()
+	if err != nil {
+		log.Printf("[PickUI] FetchItems error: %v\n", err)
+	} else {
+		p.catalog.SetItems(itemsData)
+	}
+	for _, item := range itemsData {
+		p.itemNames[item.ID] = item.Name
+		p.itemDefs[item.ID] = item
+	}
+
+	lists, err := p.api.FetchPickLists()
+	if err != nil {
+		log.Printf("[PickUI] FetchPickLists error: %v\n", err)
+		p.setError("Could not load pick lists")
+		return
+	}
+
+	p.lists = nil
+	for _, list := range lists {
+		if !p.progress.Complete(list) {
+			p.lists = append(p.lists, list)
+		}
+	}
+	log.Printf("[PickUI] %d open pick lists\n", len(p.lists))
+}
+
+func (p *PickUI) listLabel(list api.PickList) string {
+	return fmt.Sprintf("%s (%d lines)", list.Reference, len(list.Lines))
+}
+
+func (p *PickUI) itemName(id int) string {
+	if name, ok := p.itemNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("ID: %d", id)
+}
+
+func (p *PickUI) selectList(label string) {
+	for _, list := range p.lists {
+		if p.listLabel(list) == label {
+			log.Printf("[PickUI] Starting pick list %s\n", list.Reference)
+			p.session = picking.NewSession(list, p.parser, p.progress)
+			p.nextLine()
+			return
+		}
+	}
+}
+
+func (p *PickUI) nextLine() {
+	p.line = p.session.Current()
+	p.scanInput.SetText("")
+	p.qtyInput.SetText("")
+	p.confirmBtn.Disable()
+
+	if p.line == nil {
+		p.stepLabel.SetText(fmt.Sprintf("Pick list %s complete", p.session.List.Reference))
+		p.scanInput.Disable()
+		p.skipBtn.Disable()
+		return
+	}
+
+	p.stage = "location"
+	p.scanInput.Enable()
+	p.skipBtn.Enable()
+	p.scanInput.SetPlaceHolder("Scan location...")
+	p.updateStep()
+}
+
+func (p *PickUI) updateStep() {
+	done := len(p.session.Lines) - p.session.Remaining()
+	p.stepLabel.SetText(fmt.Sprintf("Line %d of %d\nGo to: %s\nPick: %d x %s",
+		done+1, len(p.session.Lines), p.line.Location, p.line.Qty, p.itemName(p.line.ItemID)))
+}
+
+func (p *PickUI) onScanned(text string) {
+	text = strings.TrimSpace(text)
+	log.Printf("[PickUI] onScanned: '%s' (stage %s)\n", text, p.stage)
+	if p.line == nil {
+		return
+	}
+
+	var want string
+	switch p.stage {
+	case "location":
+		want = config.ScanLocation
+	case "item":
+		want = config.ScanItem
+	default:
+		return
+	}
+	text, msg, ok := scanFor(text, want)
+	if !ok {
+		p.setError(msg)
+		return
+	}
+
+	switch p.stage {
+	case "location":
+		if text != p.line.Location {
+			p.setError(fmt.Sprintf("Wrong location '%s', expected %s", text, p.line.Location))
+			return
+		}
+		p.stage = "item"
+		p.scanInput.SetPlaceHolder("Scan item...")
+		p.setError("")
+	case "item":
+		if !p.matchesItem(text) {
+			p.setError(fmt.Sprintf("Wrong item '%s', expected %s", text, p.itemName(p.line.ItemID)))
+			return
+		}
+		p.stage = "qty"
+		p.scanInput.SetPlaceHolder("Confirm quantity below")
+		p.qtyInput.SetText(strconv.Itoa(p.line.Qty))
+		p.confirmBtn.Enable()
+		p.setError("")
+	}
+}
+
+// Scanned takes a scan from a hardware scanner for the current step
+func (p *PickUI) Scanned(code string) {
+	if p.scanInput == nil || p.scanInput.Disabled() {
+		return
+	}
+	p.onScanned(code)
+}
+
+// matchesItem reports whether a scanned ID, barcode or name is the
+// current line's item.
+func (p *PickUI) matchesItem(code string) bool {
+	if code == strconv.Itoa(p.line.ItemID) {
+		return true
+	}
+	item, ok := p.catalog.Find(code)
+	return ok && item.ID == p.line.ItemID
+}
+
+// SetCatalog shares the app-wide catalog instead of the screen's own
+func (p *PickUI) SetCatalog(cat *catalog.Catalog) {
+	p.catalog = cat
+}
+
+func (p *PickUI) confirm() {
+	if p.line == nil || p.stage != "qty" {
+		return
+	}
+
+	picked, err := strconv.Atoi(strings.TrimSpace(p.qtyInput.Text))
+	if err != nil || picked < 0 {
+		p.setError("Invalid number")
+		return
+	}
+	if picked > p.line.Qty {
+		p.setError(fmt.Sprintf("Cannot pick more than %d", p.line.Qty))
+		return
+	}
+
+	var lowStock string
+	if picked > 0 {
+		log.Printf("[PickUI] Picked %d of %d x item %d at %s for %s\n", picked, p.line.Qty, p.line.ItemID, p.line.Location, p.session.List.Reference)
+		commit := queue.Commit{
+			DeviceID:  p.deviceID,
+			Location:  p.line.Location,
+			Delta:     -picked,
+			ItemID:    p.line.ItemID,
+			Site:      p.api.Site,
+			Reference: p.session.List.Reference,
+			Operator:  operator,
+		}
+		p.queue.Submit(commit)
+		lowStock = checkReorderPoint(p.api, p.queue, p.itemDefs[commit.ItemID], commit)
+	}
+
+	p.session.Confirm(p.line, picked)
+	if remainder := p.line.Qty - picked; remainder > 0 {
+		// No commit frees what wasn't picked, so release it now rather
+		// than hold it until it expires
+		go p.releaseRemainder(p.session.List.Reference, *p.line, remainder)
+	}
+	switch {
+	case p.line.Short():
+		p.setError(fmt.Sprintf("Short pick recorded: %d of %d", picked, p.line.Qty))
+	case lowStock != "":
+		p.setError("Low stock - " + lowStock)
+	default:
+		p.setError("")
+	}
+	p.nextLine()
+}
+
+// releaseRemainder frees the reserved stock a short pick left behind.
+func (p *PickUI) releaseRemainder(reference string, line picking.Line, qty int) {
+	err := p.api.ReleaseReserved(reference, line.ItemID, line.Location, qty)
+	if err != nil {
+		log.Printf("[PickUI] Failed to release %d x item %d at %s for %s: %v\n", qty, line.ItemID, line.Location, reference, err)
+		return
+	}
+	log.Printf("[PickUI] Released %d unpicked x item %d at %s for %s\n", qty, line.ItemID, line.Location, reference)
+}
+
+func (p *PickUI) skip() {
+	if p.line == nil {
+		return
+	}
+	p.session.Skip(p.line)
+	p.setError("")
+	p.nextLine()
+}
+
+func (p *PickUI) setError(msg string) {
+	if p.error == nil {
+		return
+	}
+	if msg == "" {
+		p.error.ParseMarkdown("")
+	} else {
+		p.error.ParseMarkdown("**Status:** " + msg)
+	}
+}
+
+func (p *PickUI) CreateRenderer() fyne.WidgetRenderer {
+	log.Println("[PickUI] CreateRenderer called")
+	p.error = widget.NewRichTextFromMarkdown("")
+	p.load()
+
+	var options []string
+	for _, list := range p.lists {
+		options = append(options, p.listLabel(list))
+	}
+	p.listSelect = widget.NewSelect(options, func(label string) {
+		p.selectList(label)
+	})
+	p.listSelect.PlaceHolder = "Select pick list..."
+
+	p.stepLabel = widget.NewLabel("")
+
+	p.scanInput = newScanEntry()
+	p.scanInput.SetPlaceHolder("Select a pick list first")
+	p.scanInput.OnSubmitted = func(s string) {
+		p.onScanned(s)
+		p.scanInput.SetText("")
+	}
+	p.scanInput.Disable()
+
+	p.qtyInput = newScanEntry()
+	p.qtyInput.SetPlaceHolder("Picked quantity")
+	p.qtyInput.OnSubmitted = func(string) {
+		p.confirm()
+	}
+
+	p.confirmBtn = widget.NewButton("Confirm", func() {
+		p.confirm()
+	})
+	p.confirmBtn.Importance = widget.HighImportance
+	p.confirmBtn.Disable()
+
+	p.skipBtn = widget.NewButton("Skip", func() {
+		p.skip()
+	})
+	p.skipBtn.Disable()
+
+	p.backBtn = widget.NewButton("Back", func() {
+		p.onBack()
+	})
+
+	if len(options) == 0 {
+		p.stepLabel.SetText("No open pick lists")
+	}
+
+	vbox := container.NewVBox(
+		p.listSelect,
+		p.stepLabel,
+		p.scanInput,
+		p.qtyInput,
+		container.NewHBox(p.confirmBtn, p.skipBtn, p.backBtn),
+		p.error,
+	)
+
+	return widget.NewSimpleRenderer(vbox)
+}
@@ -0,0 +1,371 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/catalog"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/putaway"
+	"github.com/larkin1/wmsproject/internal/queue"
+	"github.com/larkin1/wmsproject/internal/receiving"
+	"github.com/larkin1/wmsproject/internal/stock"
+)
+
+// ReceiveUI receives stock against an open purchase order: scan the item,
+// enter the quantity, choose a put-away location and a positive commit
+// referencing the PO line is queued.
+type ReceiveUI struct {
+	widget.BaseWidget
+
+	poSelect      *widget.Select
+	linesLabel    *widget.Label
+	scanInput     *scanEntry
+	itemLabel     *widget.Label
+	qtyInput      *scanEntry
+	unitSelect    *widget.Select
+	locationInput *scanEntry
+	suggestions   *fyne.Container
+	receiveBtn    *widget.Button
+	backBtn       *widget.Button
+	error         *widget.RichText
+
+	orders    []api.PurchaseOrder
+	itemDefs  map[int]api.Item
+	catalog   *catalog.Catalog
+	locations []api.Location
+	stock     []api.StockLevel
+	receipt   *receiving.Receipt
+	line      *receiving.Line
+
+	api      *api.Client
+	queue    *queue.Queue
+	putaway  *putaway.Engine
+	deviceID string
+	onBack   func()
+}
+
+func NewReceiveUI(apiClient *api.Client, commitQueue *queue.Queue, deviceID string, onBack func()) *ReceiveUI {
+	r := &ReceiveUI{
+		api:      apiClient,
+		queue:    commitQueue,
+		deviceID: deviceID,
+		onBack:   onBack,
+		itemDefs: make(map[int]api.Item),
+		catalog:  catalog.New(),
+	}
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+func (r *ReceiveUI) load() {
+	log.Println("[ReceiveUI] load() called")
+	itemsData, err := r.api.FetchItems()
+	if err != nil {
+		log.Printf("[ReceiveUI] FetchItems error: %v\n", err)
+	} else {
+		r.catalog.SetItems(itemsData)
+	}
+	for _, item := range itemsData {
+		r.itemDefs[item.ID] = item
+	}
+
+	r.locations, err = r.api.FetchLocations()
+	if err != nil {
+		log.Printf("[ReceiveUI] FetchLocations error: %v\n", err)
+	}
+
+	r.stock, err = r.api.FetchOverview()
+	if err != nil {
+		log.Printf("[ReceiveUI] FetchOverview error: %v\n", err)
+	}
+
+	r.orders, err = r.api.FetchPurchaseOrders()
+	if err != nil {
+		log.Printf("[ReceiveUI] FetchPurchaseOrders error: %v\n", err)
+		r.setError("Could not load purchase orders")
+	}
+}
+
+func (r *ReceiveUI) orderLabel(order api.PurchaseOrder) string {
+	if order.Supplier == "" {
+		return order.Reference
+	}
+	return fmt.Sprintf("%s - %s", order.Reference, order.Supplier)
+}
+
+func (r *ReceiveUI) itemName(id int) string {
+	if item, ok := r.itemDefs[id]; ok {
+		return item.Name
+	}
+	return fmt.Sprintf("ID: %d", id)
+}
+
+func (r *ReceiveUI) selectOrder(label string) {
+	for _, order := range r.orders {
+		if r.orderLabel(order) == label {
+			log.Printf("[ReceiveUI] Receiving against %s\n", order.Reference)
+			r.receipt = receiving.NewReceipt(order, r.queue.Pending())
+			r.line = nil
+			r.itemLabel.SetText("Scan an item from this order")
+			r.scanInput.Enable()
+			r.updateLines()
+			return
+		}
+	}
+}
+
+func (r *ReceiveUI) updateLines() {
+	var lines []string
+	for _, line := range r.receipt.Lines {
+		item := r.itemDefs[line.ItemID]
+		text := fmt.Sprintf("%s: %d / %d %s", r.itemName(line.ItemID), line.Total(), line.Qty, item.Base())
+		switch line.Status() {
+		case receiving.StatusOver:
+			text += fmt.Sprintf("  OVER by %d", line.Total()-line.Qty)
+		case receiving.StatusUnder:
+			text += fmt.Sprintf("  short %d", line.Outstanding())
+		case receiving.StatusComplete:
+			text += "  complete"
+		}
+		lines = append(lines, text)
+	}
+	if n := len(r.receipt.Discrepancies()); n > 0 {
+		lines = append(lines, fmt.Sprintf("%d of %d lines differ from the order", n, len(r.receipt.Lines)))
+	}
+	r.linesLabel.SetText(strings.Join(lines, "\n"))
+}
+
+func (r *ReceiveUI) onScanned(text string) {
+	text = strings.TrimSpace(text)
+	log.Printf("[ReceiveUI] onScanned: '%s'\n", text)
+	if r.receipt == nil {
+		return
+	}
+	text, msg, ok := scanFor(text, config.ScanItem)
+	if !ok {
+		r.setError(msg)
+		return
+	}
+
+	// Resolve the ID, barcode or name; a bare ID still works when the
+	// items couldn't be loaded
+	var itemID int
+	if item, ok := r.catalog.Find(text); ok {
+		itemID = item.ID
+	} else if id, err := strconv.Atoi(text); err == nil {
+		itemID = id
+	} else {
+		r.setError(fmt.Sprintf("Unknown item '%s'", text))
+		return
+	}
+
+	line, err := r.receipt.LineForItem(itemID)
+	if err != nil {
+		r.setError(err.Error())
+		return
+	}
+
+	r.line = line
+	r.itemLabel.SetText(fmt.Sprintf("Receiving: %s (%d outstanding)", r.itemName(line.ItemID), line.Outstanding()))
+	if line.Outstanding() > 0 {
+		r.qtyInput.SetText(strconv.Itoa(line.Outstanding()))
+	} else {
+		r.qtyInput.SetText("")
+		r.setError("Line already fully received")
+	}
+
+	item, ok := r.itemDefs[line.ItemID]
+	if !ok {
+		item = api.Item{ID: line.ItemID}
+	}
+	r.unitSelect.Options = item.UnitNames()
+	r.unitSelect.SetSelected(item.Base())
+
+	r.showSuggestions(line.ItemID, line.Outstanding())
+	r.receiveBtn.Enable()
+}
+
+// Scanned takes a scan from a hardware scanner once an order is selected
+func (r *ReceiveUI) Scanned(code string) {
+	if r.scanInput == nil || r.scanInput.Disabled() {
+		return
+	}
+	r.onScanned(code)
+}
+
+// SetCatalog shares the app-wide catalog instead of the screen's own
+func (r *ReceiveUI) SetCatalog(cat *catalog.Catalog) {
+	r.catalog = cat
+}
+
+// SetPutaway sets the engine used to suggest put-away locations
+func (r *ReceiveUI) SetPutaway(engine *putaway.Engine) {
+	r.putaway = engine
+}
+
+func (r *ReceiveUI) showSuggestions(itemID, qty int) {
+	r.suggestions.RemoveAll()
+	if r.putaway == nil {
+		return
+	}
+
+	levels := stock.NewLevels(r.stock, r.queue.Pending())
+	engine := r.putaway.WithFree(levels.FreeFunc(r.locations))
+	suggested := engine.Suggest(itemID, qty, r.stock, r.locations, 5)
+	if len(suggested) == 0 {
+		r.suggestions.Add(widget.NewLabel("No put-away suggestions"))
+		return
+	}
+
+	r.locationInput.SetText(suggested[0].Location)
+	r.suggestions.Add(widget.NewLabel("Suggested:"))
+	for _, s := range suggested {
+		code := s.Location
+		r.suggestions.Add(widget.NewButton(code, func() {
+			r.locationInput.SetText(code)
+		}))
+	}
+}
+
+func (r *ReceiveUI) receive() {
+	if r.line == nil {
+		r.setError("Scan an item first")
+		return
+	}
+
+	loc := strings.TrimSpace(r.locationInput.Text)
+	if loc == "" {
+		r.setError("Enter a put-away location")
+		return
+	}
+
+	qty, err := strconv.Atoi(strings.TrimSpace(r.qtyInput.Text))
+	if err != nil || qty <= 0 {
+		r.setError("Invalid number")
+		return
+	}
+
+	item, ok := r.itemDefs[r.line.ItemID]
+	if !ok {
+		item = api.Item{ID: r.line.ItemID}
+	}
+	unit := r.unitSelect.Selected
+	delta, err := item.ToBaseUnits(unit, qty)
+	if err != nil {
+		r.setError(err.Error())
+		return
+	}
+
+	log.Printf("[ReceiveUI] Receiving %d %s (%d base) of item %d into %s for %s line %d\n", qty, unit, delta, r.line.ItemID, loc, r.receipt.Order.Reference, r.line.ID)
+	r.queue.Submit(queue.Commit{
+		DeviceID:  r.deviceID,
+		Location:  loc,
+		Delta:     delta,
+		ItemID:    r.line.ItemID,
+		UoM:       unit,
+		UoMQty:    qty,
+		Site:      r.api.Site,
+		Reference: r.receipt.Order.Reference,
+		POLineID:  r.line.ID,
+		Operator:  operator,
+	})
+	r.receipt.Record(r.line, delta)
+	r.updateLines()
+
+	if r.line.Status() == receiving.StatusOver {
+		r.setError(fmt.Sprintf("Over receipt: %d more than ordered", r.line.Total()-r.line.Qty))
+	} else {
+		r.setError("")
+	}
+
+	r.line = nil
+	r.qtyInput.SetText("")
+	r.locationInput.SetText("")
+	r.suggestions.RemoveAll()
+	r.itemLabel.SetText("Scan the next item")
+	r.receiveBtn.Disable()
+}
+
+func (r *ReceiveUI) setError(msg string) {
+	if r.error == nil {
+		return
+	}
+	if msg == "" {
+		r.error.ParseMarkdown("")
+	} else {
+		r.error.ParseMarkdown("**Status:** " + msg)
+	}
+}
+
+func (r *ReceiveUI) CreateRenderer() fyne.WidgetRenderer {
+	log.Println("[ReceiveUI] CreateRenderer called")
+	r.error = widget.NewRichTextFromMarkdown("")
+	r.load()
+
+	var options []string
+	for _, order := range r.orders {
+		options = append(options, r.orderLabel(order))
+	}
+	r.poSelect = widget.NewSelect(options, func(label string) {
+		r.selectOrder(label)
+	})
+	r.poSelect.PlaceHolder = "Select purchase order..."
+
+	r.linesLabel = widget.NewLabel("")
+	if len(options) == 0 {
+		r.linesLabel.SetText("No open purchase orders")
+	}
+
+	r.scanInput = newScanEntry()
+	r.scanInput.SetPlaceHolder("Scan item...")
+	r.scanInput.OnSubmitted = func(s string) {
+		r.onScanned(s)
+		r.scanInput.SetText("")
+	}
+	r.scanInput.Disable()
+
+	r.itemLabel = widget.NewLabel("")
+
+	r.qtyInput = newScanEntry()
+	r.qtyInput.SetPlaceHolder("Quantity received")
+
+	r.unitSelect = widget.NewSelect([]string{api.DefaultBaseUnit}, nil)
+	r.unitSelect.SetSelected(api.DefaultBaseUnit)
+
+	r.locationInput = newScanEntry()
+	r.locationInput.SetPlaceHolder("Put-away location")
+
+	r.suggestions = container.NewHBox()
+
+	r.receiveBtn = widget.NewButton("Receive", func() {
+		r.receive()
+	})
+	r.receiveBtn.Importance = widget.HighImportance
+	r.receiveBtn.Disable()
+
+	r.backBtn = widget.NewButton("Back", func() {
+		r.onBack()
+	})
+
+	vbox := container.NewVBox(
+		r.poSelect,
+		r.linesLabel,
+		widget.NewSeparator(),
+		r.scanInput,
+		r.itemLabel,
+		container.NewBorder(nil, nil, nil, r.unitSelect, r.qtyInput),
+		r.locationInput,
+		container.NewHScroll(r.suggestions),
+		container.NewHBox(r.receiveBtn, r.backBtn),
+		r.error,
+	)
+
+	return widget.NewSimpleRenderer(vbox)
+}
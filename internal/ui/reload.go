@@ -0,0 +1,8 @@
+package ui
+
+// Reloader is implemented by screens that refresh their data when a
+// realtime change to table has been applied to the local caches.
+// Reload must be called on the Fyne UI thread.
+type Reloader interface {
+	Reload(table string)
+}
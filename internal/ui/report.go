@@ -0,0 +1,283 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/export"
+	"github.com/larkin1/wmsproject/internal/location"
+	"github.com/larkin1/wmsproject/internal/report"
+)
+
+// ReportUI builds PDF reports into the site's reports directory and lists
+// them for opening or saving elsewhere.
+type ReportUI struct {
+	widget.BaseWidget
+
+	kindSelect     *widget.Select
+	fromInput      *widget.Entry
+	toInput        *widget.Entry
+	locationInput  *widget.Entry
+	referenceInput *widget.Entry
+	generateBtn    *widget.Button
+	openBtn        *widget.Button
+	saveBtn        *widget.Button
+	backBtn        *widget.Button
+	list           *widget.List
+	error          *widget.RichText
+
+	saved    []string
+	selected string
+
+	api      *api.Client
+	parser   *location.Parser
+	dataPath string
+	window   fyne.Window
+	onBack   func()
+}
+
+func NewReportUI(apiClient *api.Client, parser *location.Parser, dataPath string, onBack func()) *ReportUI {
+	r := &ReportUI{
+		api:      apiClient,
+		parser:   parser,
+		dataPath: dataPath,
+		onBack:   onBack,
+	}
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+// SetWindow sets the window the save dialog opens over
+func (r *ReportUI) SetWindow(w fyne.Window) {
+	r.window = w
+}
+
+// kind maps the selected title back to its report kind.
+func (r *ReportUI) kind() string {
+	for _, kind := range report.Kinds {
+		if report.Title(kind) == r.kindSelect.Selected {
+			return kind
+		}
+	}
+	return ""
+}
+
+func (r *ReportUI) options() (report.Options, error) {
+	opts := report.Options{
+		Site:      r.api.Site,
+		Location:  r.locationInput.Text,
+		Reference: r.referenceInput.Text,
+	}
+	var err error
+	if opts.From, err = export.ParseDate(r.fromInput.Text); err != nil {
+		return opts, err
+	}
+	if opts.To, err = export.ParseDate(r.toInput.Text); err != nil {
+		return opts, err
+	}
+	if !opts.To.IsZero() {
+		opts.To = opts.To.AddDate(0, 0, 1) // include the whole day
+	}
+	return opts, nil
+}
+
+func (r *ReportUI) generate() {
+	kind := r.kind()
+	opts, err := r.options()
+	if err != nil {
+		r.setError(err.Error())
+		return
+	}
+
+	doc, err := report.Generate(r.api, r.parser, kind, opts)
+	if err != nil {
+		log.Printf("[ReportUI] Generate %s error: %v\n", kind, err)
+		r.setError(fmt.Sprintf("Could not build report: %v", err))
+		return
+	}
+	path, err := report.Save(doc, r.dataPath, kind)
+	if err != nil {
+		log.Printf("[ReportUI] Save error: %v\n", err)
+		r.setError("Could not save report: " + err.Error())
+		return
+	}
+
+	log.Printf("[ReportUI] Saved %s\n", path)
+	r.loadSaved()
+	r.list.Select(0)
+	r.setError(fmt.Sprintf("Saved %s (%d pages)", filepath.Base(path), len(doc.Pages())))
+}
+
+func (r *ReportUI) loadSaved() {
+	saved, err := report.Saved(r.dataPath)
+	if err != nil {
+		log.Printf("[ReportUI] Listing reports error: %v\n", err)
+	}
+	r.saved = saved
+	if r.list != nil {
+		r.list.UnselectAll()
+		r.list.Refresh()
+	}
+	r.selected = ""
+	r.updateButtons()
+}
+
+func (r *ReportUI) updateButtons() {
+	if r.openBtn == nil {
+		return
+	}
+	if r.selected == "" {
+		r.openBtn.Disable()
+		r.saveBtn.Disable()
+	} else {
+		r.openBtn.Enable()
+		r.saveBtn.Enable()
+	}
+}
+
+// open hands the report to the system PDF viewer, which can print or share it.
+func (r *ReportUI) open() {
+	u := &url.URL{Scheme: "file", Path: filepath.ToSlash(r.selected)}
+	if err := fyne.CurrentApp().OpenURL(u); err != nil {
+		log.Printf("[ReportUI] OpenURL error: %v\n", err)
+		r.setError("Could not open report: " + err.Error())
+	}
+}
+
+// saveCopy writes the selected report to a location the user picks.
+func (r *ReportUI) saveCopy() {
+	source := r.selected
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		data, err := os.ReadFile(source)
+		if err == nil {
+			_, err = writer.Write(data)
+		}
+		if err != nil {
+			log.Printf("[ReportUI] Save copy error: %v\n", err)
+			r.setError("Could not save copy: " + err.Error())
+			return
+		}
+		r.setError("Saved a copy to " + writer.URI().Name())
+	}, r.window)
+	save.SetFileName(filepath.Base(source))
+	save.SetFilter(storage.NewExtensionFileFilter([]string{".pdf"}))
+	save.Show()
+}
+
+func (r *ReportUI) setError(msg string) {
+	if r.error == nil {
+		return
+	}
+	if msg == "" {
+		r.error.ParseMarkdown("")
+	} else {
+		r.error.ParseMarkdown("**Status:** " + msg)
+	}
+}
+
+func (r *ReportUI) CreateRenderer() fyne.WidgetRenderer {
+	log.Println("[ReportUI] CreateRenderer called")
+	r.error = widget.NewRichTextFromMarkdown("")
+
+	// Movement and variance reports default to the last week
+	now := time.Now()
+	r.fromInput = widget.NewEntry()
+	r.fromInput.SetPlaceHolder("YYYY-MM-DD")
+	r.fromInput.SetText(now.AddDate(0, 0, -6).Format("2006-01-02"))
+	r.toInput = widget.NewEntry()
+	r.toInput.SetPlaceHolder("YYYY-MM-DD")
+	r.toInput.SetText(now.Format("2006-01-02"))
+	r.locationInput = widget.NewEntry()
+	r.locationInput.SetPlaceHolder("All locations")
+	r.referenceInput = widget.NewEntry()
+	r.referenceInput.SetPlaceHolder(report.CountReference)
+
+	var titles []string
+	for _, kind := range report.Kinds {
+		titles = append(titles, report.Title(kind))
+	}
+	r.kindSelect = widget.NewSelect(titles, func(string) {
+		kind := r.kind()
+		if kind == report.Stock {
+			r.fromInput.Disable()
+			r.toInput.Disable()
+		} else {
+			r.fromInput.Enable()
+			r.toInput.Enable()
+		}
+		if kind == report.Variance {
+			r.referenceInput.Enable()
+		} else {
+			r.referenceInput.Disable()
+		}
+	})
+	r.kindSelect.SetSelected(report.Title(report.Stock))
+
+	r.generateBtn = widget.NewButton("Generate PDF", func() {
+		r.generate()
+	})
+	r.generateBtn.Importance = widget.HighImportance
+
+	r.openBtn = widget.NewButton("Open", func() {
+		r.open()
+	})
+	r.saveBtn = widget.NewButton("Save a Copy...", func() {
+		r.saveCopy()
+	})
+	r.backBtn = widget.NewButton("Back", func() {
+		r.onBack()
+	})
+
+	r.list = widget.NewList(
+		func() int {
+			return len(r.saved)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(filepath.Base(r.saved[id]))
+		},
+	)
+	r.list.OnSelected = func(id widget.ListItemID) {
+		r.selected = r.saved[id]
+		r.updateButtons()
+	}
+	r.loadSaved()
+
+	title := widget.NewLabel("Reports")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Report", r.kindSelect),
+		widget.NewFormItem("From", r.fromInput),
+		widget.NewFormItem("To", r.toInput),
+		widget.NewFormItem("Location", r.locationInput),
+		widget.NewFormItem("Count ref", r.referenceInput),
+	)
+
+	content := container.NewBorder(
+		container.NewVBox(title, form, r.generateBtn, r.error, widget.NewLabel("Saved reports")),
+		container.NewHBox(r.openBtn, r.saveBtn, r.backBtn),
+		nil,
+		nil,
+		r.list,
+	)
+
+	return widget.NewSimpleRenderer(content)
+}
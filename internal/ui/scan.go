@@ -0,0 +1,259 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/larkin1/wmsproject/internal/barcode"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/scanner"
+)
+
+// ScanReceiver is implemented by screens that take barcode scans from
+// hardware scanners. Scanned must be called on the Fyne UI thread.
+type ScanReceiver interface {
+	Scanned(code string)
+}
+
+// The keyboard-wedge decoder and where its scans go, set by ListenForScans.
+var (
+	wedge      *scanner.Wedge
+	routeWedge func(code string)
+)
+
+// ListenForScans catches keyboard-wedge scans typed into the window,
+// whether a scan entry or nothing has focus, and passes them to route
+// instead of the field. A nil wedge stops listening.
+func ListenForScans(c fyne.Canvas, w *scanner.Wedge, route func(code string)) {
+	wedge, routeWedge = w, route
+	if w == nil {
+		c.SetOnTypedRune(nil)
+		c.SetOnTypedKey(nil)
+		return
+	}
+	c.SetOnTypedRune(func(r rune) {
+		if _, scan := wedge.Type(r); scan != "" {
+			routeWedge(scan)
+		}
+	})
+	c.SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		if isEnter(ev) {
+			if _, scan := wedge.Type('\n'); scan != "" {
+				routeWedge(scan)
+			}
+		}
+	})
+}
+
+// The rules scans are classified by and the operator signed in by badge,
+// set by SetScanRules and badge scans.
+var (
+	scanRules *scanner.Classifier
+	operator  string
+	onSignIn  func(operator string)
+)
+
+// SetScanRules sets the rules scans are classified by. signedIn, if set,
+// is called when an operator scans their badge.
+func SetScanRules(rules *scanner.Classifier, signedIn func(operator string)) {
+	scanRules, onSignIn = rules, signedIn
+}
+
+// ScanBadge signs in with code if it is an operator badge, for screens
+// that take no other scans.
+func ScanBadge(code string) bool {
+	scan, err := scanRules.Classify(code)
+	if err != nil || scan.Kind != config.ScanBadge {
+		return false
+	}
+	signIn(scan.Value)
+	return true
+}
+
+func signIn(badge string) {
+	log.Printf("[Scan] Operator %s signed in\n", badge)
+	operator = badge
+	if onSignIn != nil {
+		onSignIn(badge)
+	}
+}
+
+// scanKinds names each kind of code for messages.
+var scanKinds = map[string]string{
+	config.ScanLocation: "a location",
+	config.ScanItem:     "an item",
+	config.ScanBadge:    "a badge",
+	config.ScanCommand:  "a command",
+}
+
+// scanFor classifies a scan for a step that wants one kind of code,
+// returning the code to use. When the scan can't be used it returns
+// false and what to tell the operator: why it was rejected, or that
+// their badge signed them in.
+func scanFor(code, kind string) (string, string, bool) {
+	scan, err := scanRules.Classify(code)
+	switch {
+	case err != nil:
+		return "", err.Error(), false
+	case scan.Kind == config.ScanBadge:
+		signIn(scan.Value)
+		return "", "Signed in as " + scan.Value, false
+	case scan.Kind != "" && scan.Kind != kind:
+		return "", fmt.Sprintf("'%s' is %s code, expected %s", scan.Code, scanKinds[scan.Kind], scanKinds[kind]), false
+	}
+	return scan.Value, "", true
+}
+
+func isEnter(ev *fyne.KeyEvent) bool {
+	return ev.Name == fyne.KeyReturn || ev.Name == fyne.KeyEnter
+}
+
+// scanEntry is an Entry that hands keyboard-wedge scans on rather than
+// taking them as text, so a scan goes to the workflow even when the
+// operator has tapped into a quantity field.
+type scanEntry struct {
+	widget.Entry
+}
+
+func newScanEntry() *scanEntry {
+	e := &scanEntry{}
+	e.Wrapping = fyne.TextWrap(fyne.TextTruncateClip)
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+func (e *scanEntry) TypedRune(r rune) {
+	if wedge == nil {
+		e.Entry.TypedRune(r)
+		return
+	}
+	pass, scan := wedge.Type(r)
+	e.typeText(pass)
+	if scan != "" {
+		routeWedge(scan)
+	}
+}
+
+func (e *scanEntry) TypedKey(ev *fyne.KeyEvent) {
+	if wedge == nil || !isEnter(ev) {
+		e.Entry.TypedKey(ev)
+		return
+	}
+	pass, scan := wedge.Type('\n')
+	e.typeText(pass)
+	if scan != "" {
+		routeWedge(scan)
+	}
+}
+
+// typeText types text the wedge passed through, with '\n' as Enter.
+func (e *scanEntry) typeText(text string) {
+	for _, r := range text {
+		if r == '\n' {
+			e.Entry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyReturn})
+		} else {
+			e.Entry.TypedRune(r)
+		}
+	}
+}
+
+// scanCamera shows the camera's frames until one holds a barcode, then
+// passes its text to onScan. Failures go to onError; cancelling calls
+// neither.
+func scanCamera(w fyne.Window, cam *scanner.Camera, onScan, onError func(string)) {
+	preview := canvas.NewImageFromImage(nil)
+	preview.FillMode = canvas.ImageFillContain
+	preview.SetMinSize(fyne.NewSize(480, 360))
+	status := widget.NewLabel("Looking for a barcode...")
+
+	stop := make(chan struct{})
+	dlg := dialog.NewCustom("Camera Scan", "Cancel", container.NewBorder(nil, status, nil, nil, preview), w)
+	dlg.SetOnClosed(func() {
+		select {
+		case <-stop:
+		default:
+			close(stop)
+		}
+	})
+	dlg.Show()
+
+	go func() {
+		results, err := cam.Scan(stop, func(frame image.Image) {
+			fyne.Do(func() {
+				preview.Image = frame
+				preview.Refresh()
+			})
+		})
+		fyne.Do(func() {
+			if errors.Is(err, scanner.ErrStopped) {
+				return
+			}
+			dlg.Hide()
+			if err != nil {
+				log.Printf("[Camera] Capture failed: %v\n", err)
+				onError(fmt.Sprintf("Camera failed: %v", err))
+				return
+			}
+			chooseScan(w, results, onScan)
+		})
+	}()
+}
+
+// scanImageFile decodes the barcodes in an image file the operator picks,
+// passing the text to onScan.
+func scanImageFile(w fyne.Window, onScan, onError func(string)) {
+	open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		name := reader.URI().Name()
+		go func() {
+			defer reader.Close()
+			results, err := barcode.DecodeFile(reader)
+			fyne.Do(func() {
+				switch {
+				case err != nil:
+					onError(fmt.Sprintf("Cannot read %s: %v", name, err))
+				case len(results) == 0:
+					onError(fmt.Sprintf("No barcode found in %s", name))
+				default:
+					chooseScan(w, results, onScan)
+				}
+			})
+		}()
+	}, w)
+	open.SetFilter(storage.NewExtensionFileFilter([]string{".png", ".jpg", ".jpeg", ".gif"}))
+	open.Show()
+}
+
+// chooseScan passes on a single barcode, or asks which one was meant when
+// there are several in view.
+func chooseScan(w fyne.Window, results []barcode.Result, onScan func(string)) {
+	for _, r := range results {
+		log.Printf("[Camera] Decoded %s: '%s'\n", r.Format, r.Text)
+	}
+	if len(results) == 1 {
+		onScan(results[0].Text)
+		return
+	}
+
+	var dlg dialog.Dialog
+	list := container.NewVBox()
+	for _, r := range results {
+		text := r.Text
+		list.Add(widget.NewButton(fmt.Sprintf("%s (%s)", text, r.Format), func() {
+			dlg.Hide()
+			onScan(text)
+		}))
+	}
+	dlg = dialog.NewCustom("Choose Barcode", "Cancel", list, w)
+	dlg.Show()
+}
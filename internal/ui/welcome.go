@@ -9,6 +9,10 @@ import (
 type WelcomeScreen struct {
 	widget.BaseWidget
 	onScreenChange func(string)
+
+	sites        []string
+	site         string
+	onSiteChange func(string)
 }
 
 func NewWelcomeScreen(onScreenChange func(string)) *WelcomeScreen {
@@ -19,12 +23,51 @@ func NewWelcomeScreen(onScreenChange func(string)) *WelcomeScreen {
 	return w
 }
 
+// SetSites enables the site switcher with the given sites and active site.
+func (w *WelcomeScreen) SetSites(sites []string, current string, onSiteChange func(string)) {
+	w.sites = sites
+	w.site = current
+	w.onSiteChange = onSiteChange
+}
+
 func (w *WelcomeScreen) CreateRenderer() fyne.WidgetRenderer {
 	addBtn := widget.NewButton("Add/Remove Stock", func() {
 		w.onScreenChange("commit")
 	})
 	addBtn.Importance = widget.HighImportance
 
+	pickBtn := widget.NewButton("Pick Orders", func() {
+		w.onScreenChange("pick")
+	})
+
+	receiveBtn := widget.NewButton("Receive Purchase Orders", func() {
+		w.onScreenChange("receive")
+	})
+
+	lowStockBtn := widget.NewButton("Low Stock", func() {
+		w.onScreenChange("lowstock")
+	})
+
+	locationsBtn := widget.NewButton("Browse Locations", func() {
+		w.onScreenChange("locations")
+	})
+
+	importBtn := widget.NewButton("Import CSV", func() {
+		w.onScreenChange("import")
+	})
+
+	exportBtn := widget.NewButton("Export", func() {
+		w.onScreenChange("export")
+	})
+
+	labelsBtn := widget.NewButton("Print Labels", func() {
+		w.onScreenChange("labels")
+	})
+
+	reportsBtn := widget.NewButton("Reports", func() {
+		w.onScreenChange("reports")
+	})
+
 	exitBtn := widget.NewButton("Exit", func() {
 		fyne.CurrentApp().Quit()
 	})
@@ -39,11 +82,33 @@ func (w *WelcomeScreen) CreateRenderer() fyne.WidgetRenderer {
 	vbox := container.NewVBox(
 		title,
 		subtitle,
-		widget.NewSeparator(),
-		addBtn,
-		exitBtn,
 	)
 
+	if len(w.sites) > 0 {
+		siteSelect := widget.NewSelect(w.sites, nil)
+		siteSelect.PlaceHolder = "Select site..."
+		siteSelect.SetSelected(w.site)
+		siteSelect.OnChanged = func(site string) {
+			if site != w.site && w.onSiteChange != nil {
+				w.site = site
+				w.onSiteChange(site)
+			}
+		}
+		vbox.Add(container.NewBorder(nil, nil, widget.NewLabel("Site:"), nil, siteSelect))
+	}
+
+	vbox.Add(widget.NewSeparator())
+	vbox.Add(addBtn)
+	vbox.Add(pickBtn)
+	vbox.Add(receiveBtn)
+	vbox.Add(locationsBtn)
+	vbox.Add(lowStockBtn)
+	vbox.Add(labelsBtn)
+	vbox.Add(importBtn)
+	vbox.Add(exportBtn)
+	vbox.Add(reportsBtn)
+	vbox.Add(exitBtn)
+
 	centered := container.NewCenter(vbox)
 	return widget.NewSimpleRenderer(centered)
 }
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogFile is the delivery log in the site's data directory, one JSON
+// entry per line.
+const LogFile = "webhook_log.jsonl"
+
+// maxLogEntries is how many entries the log keeps; older ones are dropped
+// when a dispatcher starts.
+const maxLogEntries = 5000
+
+// Delivery results.
+const (
+	Delivered = "delivered"
+	Retrying  = "retrying"
+	Failed    = "failed"
+)
+
+// LogEntry records one delivery attempt.
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	ID      string    `json:"id"`
+	Event   string    `json:"event"`
+	URL     string    `json:"url"`
+	Attempt int       `json:"attempt"`
+	Status  int       `json:"status,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Result  string    `json:"result"`
+}
+
+func appendLog(path string, entry LogEntry) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	json.NewEncoder(f).Encode(entry)
+}
+
+// ReadLog returns the last n entries of a site's delivery log, oldest
+// first; n <= 0 returns them all.
+func ReadLog(dataPath string, n int) ([]LogEntry, error) {
+	f, err := os.Open(filepath.Join(dataPath, LogFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []LogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry LogEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if n > 0 && len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return entries, scanner.Err()
+}
+
+// trimLog drops all but the newest maxLogEntries lines.
+func trimLog(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines) <= maxLogEntries+1 {
+		return
+	}
+	os.WriteFile(path, bytes.Join(lines[len(lines)-maxLogEntries-1:], nil), 0644)
+}
@@ -0,0 +1,406 @@
+// Package webhook notifies external systems of stock events. Events are
+// POSTed as JSON signed with the webhook's secret, retried with backoff
+// until delivered, and kept in an outbox file so restarts lose nothing.
+//
+// Each request carries:
+//
+//	X-WMS-Event:     commit.synced, stock.low or stock.negative
+//	X-WMS-Delivery:  the event ID, the same across retries
+//	X-WMS-Timestamp: Unix seconds when the request was sent
+//	X-WMS-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/queue"
+	"github.com/larkin1/wmsproject/internal/stock"
+)
+
+// Events webhooks can subscribe to.
+const (
+	CommitSynced  = "commit.synced"
+	StockLow      = "stock.low"
+	StockNegative = "stock.negative"
+)
+
+// Events lists every event name.
+var Events = []string{CommitSynced, StockLow, StockNegative}
+
+// MaxAttempts is how many times a delivery is tried before it is dropped.
+const MaxAttempts = 8
+
+// Event is the JSON body POSTed to a webhook.
+type Event struct {
+	ID   string          `json:"id"`
+	Type string          `json:"event"`
+	Site string          `json:"site,omitempty"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// LowStock is the data of a stock.low event: an item fell below its
+// reorder point.
+type LowStock struct {
+	ItemID  int    `json:"item_id"`
+	Item    string `json:"item"`
+	OnHand  int    `json:"on_hand"`
+	MinQty  int    `json:"min_qty"`
+	Reorder int    `json:"reorder"`
+}
+
+// NegativeStock is the data of a stock.negative event: a location's
+// quantity of an item went below zero.
+type NegativeStock struct {
+	Location string `json:"location"`
+	ItemID   int    `json:"item_id"`
+	Item     string `json:"item"`
+	Qty      int    `json:"qty"`
+}
+
+// delivery is an event waiting to be sent to one webhook.
+type delivery struct {
+	URL      string    `json:"url"`
+	Event    Event     `json:"event"`
+	Attempts int       `json:"attempts"`
+	NextAt   time.Time `json:"next_at"`
+}
+
+// Dispatcher queues events for the configured webhooks and delivers them.
+type Dispatcher struct {
+	client      *api.Client
+	hooks       []config.Webhook
+	http        *http.Client
+	outboxPath  string
+	logPath     string
+	retryPeriod time.Duration
+
+	mu       sync.Mutex
+	outbox   []delivery
+	flushing sync.Mutex // one Flush at a time, so nothing is sent twice
+
+	wake     chan struct{}
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates a dispatcher keeping its outbox and delivery log in dataPath.
+func New(client *api.Client, hooks []config.Webhook, dataPath string) *Dispatcher {
+	d := &Dispatcher{
+		client:      client,
+		hooks:       hooks,
+		http:        &http.Client{Timeout: 10 * time.Second},
+		outboxPath:  filepath.Join(dataPath, "webhook_outbox.json"),
+		logPath:     filepath.Join(dataPath, LogFile),
+		retryPeriod: 5 * time.Second,
+		wake:        make(chan struct{}, 1),
+		stopChan:    make(chan struct{}),
+	}
+	if data, err := os.ReadFile(d.outboxPath); err == nil {
+		var saved []delivery
+		json.Unmarshal(data, &saved)
+		// Drop deliveries for webhooks no longer configured
+		for _, dl := range saved {
+			if slices.ContainsFunc(hooks, func(h config.Webhook) bool { return h.URL == dl.URL }) {
+				d.outbox = append(d.outbox, dl)
+			}
+		}
+	}
+	trimLog(d.logPath)
+	return d
+}
+
+// Start delivers events in the background until Stop.
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go d.worker()
+}
+
+func (d *Dispatcher) Stop() {
+	close(d.stopChan)
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.retryPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stopChan:
+			return
+		case <-d.wake:
+			d.Flush()
+		case <-ticker.C:
+			d.Flush()
+		}
+	}
+}
+
+// CommitsSynced is a queue.OnBatchSynced listener. It sends commit.synced
+// for each commit and, for removals, checks whether the flush took an item
+// below its reorder point or a location below zero. Stock levels already
+// include the whole flush, so each crossing is worked out once from the
+// flush's total change, not once per commit. The events are queued
+// together, so the outbox is written once per flush.
+func (d *Dispatcher) CommitsSynced(commits []queue.Commit) {
+	var batch []delivery
+	defer func() { d.enqueue(batch) }()
+
+	type key struct {
+		location string
+		itemID   int
+	}
+	itemDelta := make(map[int]int)
+	locationDelta := make(map[key]int)
+	var removed []key // in order, one per location and item
+	for _, commit := range commits {
+		batch = append(batch, d.deliveries(CommitSynced, commit)...)
+		k := key{commit.Location, commit.ItemID}
+		if commit.Delta < 0 && !slices.Contains(removed, k) {
+			removed = append(removed, k)
+		}
+		itemDelta[commit.ItemID] += commit.Delta
+		locationDelta[k] += commit.Delta
+	}
+	if len(removed) == 0 || !(d.wants(StockLow) || d.wants(StockNegative)) {
+		return
+	}
+
+	rows, err := d.client.FetchOverview()
+	if err != nil {
+		log.Printf("[Webhook] No stock levels to check: %v\n", err)
+		return
+	}
+	levels := stock.NewLevels(rows, nil)
+	items := make(map[int]api.Item)
+	if list, err := d.client.Store.Items(); err == nil {
+		for _, it := range list {
+			items[it.ID] = it
+		}
+	}
+
+	checked := make(map[int]bool)
+	for _, k := range removed {
+		item := items[k.itemID]
+		if !checked[k.itemID] {
+			checked[k.itemID] = true
+			// Levels already include the flush, so without it is one step back
+			onHand := levels.Item(k.itemID)
+			if stock.BelowReorderPoint(item, onHand) && !stock.BelowReorderPoint(item, onHand-itemDelta[k.itemID]) {
+				batch = append(batch, d.deliveries(StockLow, LowStock{
+					ItemID:  k.itemID,
+					Item:    item.Name,
+					OnHand:  onHand,
+					MinQty:  item.MinQty,
+					Reorder: stock.ReorderQty(item, onHand),
+				})...)
+			}
+		}
+		if qty := levels.At(k.location, k.itemID); qty < 0 && qty-locationDelta[k] >= 0 {
+			batch = append(batch, d.deliveries(StockNegative, NegativeStock{
+				Location: k.location,
+				ItemID:   k.itemID,
+				Item:     item.Name,
+				Qty:      qty,
+			})...)
+		}
+	}
+}
+
+func (d *Dispatcher) wants(event string) bool {
+	for _, hook := range d.hooks {
+		if len(hook.Events) == 0 || slices.Contains(hook.Events, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Emit queues an event for every webhook subscribed to it.
+func (d *Dispatcher) Emit(event string, data interface{}) {
+	d.enqueue(d.deliveries(event, data))
+}
+
+// deliveries builds an event's deliveries to the webhooks subscribed to it.
+func (d *Dispatcher) deliveries(event string, data interface{}) []delivery {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("[Webhook] Cannot encode %s: %v\n", event, err)
+		return nil
+	}
+	ev := Event{ID: newID(), Type: event, Site: d.client.Site, Time: time.Now().UTC(), Data: raw}
+
+	var out []delivery
+	for _, hook := range d.hooks {
+		if len(hook.Events) == 0 || slices.Contains(hook.Events, event) {
+			out = append(out, delivery{URL: hook.URL, Event: ev, NextAt: ev.Time})
+		}
+	}
+	return out
+}
+
+// enqueue adds deliveries to the outbox, saving it once, and wakes the worker.
+func (d *Dispatcher) enqueue(deliveries []delivery) {
+	if len(deliveries) == 0 {
+		return
+	}
+	d.mu.Lock()
+	d.outbox = append(d.outbox, deliveries...)
+	d.save()
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pending returns how many deliveries are waiting.
+func (d *Dispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.outbox)
+}
+
+// Flush tries every delivery that is due and returns how many are still
+// waiting.
+func (d *Dispatcher) Flush() int {
+	d.flushing.Lock()
+	defer d.flushing.Unlock()
+
+	now := time.Now()
+	d.mu.Lock()
+	var due []delivery
+	for _, dl := range d.outbox {
+		if !dl.NextAt.After(now) {
+			due = append(due, dl)
+		}
+	}
+	d.mu.Unlock()
+
+	// Send without the lock so events can be queued meanwhile
+	done := make(map[string]bool)
+	retry := make(map[string]time.Time)
+	for _, dl := range due {
+		key := dl.Event.ID + " " + dl.URL
+		dl.Attempts++
+		entry := LogEntry{Time: time.Now().UTC(), ID: dl.Event.ID, Event: dl.Event.Type, URL: dl.URL, Attempt: dl.Attempts}
+		status, err := d.send(dl)
+		entry.Status = status
+		switch {
+		case err == nil:
+			entry.Result = Delivered
+			done[key] = true
+		case dl.Attempts >= MaxAttempts:
+			entry.Error, entry.Result = err.Error(), Failed
+			done[key] = true
+			log.Printf("[Webhook] Giving up on %s to %s: %v\n", dl.Event.ID, dl.URL, err)
+		default:
+			entry.Error, entry.Result = err.Error(), Retrying
+			retry[key] = time.Now().Add(backoff(dl.Attempts))
+		}
+		appendLog(d.logPath, entry)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	kept := d.outbox[:0]
+	for _, dl := range d.outbox {
+		key := dl.Event.ID + " " + dl.URL
+		if done[key] {
+			continue
+		}
+		if next, ok := retry[key]; ok {
+			dl.Attempts++
+			dl.NextAt = next
+		}
+		kept = append(kept, dl)
+	}
+	d.outbox = kept
+	d.save()
+	return len(d.outbox)
+}
+
+// send POSTs one delivery, returning the response status.
+func (d *Dispatcher) send(dl delivery) (int, error) {
+	secret := ""
+	for _, hook := range d.hooks {
+		if hook.URL == dl.URL {
+			secret = hook.Secret
+		}
+	}
+
+	body, err := json.Marshal(dl.Event)
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequest(http.MethodPost, dl.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-WMS-Event", dl.Event.Type)
+	req.Header.Set("X-WMS-Delivery", dl.Event.ID)
+	req.Header.Set("X-WMS-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-WMS-Signature", Sign(secret, timestamp, body))
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the X-WMS-Signature value for a body sent at timestamp.
+// Receivers recompute it with the shared secret and compare in constant
+// time, rejecting old timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff is the wait before retrying after the given number of attempts:
+// 30s doubling up to an hour.
+func backoff(attempts int) time.Duration {
+	wait := 30 * time.Second << (attempts - 1)
+	if wait > time.Hour || wait <= 0 {
+		return time.Hour
+	}
+	return wait
+}
+
+func (d *Dispatcher) save() {
+	data, _ := json.MarshalIndent(d.outbox, "", "  ")
+	os.WriteFile(d.outboxPath, data, 0644)
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
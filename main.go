@@ -1,26 +1,46 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"github.com/larkin1/wmsproject/internal/api"
+	"github.com/larkin1/wmsproject/internal/catalog"
+	"github.com/larkin1/wmsproject/internal/config"
+	"github.com/larkin1/wmsproject/internal/location"
+	"github.com/larkin1/wmsproject/internal/mqtt"
+	"github.com/larkin1/wmsproject/internal/putaway"
 	"github.com/larkin1/wmsproject/internal/queue"
+	"github.com/larkin1/wmsproject/internal/scanner"
+	"github.com/larkin1/wmsproject/internal/server"
 	"github.com/larkin1/wmsproject/internal/ui"
+	"github.com/larkin1/wmsproject/internal/webhook"
 )
 
 var (
-	basePath     string
-	settingsPath string
-	appAPI       *api.Client
-	commitQueue  *queue.Queue
-	mainWindow   fyne.Window
-	fyneApp      fyne.App
+	basePath       string
+	dataPath       string // per-site caches and queue, see config.SiteDir
+	settingsPath   string
+	appSettings    *config.Settings
+	appAPI         *api.Client
+	commitQueue    *queue.Queue
+	appCatalog     *catalog.Catalog
+	subscriber     *api.Subscriber
+	apiServer      *server.Server
+	webhooks       *webhook.Dispatcher
+	mqttEvents     *mqtt.Events
+	serialScanner  *scanner.Serial
+	currentScreen  fyne.CanvasObject
+	locationParser *location.Parser
+	putawayEngine  *putaway.Engine
+	mainWindow     fyne.Window
+	fyneApp        fyne.App
 )
 
 func init() {
@@ -56,64 +76,291 @@ func loadSettings() (bool, error) {
 
 	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
 		log.Println("[Main] Settings file not found, creating empty")
-		// Create empty settings
-		emptySettings := map[string]string{
-			"api_url": "",
-			"api_key": "",
-		}
-		data, _ := json.MarshalIndent(emptySettings, "", "  ")
-		err := os.WriteFile(settingsPath, data, 0644)
+		appSettings, err = config.CreateDefault(settingsPath)
 		if err != nil {
 			log.Printf("[Main] Failed to write settings: %v\n", err)
 		}
 		return false, nil
 	}
 
-	data, err := os.ReadFile(settingsPath)
+	settings, err := config.Load(settingsPath)
 	if err != nil {
-		log.Printf("[Main] Failed to read settings: %v\n", err)
+		log.Printf("[Main] Failed to load settings: %v\n", err)
 		return false, err
 	}
-
-	var settings map[string]string
-	err = json.Unmarshal(data, &settings)
-	if err != nil {
-		log.Printf("[Main] Failed to parse settings: %v\n", err)
-		return false, err
+	appSettings = settings
+	if settings.DeviceID == "" {
+		settings.DeviceID = config.DefaultDeviceID
 	}
 
-	log.Printf("[Main] Settings loaded: api_url=%s\n", settings["api_url"])
+	log.Printf("[Main] Settings loaded: api_url=%s\n", settings.APIURL)
 
-	if settings["api_url"] == "" || settings["api_key"] == "" {
+	if settings.APIURL == "" || settings.APIKey == "" {
 		log.Println("[Main] Settings incomplete")
 		return false, nil
 	}
 
-	appAPI = api.NewClient(settings["api_url"], settings["api_key"], basePath)
-	commitQueue = queue.NewQueue(appAPI, basePath)
-	commitQueue.Start()
+	startSite()
 
 	log.Println("[Main] API client and queue initialized")
 	return true, nil
 }
 
+// startSite creates the API client and queue for the active site. Each site
+// keeps its caches and pending commits in its own directory.
+func startSite() {
+	stopServices()
+
+	var err error
+	dataPath, err = config.SiteDir(basePath, appSettings.Site)
+	if err != nil {
+		log.Printf("[Main] %v, using the default site\n", err)
+		appSettings.Site = ""
+		dataPath = basePath
+	}
+	os.MkdirAll(dataPath, 0755)
+	log.Printf("[Main] Site %q using data path: %s\n", appSettings.Site, dataPath)
+
+	appAPI = api.NewClient(appSettings.APIURL, appSettings.APIKey, dataPath)
+	appAPI.Site = appSettings.Site
+	if appSettings.MaxCacheAge != "" {
+		maxAge, err := time.ParseDuration(appSettings.MaxCacheAge)
+		if err != nil {
+			log.Printf("[Main] Invalid max_cache_age %q: %v\n", appSettings.MaxCacheAge, err)
+		}
+		appAPI.MaxCacheAge = maxAge
+	}
+	commitQueue = queue.NewQueue(appAPI, dataPath)
+	if len(appSettings.Webhooks) > 0 {
+		webhooks = webhook.New(appAPI, appSettings.Webhooks, dataPath)
+		commitQueue.OnBatchSynced(webhooks.CommitsSynced)
+		webhooks.Start()
+	}
+	if appSettings.MQTTBroker != "" {
+		events, err := mqtt.Start(appSettings, commitQueue, dataPath)
+		if err != nil {
+			log.Printf("[Main] MQTT disabled: %v\n", err)
+		}
+		mqttEvents = events
+	}
+	commitQueue.Start()
+
+	appCatalog = catalog.New()
+	appCatalog.Refresh(appAPI)
+
+	startRealtime()
+	startServer()
+	startScanners()
+}
+
+// startScanners listens for keyboard-wedge scans and reads the serial
+// scanner, if set up in settings, sending every scan to the visible screen.
+func startScanners() {
+	rules, err := scanner.NewClassifier(appSettings.ScanRules)
+	if err != nil {
+		log.Printf("[Main] %v (accepting all scans)\n", err)
+	}
+	ui.SetScanRules(rules, func(operator string) {
+		mainWindow.SetTitle("WMS - Warehouse Management System - " + operator)
+	})
+
+	ui.ListenForScans(mainWindow.Canvas(), scanner.NewWedge(appSettings.ScannerPrefix, appSettings.ScannerSuffix), routeScan)
+	if appSettings.ScannerDevice != "" {
+		serialScanner = scanner.NewSerial(appSettings.ScannerDevice, func(code string) {
+			fyne.Do(func() {
+				routeScan(code)
+			})
+		})
+		serialScanner.Start()
+	}
+}
+
+func routeScan(code string) {
+	if r, ok := currentScreen.(ui.ScanReceiver); ok {
+		r.Scanned(code)
+		return
+	}
+	if ui.ScanBadge(code) {
+		return
+	}
+	log.Printf("[Main] Scan %q ignored, screen takes no scans\n", code)
+}
+
+// startRealtime subscribes to live changes if enabled in settings. Changes
+// are applied to the caches and the visible screen reloads.
+func startRealtime() {
+	if appSettings.Realtime == "" {
+		return
+	}
+
+	sub, err := appAPI.NewSubscriber(appSettings.Realtime, appSettings.RealtimeURL)
+	if err != nil {
+		log.Printf("[Main] Realtime disabled: %v\n", err)
+		return
+	}
+	sub.OnChange(func(ev api.ChangeEvent) {
+		appCatalog.Refresh(appAPI)
+		fyne.Do(func() {
+			if r, ok := currentScreen.(ui.Reloader); ok {
+				r.Reload(ev.Table)
+			}
+		})
+	})
+	sub.Start()
+	subscriber = sub
+}
+
+// startServer runs the local HTTP API if an address is set in settings.
+func startServer() {
+	if appSettings.ServerAddr == "" {
+		return
+	}
+
+	srv := server.New(appAPI, commitQueue, appSettings.ServerToken)
+	srv.DeviceID = appSettings.DeviceID
+	srv.CapacityPolicy = appSettings.CapacityPolicy
+	if err := srv.Start(appSettings.ServerAddr); err != nil {
+		log.Printf("[Main] API server disabled: %v\n", err)
+		return
+	}
+	apiServer = srv
+}
+
+func stopServices() {
+	if serialScanner != nil {
+		log.Println("[Main] Stopping scanner")
+		serialScanner.Stop()
+		serialScanner = nil
+	}
+	if apiServer != nil {
+		log.Println("[Main] Stopping API server")
+		apiServer.Stop()
+		apiServer = nil
+	}
+	if subscriber != nil {
+		log.Println("[Main] Stopping realtime")
+		subscriber.Stop()
+		subscriber = nil
+	}
+	if commitQueue != nil {
+		log.Println("[Main] Stopping queue")
+		commitQueue.Stop()
+	}
+	if webhooks != nil {
+		log.Println("[Main] Stopping webhooks")
+		webhooks.Stop()
+		webhooks = nil
+	}
+	if mqttEvents != nil {
+		log.Println("[Main] Stopping MQTT")
+		mqttEvents.Stop()
+		mqttEvents = nil
+	}
+}
+
+func setScreen(screen fyne.CanvasObject) {
+	currentScreen = screen
+	mainWindow.SetContent(screen)
+}
+
+func switchSite(site string) {
+	log.Printf("[Main] Switching to site: %s\n", site)
+	if err := config.CheckSite(site); site != "" && err != nil {
+		log.Printf("[Main] Not switching: %v\n", err)
+		return
+	}
+	appSettings.Site = site
+	err := config.Save(settingsPath, appSettings)
+	if err != nil {
+		log.Printf("[Main] Failed to save settings: %v\n", err)
+	}
+
+	startSite()
+	setScreen(makeApp())
+}
+
+func loadLocationParser() *location.Parser {
+	pattern := ""
+	if appSettings != nil {
+		pattern = appSettings.LocationPattern
+	}
+
+	parser, err := location.NewParser(pattern)
+	if err != nil {
+		log.Printf("[Main] %v (using default pattern)\n", err)
+		parser, _ = location.NewParser("")
+	}
+	return parser
+}
+
 func switchScreen(screenName string) {
 	log.Printf("[Main] Switching to screen: %s\n", screenName)
 	switch screenName {
 	case "commit":
-		commitUI := ui.NewCommitUI(appAPI, commitQueue, basePath)
+		commitUI := ui.NewCommitUI(appAPI, commitQueue, appSettings.DeviceID)
 		commitUI.SetWindow(mainWindow)
-		mainWindow.SetContent(commitUI)
+		commitUI.SetCatalog(appCatalog)
+		commitUI.SetPutaway(putawayEngine)
+		commitUI.SetCapacityPolicy(appSettings.CapacityPolicy)
+		commitUI.SetCamera(scanner.NewCamera(appSettings.CameraCommand))
+		setScreen(commitUI)
+	case "locations":
+		setScreen(ui.NewLocationBrowser(appAPI, locationParser, func() {
+			switchScreen("welcome")
+		}))
+	case "pick":
+		pickUI := ui.NewPickUI(appAPI, commitQueue, locationParser, appSettings.DeviceID, dataPath, func() {
+			switchScreen("welcome")
+		})
+		pickUI.SetCatalog(appCatalog)
+		setScreen(pickUI)
+	case "receive":
+		receiveUI := ui.NewReceiveUI(appAPI, commitQueue, appSettings.DeviceID, func() {
+			switchScreen("welcome")
+		})
+		receiveUI.SetCatalog(appCatalog)
+		receiveUI.SetPutaway(putawayEngine)
+		setScreen(receiveUI)
+	case "lowstock":
+		setScreen(ui.NewLowStockUI(appAPI, commitQueue, func() {
+			switchScreen("welcome")
+		}))
+	case "import":
+		importUI := ui.NewImportUI(appAPI, appSettings.DeviceID, func() {
+			switchScreen("welcome")
+		})
+		importUI.SetWindow(mainWindow)
+		setScreen(importUI)
+	case "export":
+		exportUI := ui.NewExportUI(appAPI, func() {
+			switchScreen("welcome")
+		})
+		exportUI.SetWindow(mainWindow)
+		setScreen(exportUI)
+	case "labels":
+		labelUI := ui.NewLabelUI(appAPI, func() {
+			switchScreen("welcome")
+		})
+		labelUI.SetWindow(mainWindow)
+		labelUI.SetPrinter(appSettings.LabelPrinter, appSettings.LabelDPI)
+		labelUI.SetTemplates(appSettings.LabelTemplates)
+		setScreen(labelUI)
+	case "reports":
+		reportUI := ui.NewReportUI(appAPI, locationParser, dataPath, func() {
+			switchScreen("welcome")
+		})
+		reportUI.SetWindow(mainWindow)
+		setScreen(reportUI)
 	case "welcome":
-		mainWindow.SetContent(makeApp())
+		setScreen(makeApp())
 	default:
-		mainWindow.SetContent(makeApp())
+		setScreen(makeApp())
 	}
 }
 
 func main() {
 	log.Println("[Main] Starting WMS app")
-	a := app.NewWithID("com.velocidrone.velocidrone")
+	a := app.NewWithID(config.AppID)
 	fyneApp = a
 
 	w := a.NewWindow("WMS - Warehouse Management System")
@@ -128,27 +375,31 @@ func main() {
 	os.MkdirAll(basePath, 0755)
 
 	hasSettings, _ := loadSettings()
+	locationParser = loadLocationParser()
+	putawayEngine = putaway.NewEngine(locationParser, nil)
+	if appSettings != nil {
+		putawayEngine.Rules = appSettings.PutawayRules
+	}
 
 	if !hasSettings {
 		log.Println("[Main] No settings found, showing settings screen")
 		// Show settings screen
 		settingsUI := ui.NewSettingsUI(func(apiURL, apiKey string) {
 			log.Printf("[Main] Settings saved: %s\n", apiURL)
-			appAPI = api.NewClient(apiURL, apiKey, basePath)
-			commitQueue = queue.NewQueue(appAPI, basePath)
-			commitQueue.Start()
 
 			// Save settings
-			settings := map[string]string{
-				"api_url": apiURL,
-				"api_key": apiKey,
+			if appSettings == nil {
+				appSettings = &config.Settings{DeviceID: config.DefaultDeviceID}
 			}
-			data, _ := json.MarshalIndent(settings, "", "  ")
-			err := os.WriteFile(settingsPath, data, 0644)
+			appSettings.APIURL = apiURL
+			appSettings.APIKey = apiKey
+			err := config.Save(settingsPath, appSettings)
 			if err != nil {
 				log.Printf("[Main] Failed to save settings: %v\n", err)
 			}
 
+			startSite()
+
 			// Show welcome screen
 			w.SetContent(makeApp())
 		}, basePath)
@@ -161,14 +412,19 @@ func main() {
 
 	w.ShowAndRun()
 
-	if commitQueue != nil {
-		log.Println("[Main] Stopping queue")
-		commitQueue.Stop()
-	}
+	stopServices()
 }
 
 func makeApp() fyne.CanvasObject {
-	return container.NewVBox(
-		ui.NewWelcomeScreen(switchScreen),
-	)
+	welcome := ui.NewWelcomeScreen(switchScreen)
+
+	if appSettings != nil && (len(appSettings.Sites) > 0 || appSettings.Site != "") {
+		sites := appSettings.Sites
+		if appSettings.Site != "" && !slices.Contains(sites, appSettings.Site) {
+			sites = append([]string{appSettings.Site}, sites...)
+		}
+		welcome.SetSites(sites, appSettings.Site, switchSite)
+	}
+
+	return container.NewVBox(welcome)
 }